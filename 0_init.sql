@@ -10,39 +10,153 @@
 BEGIN;
 
 -- Удаление существующих объектов
+DROP TABLE IF EXISTS abc_xyz_items CASCADE;
+DROP TABLE IF EXISTS abc_xyz_snapshots CASCADE;
+DROP TABLE IF EXISTS bench_results CASCADE;
+DROP TABLE IF EXISTS bench_runs CASCADE;
+DROP TABLE IF EXISTS change_log CASCADE;
+DROP TABLE IF EXISTS customer_merges CASCADE;
+DROP TABLE IF EXISTS part_renames CASCADE;
+DROP TABLE IF EXISTS credit_overrides CASCADE;
+DROP TABLE IF EXISTS payments CASCADE;
+DROP TABLE IF EXISTS invoice_lines CASCADE;
+DROP TABLE IF EXISTS invoices CASCADE;
+DROP TABLE IF EXISTS invoice_numbering CASCADE;
+DROP TABLE IF EXISTS shipment_returns CASCADE;
+DROP TABLE IF EXISTS shipment_status_history CASCADE;
 DROP TABLE IF EXISTS shipments_audit CASCADE;
 DROP TABLE IF EXISTS shipments CASCADE;
+DROP TABLE IF EXISTS volume_discounts CASCADE;
+DROP TABLE IF EXISTS discount_rules CASCADE;
+DROP TABLE IF EXISTS price_lists CASCADE;
+DROP TABLE IF EXISTS customer_contacts CASCADE;
 DROP TABLE IF EXISTS customers CASCADE;
+DROP TABLE IF EXISTS cities CASCADE;
+DROP TABLE IF EXISTS part_units CASCADE;
 DROP TABLE IF EXISTS parts CASCADE;
+DROP TABLE IF EXISTS units CASCADE;
+DROP TABLE IF EXISTS exchange_rates CASCADE;
+DROP TABLE IF EXISTS currencies CASCADE;
 
 DROP FUNCTION IF EXISTS fn_cascade_delete_shipments() CASCADE;
 DROP FUNCTION IF EXISTS fn_log_shipment_insert() CASCADE;
+DROP FUNCTION IF EXISTS fn_log_change() CASCADE;
 DROP PROCEDURE IF EXISTS p_customer_shipment_summary(INT, OUT DECIMAL(10,2), OUT DECIMAL(10,2));
+DROP PROCEDURE IF EXISTS p_customer_shipment_summary(INT, OUT DECIMAL(10,2));
 DROP FUNCTION IF EXISTS fn_customer_count_by_city(TEXT);
 DROP FUNCTION IF EXISTS fn_shipments_in_range(DATE, DATE);
+DROP FUNCTION IF EXISTS fn_exchange_rate(TEXT, DATE) CASCADE;
+DROP FUNCTION IF EXISTS fn_city_key(TEXT) CASCADE;
 DROP VIEW IF EXISTS v_full_shipment_info CASCADE;
+DROP VIEW IF EXISTS v_shipment_net CASCADE;
 
 -- ============================================================================
 -- ТАБЛИЦЫ
 -- ============================================================================
 
+-- Справочник валют
+CREATE TABLE currencies (
+    currency_code        TEXT PRIMARY KEY CHECK (currency_code ~ '^[A-Z]{3}$'),
+    name                 TEXT NOT NULL
+);
+
+-- Курсы валют ЦБ: стоимость одной единицы валюты в рублях на дату
+CREATE TABLE exchange_rates (
+    currency_code        TEXT NOT NULL REFERENCES currencies(currency_code),
+    rate_date            DATE NOT NULL,
+    rate                 DECIMAL(18,8) NOT NULL CHECK (rate > 0),
+    PRIMARY KEY (currency_code, rate_date)
+);
+
+-- Справочник единиц измерения.
+-- factor - сколько базовых единиц (base_unit) содержится в единице: 1 т = 1000 кг
+CREATE TABLE units (
+    unit_code            TEXT PRIMARY KEY,
+    name                 TEXT NOT NULL,
+    base_unit            TEXT NOT NULL,
+    factor               DECIMAL(18,6) NOT NULL CHECK (factor > 0),
+    CONSTRAINT fk_unit_base FOREIGN KEY (base_unit) REFERENCES units(unit_code)
+);
+
 -- Справочник деталей (Файл02)
 CREATE TABLE parts (
     part_code            TEXT PRIMARY KEY,
     part_type            TEXT NOT NULL CHECK (part_type IN ('покупная', 'собственного производства')),
     name                 TEXT NOT NULL,
-    unit                 TEXT NOT NULL CHECK (unit IN ('шт','кг','м','компл')),
+    unit                 TEXT NOT NULL REFERENCES units(unit_code),
     plan_price           DECIMAL(10,2) NOT NULL CHECK (plan_price >= 0),
+    currency             TEXT NOT NULL DEFAULT 'RUB' REFERENCES currencies(currency_code),
+    -- Удаление в корзину: запись скрыта из списков, отгрузки сохраняются
+    deleted_at           TIMESTAMP,
+    deleted_by           TEXT,
     CONSTRAINT chk_part_code_not_empty CHECK (LENGTH(part_code) > 0)
 );
 
+-- Допустимые единицы отгрузки детали.
+-- factor - сколько единиц детали (parts.unit) содержится в единице: 1 компл = 100 шт
+CREATE TABLE part_units (
+    part_code            TEXT NOT NULL,
+    unit_code            TEXT NOT NULL REFERENCES units(unit_code),
+    factor               DECIMAL(18,6) NOT NULL CHECK (factor > 0),
+    PRIMARY KEY (part_code, unit_code),
+    CONSTRAINT fk_part_unit_part FOREIGN KEY (part_code)
+        REFERENCES parts(part_code)
+        ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+-- Ключ для сравнения названий городов: без регистра, ё = е, без префикса «г.» / «город»
+-- и лишних пробелов. Нужен до таблицы cities - по нему вычисляется name_key
+CREATE OR REPLACE FUNCTION fn_city_key(p_name TEXT)
+RETURNS TEXT
+LANGUAGE sql
+IMMUTABLE
+AS $$
+    SELECT btrim(regexp_replace(
+        regexp_replace(translate(lower(btrim(p_name)), 'ё', 'е'), '^(г\.|город\s|г\s)\s*', ''),
+        '\s+', ' ', 'g'));
+$$;
+
+-- Справочник городов. Покупатели и скидки ссылаются на каноническое название
+CREATE TABLE cities (
+    name                 TEXT PRIMARY KEY,
+    region               TEXT NOT NULL DEFAULT '',
+    name_key             TEXT GENERATED ALWAYS AS (fn_city_key(name)) STORED,
+    CONSTRAINT uq_city_key UNIQUE (name_key),
+    CONSTRAINT chk_city_not_empty CHECK (name_key <> '')
+);
+
 -- Покупатели (Файл15)
+-- address - улица и дом одной строкой (для документов), street и building - то же по частям.
+-- Внешний ключ на cities добавляется после приведения городов тестовых данных
 CREATE TABLE customers (
     customer_id          INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
     name                 TEXT NOT NULL,
     address              TEXT NOT NULL DEFAULT 'Не указан',
-    city                 TEXT NOT NULL
+    city                 TEXT NOT NULL,
+    postal_code          TEXT CHECK (postal_code ~ '^[0-9]{6}$'),
+    street               TEXT,
+    building             TEXT,
+    currency             TEXT NOT NULL DEFAULT 'RUB' REFERENCES currencies(currency_code),
+    -- Кредитный лимит в валюте покупателя; NULL - без ограничения
+    credit_limit         DECIMAL(12,2) CHECK (credit_limit >= 0),
+    -- Удаление в корзину: запись скрыта из списков, отгрузки сохраняются
+    deleted_at           TIMESTAMP,
+    deleted_by           TEXT
+);
+
+-- Контактные лица покупателя; основной контакт - не более одного
+CREATE TABLE customer_contacts (
+    contact_id           BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    customer_id          INT NOT NULL REFERENCES customers(customer_id) ON DELETE CASCADE,
+    name                 TEXT NOT NULL CHECK (name <> ''),
+    role                 TEXT NOT NULL DEFAULT '',
+    phone                TEXT CHECK (phone ~ '^\+[0-9]{10,15}$'),
+    email                TEXT CHECK (email ~ '^[^@\s]+@[^@\s]+\.[^@\s]+$'),
+    is_primary           BOOLEAN NOT NULL DEFAULT false,
+    CONSTRAINT chk_contact_channel CHECK (phone IS NOT NULL OR email IS NOT NULL)
 );
+CREATE INDEX idx_customer_contacts_customer ON customer_contacts(customer_id);
+CREATE UNIQUE INDEX uq_customer_primary_contact ON customer_contacts(customer_id) WHERE is_primary;
 
 -- Учет отгрузки готовой продукции (Файл14)
 CREATE TABLE shipments (
@@ -50,9 +164,15 @@ CREATE TABLE shipments (
     shipment_doc_no      INT NOT NULL CHECK (shipment_doc_no > 0),
     customer_id          INT NOT NULL,
     part_code            TEXT NOT NULL,
-    unit                 TEXT NOT NULL CHECK (unit IN ('шт','кг','м','компл')),
+    unit                 TEXT NOT NULL REFERENCES units(unit_code),
     qty                  DECIMAL(10,2) NOT NULL CHECK (qty > 0),
+    -- Действующая цена за единицу детали на момент оформления (прайс-лист и скидки),
+    -- в валюте детали. NOT NULL включается после загрузки тестовых данных
+    price                DECIMAL(10,2) CHECK (price >= 0),
     shipment_date        DATE NOT NULL DEFAULT CURRENT_DATE,
+    status               TEXT NOT NULL DEFAULT 'draft'
+                         CHECK (status IN ('draft','confirmed','picked','shipped','delivered','cancelled')),
+    invoice_id           BIGINT,
     PRIMARY KEY (warehouse_no, shipment_doc_no),
     
     -- Связь с customers БЕЗ системного каскада (триггер будет создан)
@@ -65,6 +185,41 @@ CREATE TABLE shipments (
         ON DELETE CASCADE ON UPDATE CASCADE
 );
 
+-- Прайс-листы: договорная цена детали для покупателя (в единице и валюте детали).
+-- valid_to IS NULL - цена действует бессрочно
+CREATE TABLE price_lists (
+    price_list_id        BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    customer_id          INT NOT NULL REFERENCES customers(customer_id) ON DELETE CASCADE,
+    part_code            TEXT NOT NULL REFERENCES parts(part_code) ON DELETE CASCADE ON UPDATE CASCADE,
+    price                DECIMAL(10,2) NOT NULL CHECK (price >= 0),
+    valid_from           DATE NOT NULL,
+    valid_to             DATE,
+    CONSTRAINT chk_price_list_period CHECK (valid_to IS NULL OR valid_from <= valid_to)
+);
+CREATE INDEX idx_price_lists_customer_part ON price_lists(customer_id, part_code);
+
+-- Скидки покупателю или всем покупателям города; part_code IS NULL - на все детали
+CREATE TABLE discount_rules (
+    rule_id              BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    customer_id          INT REFERENCES customers(customer_id) ON DELETE CASCADE,
+    city                 TEXT REFERENCES cities(name) ON UPDATE CASCADE,
+    part_code            TEXT REFERENCES parts(part_code) ON DELETE CASCADE ON UPDATE CASCADE,
+    percent              DECIMAL(5,2) NOT NULL CHECK (percent > 0 AND percent < 100),
+    valid_from           DATE NOT NULL,
+    valid_to             DATE,
+    CONSTRAINT chk_discount_scope CHECK ((customer_id IS NULL) <> (city IS NULL)),
+    CONSTRAINT chk_discount_period CHECK (valid_to IS NULL OR valid_from <= valid_to)
+);
+
+-- Скидки за объем: от min_qty единиц детали в одной отгрузке; part_code IS NULL - на все детали
+CREATE TABLE volume_discounts (
+    tier_id              BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    part_code            TEXT REFERENCES parts(part_code) ON DELETE CASCADE ON UPDATE CASCADE,
+    min_qty              DECIMAL(10,2) NOT NULL CHECK (min_qty > 0),
+    percent              DECIMAL(5,2) NOT NULL CHECK (percent > 0 AND percent < 100),
+    CONSTRAINT uq_volume_tier UNIQUE NULLS NOT DISTINCT (part_code, min_qty)
+);
+
 -- Таблица аудита для логирования операций
 CREATE TABLE shipments_audit (
     audit_id             BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
@@ -78,12 +233,234 @@ CREATE TABLE shipments_audit (
     action_time          TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
 );
 
+-- История переходов документа отгрузки по статусам (кто и когда)
+CREATE TABLE shipment_status_history (
+    history_id           BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    warehouse_no         INT NOT NULL,
+    shipment_doc_no      INT NOT NULL,
+    from_status          TEXT,
+    to_status            TEXT NOT NULL,
+    changed_by           TEXT NOT NULL,
+    changed_at           TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_status_history_shipment FOREIGN KEY (warehouse_no, shipment_doc_no)
+        REFERENCES shipments(warehouse_no, shipment_doc_no)
+        ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+-- Возвраты от покупателей по исходному документу отгрузки (кредитовые ноты)
+CREATE TABLE shipment_returns (
+    return_id            BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    warehouse_no         INT NOT NULL,
+    shipment_doc_no      INT NOT NULL,
+    qty                  DECIMAL(10,2) NOT NULL CHECK (qty > 0),
+    return_date          DATE NOT NULL DEFAULT CURRENT_DATE,
+    reason               TEXT NOT NULL DEFAULT 'Не указана',
+    credit_amount        DECIMAL(12,2) NOT NULL CHECK (credit_amount >= 0),
+    currency             TEXT NOT NULL DEFAULT 'RUB' REFERENCES currencies(currency_code),
+    created_by           TEXT NOT NULL,
+    created_at           TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_return_shipment FOREIGN KEY (warehouse_no, shipment_doc_no)
+        REFERENCES shipments(warehouse_no, shipment_doc_no)
+        ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+-- Счета покупателям за период. Номера идут без пропусков в пределах года.
+CREATE TABLE invoice_numbering (
+    year                 INT PRIMARY KEY,
+    last_no              INT NOT NULL
+);
+
+CREATE TABLE invoices (
+    invoice_id           BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    invoice_no           TEXT NOT NULL UNIQUE,
+    customer_id          INT NOT NULL REFERENCES customers(customer_id),
+    period_from          DATE NOT NULL,
+    period_to            DATE NOT NULL,
+    issue_date           DATE NOT NULL DEFAULT CURRENT_DATE,
+    currency             TEXT NOT NULL DEFAULT 'RUB' REFERENCES currencies(currency_code),
+    vat_rate             DECIMAL(5,2) NOT NULL CHECK (vat_rate >= 0 AND vat_rate <= 100),
+    net_amount           DECIMAL(12,2) NOT NULL,
+    vat_amount           DECIMAL(12,2) NOT NULL,
+    total_amount         DECIMAL(12,2) NOT NULL,
+    status               TEXT NOT NULL DEFAULT 'issued' CHECK (status IN ('issued','void')),
+    created_by           TEXT NOT NULL,
+    created_at           TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    voided_by            TEXT,
+    voided_at            TIMESTAMP,
+    CONSTRAINT chk_invoice_period CHECK (period_from <= period_to)
+);
+
+-- Строки счета - копия данных отгрузки на момент выставления
+CREATE TABLE invoice_lines (
+    invoice_id           BIGINT NOT NULL REFERENCES invoices(invoice_id) ON DELETE CASCADE,
+    line_no              INT NOT NULL,
+    warehouse_no         INT NOT NULL,
+    shipment_doc_no      INT NOT NULL,
+    shipment_date        DATE NOT NULL,
+    part_code            TEXT NOT NULL,
+    part_name            TEXT NOT NULL,
+    unit                 TEXT NOT NULL,
+    qty                  DECIMAL(10,2) NOT NULL,
+    price                DECIMAL(10,2) NOT NULL,
+    amount               DECIMAL(12,2) NOT NULL,
+    PRIMARY KEY (invoice_id, line_no)
+);
+
+-- Оплаты покупателей (уменьшают задолженность)
+CREATE TABLE payments (
+    payment_id           BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    customer_id          INT NOT NULL REFERENCES customers(customer_id) ON DELETE CASCADE,
+    payment_date         DATE NOT NULL DEFAULT CURRENT_DATE,
+    amount               DECIMAL(12,2) NOT NULL CHECK (amount > 0),
+    currency             TEXT NOT NULL REFERENCES currencies(currency_code),
+    reference            TEXT NOT NULL DEFAULT '',
+    created_by           TEXT NOT NULL,
+    created_at           TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_payments_customer ON payments(customer_id);
+
+-- Отгрузки, оформленные сверх кредитного лимита (кто разрешил и какой была задолженность)
+CREATE TABLE credit_overrides (
+    override_id          BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    warehouse_no         INT NOT NULL,
+    shipment_doc_no      INT NOT NULL,
+    customer_id          INT NOT NULL,
+    credit_limit         DECIMAL(12,2) NOT NULL,
+    exposure             DECIMAL(12,2) NOT NULL,
+    currency             TEXT NOT NULL,
+    overridden_by        TEXT NOT NULL,
+    overridden_at        TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_credit_override_shipment FOREIGN KEY (warehouse_no, shipment_doc_no)
+        REFERENCES shipments(warehouse_no, shipment_doc_no)
+        ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+-- Журнал изменений деталей, покупателей, отгрузок и их подчиненных записей: образы строки до и после.
+-- change_id - транзакция, все строки одной операции (включая каскады) имеют один change_id.
+-- Оператор передается приложением через set_config('app.user'), признак отмены - через 'app.undo'
+CREATE TABLE change_log (
+    log_id               BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    change_id            BIGINT NOT NULL DEFAULT pg_current_xact_id()::TEXT::BIGINT,
+    table_name           TEXT NOT NULL,
+    operation            TEXT NOT NULL CHECK (operation IN ('INSERT','UPDATE','DELETE')),
+    before_image         JSONB,
+    after_image          JSONB,
+    changed_by           TEXT NOT NULL DEFAULT COALESCE(NULLIF(current_setting('app.user', true), ''), 'system'),
+    changed_at           TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    is_undo              BOOLEAN NOT NULL DEFAULT COALESCE(current_setting('app.undo', true), '') = 'on',
+    undone_by            TEXT,
+    undone_at            TIMESTAMP
+);
+CREATE INDEX idx_change_log_change ON change_log(change_id);
+CREATE INDEX idx_change_log_user ON change_log(changed_by, log_id);
+
+-- Объединения дублей покупателей: копия удаленного дубля и число перенесенных отгрузок
+CREATE TABLE customer_merges (
+    merge_id             BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    survivor_id          INT NOT NULL REFERENCES customers(customer_id) ON DELETE CASCADE,
+    duplicate_id         INT NOT NULL,
+    duplicate_image      JSONB NOT NULL,
+    shipments_moved      INT NOT NULL,
+    merged_by            TEXT NOT NULL,
+    merged_at            TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Переименования кодов деталей: по старому коду можно найти деталь в выставленных
+-- счетах, которые сохраняют напечатанный код
+CREATE TABLE part_renames (
+    rename_id            BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    old_code             TEXT NOT NULL,
+    new_code             TEXT NOT NULL,
+    renamed_by           TEXT NOT NULL,
+    renamed_at           TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Прогоны нагрузочного сравнения стратегий запросов (cmd/bench): данные
+-- генерируются во временной транзакции, сохраняются только измерения
+CREATE TABLE bench_runs (
+    run_id               BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    run_at               TIMESTAMP NOT NULL,
+    run_by               TEXT NOT NULL,
+    seed                 BIGINT NOT NULL,
+    customers            INT NOT NULL CHECK (customers >= 0),
+    parts                INT NOT NULL CHECK (parts >= 0),
+    shipments            INT NOT NULL CHECK (shipments >= 0),
+    iterations           INT NOT NULL CHECK (iterations > 0)
+);
+
+-- Результаты стратегии за прогон: задержки в мс, строки и выделения памяти на запуск
+CREATE TABLE bench_results (
+    result_id            BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    run_id               BIGINT NOT NULL REFERENCES bench_runs(run_id) ON DELETE CASCADE,
+    strategy             TEXT NOT NULL,
+    min_ms               NUMERIC(12,3) NOT NULL,
+    p50_ms               NUMERIC(12,3) NOT NULL,
+    p90_ms               NUMERIC(12,3) NOT NULL,
+    p99_ms               NUMERIC(12,3) NOT NULL,
+    max_ms               NUMERIC(12,3) NOT NULL,
+    mean_ms              NUMERIC(12,3) NOT NULL,
+    result_rows          INT NOT NULL,
+    rows_read            BIGINT NOT NULL,
+    alloc_bytes          BIGINT NOT NULL,
+    allocs               BIGINT NOT NULL,
+    CONSTRAINT uq_bench_result UNIQUE (run_id, strategy)
+);
+
+-- Снимки ABC/XYZ-классификации деталей или покупателей за период месяцев
+-- [period_from, period_to); пороги в процентах. Один снимок на предмет и период
+CREATE TABLE abc_xyz_snapshots (
+    snapshot_id          BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    subject              TEXT NOT NULL CHECK (subject IN ('part', 'customer')),
+    period_from          DATE NOT NULL,
+    period_to            DATE NOT NULL,
+    months               INT NOT NULL CHECK (months > 0),
+    a_threshold          NUMERIC(5,2) NOT NULL,
+    b_threshold          NUMERIC(5,2) NOT NULL,
+    x_threshold          NUMERIC(7,2) NOT NULL,
+    y_threshold          NUMERIC(7,2) NOT NULL,
+    total_value          NUMERIC(18,2) NOT NULL,
+    created_at           TIMESTAMP NOT NULL,
+    created_by           TEXT NOT NULL,
+    CONSTRAINT chk_abc_xyz_period CHECK (period_from < period_to),
+    CONSTRAINT chk_abc_thresholds CHECK (0 < a_threshold AND a_threshold < b_threshold AND b_threshold <= 100),
+    CONSTRAINT chk_xyz_thresholds CHECK (0 < x_threshold AND x_threshold < y_threshold),
+    CONSTRAINT uq_abc_xyz_snapshot UNIQUE (subject, period_from, period_to)
+);
+
+-- Классы позиций снимка: item_key - код детали или номер покупателя;
+-- cv - коэффициент вариации помесячного количества в %, NULL без спроса
+CREATE TABLE abc_xyz_items (
+    snapshot_id          BIGINT NOT NULL REFERENCES abc_xyz_snapshots(snapshot_id) ON DELETE CASCADE,
+    item_key             TEXT NOT NULL,
+    name                 TEXT NOT NULL,
+    value                NUMERIC(18,2) NOT NULL,
+    share                NUMERIC(7,2) NOT NULL,
+    cumulative_share     NUMERIC(7,2) NOT NULL,
+    qty                  NUMERIC NOT NULL,
+    mean_qty             NUMERIC NOT NULL,
+    cv                   NUMERIC(12,2),
+    abc_class            TEXT NOT NULL CHECK (abc_class IN ('A', 'B', 'C')),
+    xyz_class            TEXT NOT NULL CHECK (xyz_class IN ('X', 'Y', 'Z')),
+    PRIMARY KEY (snapshot_id, item_key)
+);
+
+-- Отгрузка, включенная в действующий счет, не может попасть в другой
+ALTER TABLE shipments ADD CONSTRAINT fk_shipment_invoice
+    FOREIGN KEY (invoice_id) REFERENCES invoices(invoice_id);
+CREATE INDEX idx_shipments_invoice ON shipments(invoice_id);
+
+-- Отчеты за период (Задача 2) фильтруют отгрузки полуинтервалом по дате
+CREATE INDEX idx_shipments_date ON shipments(shipment_date);
+
 -- ============================================================================
 -- ТРИГГЕРЫ
 -- ============================================================================
 
 -- Триггер 1: Каскадное удаление отгрузок при удалении покупателя
--- BEFORE DELETE - сначала удаляем дочерние записи, потом родительскую
+-- BEFORE DELETE - сначала удаляем дочерние записи, потом родительскую.
+-- Приложение удаляет покупателя в корзину (deleted_at), DELETE выполняется
+-- только при окончательном удалении из корзины администратором.
+-- При объединении дублей (app.merge = 'on') отгрузки уже перенесены, и триггер не срабатывает
 CREATE OR REPLACE FUNCTION fn_cascade_delete_shipments() 
 RETURNS TRIGGER 
 LANGUAGE plpgsql 
@@ -98,6 +475,7 @@ $$;
 CREATE TRIGGER trg_customers_before_delete
 BEFORE DELETE ON customers
 FOR EACH ROW
+WHEN (current_setting('app.merge', true) IS DISTINCT FROM 'on')
 EXECUTE FUNCTION fn_cascade_delete_shipments();
 
 -- Триггер 2: Логирование вставок в shipments
@@ -123,6 +501,101 @@ AFTER INSERT ON shipments
 FOR EACH ROW
 EXECUTE FUNCTION fn_log_shipment_insert();
 
+-- Триггер 3: Журнал изменений для отмены операций
+CREATE OR REPLACE FUNCTION fn_log_change()
+RETURNS TRIGGER
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        INSERT INTO change_log (table_name, operation, after_image)
+        VALUES (TG_TABLE_NAME, TG_OP, to_jsonb(NEW));
+    ELSIF TG_OP = 'UPDATE' THEN
+        IF OLD IS DISTINCT FROM NEW THEN
+            INSERT INTO change_log (table_name, operation, before_image, after_image)
+            VALUES (TG_TABLE_NAME, TG_OP, to_jsonb(OLD), to_jsonb(NEW));
+        END IF;
+    ELSE
+        INSERT INTO change_log (table_name, operation, before_image)
+        VALUES (TG_TABLE_NAME, TG_OP, to_jsonb(OLD));
+    END IF;
+    RETURN NULL;
+END;
+$$;
+
+CREATE TRIGGER trg_parts_change_log
+AFTER INSERT OR UPDATE OR DELETE ON parts
+FOR EACH ROW
+EXECUTE FUNCTION fn_log_change();
+
+CREATE TRIGGER trg_customers_change_log
+AFTER INSERT OR UPDATE OR DELETE ON customers
+FOR EACH ROW
+EXECUTE FUNCTION fn_log_change();
+
+CREATE TRIGGER trg_shipments_change_log
+AFTER INSERT OR UPDATE OR DELETE ON shipments
+FOR EACH ROW
+EXECUTE FUNCTION fn_log_change();
+
+-- Подчиненные записи отгрузки удаляются каскадом вместе с ней: журнал удалений
+-- нужен, чтобы отмена удаления отгрузки вернула и их
+CREATE TRIGGER trg_shipment_status_history_change_log
+AFTER DELETE ON shipment_status_history
+FOR EACH ROW
+EXECUTE FUNCTION fn_log_change();
+
+CREATE TRIGGER trg_shipment_returns_change_log
+AFTER DELETE ON shipment_returns
+FOR EACH ROW
+EXECUTE FUNCTION fn_log_change();
+
+CREATE TRIGGER trg_credit_overrides_change_log
+AFTER DELETE ON credit_overrides
+FOR EACH ROW
+EXECUTE FUNCTION fn_log_change();
+
+-- Справочные записи деталей и покупателей журналируются полностью: изменение
+-- детали или покупателя (смена единицы, переименование, удаление) меняет и их
+CREATE TRIGGER trg_part_units_change_log
+AFTER INSERT OR UPDATE OR DELETE ON part_units
+FOR EACH ROW
+EXECUTE FUNCTION fn_log_change();
+
+CREATE TRIGGER trg_price_lists_change_log
+AFTER INSERT OR UPDATE OR DELETE ON price_lists
+FOR EACH ROW
+EXECUTE FUNCTION fn_log_change();
+
+CREATE TRIGGER trg_discount_rules_change_log
+AFTER INSERT OR UPDATE OR DELETE ON discount_rules
+FOR EACH ROW
+EXECUTE FUNCTION fn_log_change();
+
+CREATE TRIGGER trg_volume_discounts_change_log
+AFTER INSERT OR UPDATE OR DELETE ON volume_discounts
+FOR EACH ROW
+EXECUTE FUNCTION fn_log_change();
+
+CREATE TRIGGER trg_customer_contacts_change_log
+AFTER INSERT OR UPDATE OR DELETE ON customer_contacts
+FOR EACH ROW
+EXECUTE FUNCTION fn_log_change();
+
+-- Оплаты удаляются каскадом при окончательном удалении покупателя: журнал
+-- удалений нужен, чтобы отмена удаления вернула и их
+CREATE TRIGGER trg_payments_change_log
+AFTER DELETE ON payments
+FOR EACH ROW
+EXECUTE FUNCTION fn_log_change();
+
+-- Вставка отмечает изменение как объединение покупателей, которое не отменяется;
+-- удаление (каскадом вместе с основным покупателем) отменяется вместе с ним
+CREATE TRIGGER trg_customer_merges_change_log
+AFTER INSERT OR DELETE ON customer_merges
+FOR EACH ROW
+EXECUTE FUNCTION fn_log_change();
+
 -- ============================================================================
 -- ХРАНИМАЯ ПРОЦЕДУРА
 -- ============================================================================
@@ -130,20 +603,21 @@ EXECUTE FUNCTION fn_log_shipment_insert();
 -- Процедура с выходными параметрами: суммарная информация по отгрузкам покупателя
 CREATE OR REPLACE PROCEDURE p_customer_shipment_summary(
     IN p_customer_id INT,
-    OUT total_qty DECIMAL(10,2),
     OUT total_value DECIMAL(10,2)
 )
 LANGUAGE plpgsql
 AS $$
 BEGIN
-    -- Суммарное количество и стоимость отгрузок
+    -- Суммарная стоимость продаж (отгруженных и доставленных документов) за вычетом
+    -- возвратов, в рублях по курсу на дату отгрузки. Суммарного количества нет:
+    -- детали учитываются в разных единицах (шт, кг), и их количества не складываются
     SELECT 
-        COALESCE(SUM(s.qty), 0),
-        COALESCE(SUM(s.qty * p.plan_price), 0)
-    INTO total_qty, total_value
+        COALESCE(SUM(ROUND(n.net_base_qty * s.price * fn_exchange_rate(p.currency, s.shipment_date), 2)), 0)
+    INTO total_value
     FROM shipments s
+    JOIN v_shipment_net n ON s.warehouse_no = n.warehouse_no AND s.shipment_doc_no = n.shipment_doc_no
     JOIN parts p ON s.part_code = p.part_code
-    WHERE s.customer_id = p_customer_id;
+    WHERE s.customer_id = p_customer_id AND s.status IN ('shipped', 'delivered');
 END;
 $$;
 
@@ -151,14 +625,41 @@ $$;
 -- ФУНКЦИИ
 -- ============================================================================
 
--- Скалярная функция: количество покупателей в городе
+-- Скалярная функция: количество покупателей в городе (название сравнивается по ключу)
 CREATE OR REPLACE FUNCTION fn_customer_count_by_city(p_city TEXT) 
 RETURNS INT 
 LANGUAGE sql 
 AS $$
     SELECT COUNT(*)::INT
     FROM customers
-    WHERE city = p_city;
+    WHERE fn_city_key(city) = fn_city_key(p_city);
+$$;
+
+-- Скалярная функция: курс валюты в рублях на дату.
+-- Берется последний известный курс не позже даты (ЦБ не устанавливает курс на выходные)
+CREATE OR REPLACE FUNCTION fn_exchange_rate(p_currency TEXT, p_date DATE)
+RETURNS DECIMAL
+LANGUAGE plpgsql
+STABLE
+AS $$
+DECLARE
+    v_rate DECIMAL;
+BEGIN
+    IF p_currency = 'RUB' THEN
+        RETURN 1;
+    END IF;
+
+    SELECT rate INTO v_rate
+    FROM exchange_rates
+    WHERE currency_code = p_currency AND rate_date <= p_date
+    ORDER BY rate_date DESC
+    LIMIT 1;
+
+    IF v_rate IS NULL THEN
+        RAISE EXCEPTION 'Нет курса % на %', p_currency, p_date USING ERRCODE = 'no_data_found';
+    END IF;
+    RETURN v_rate;
+END;
 $$;
 
 -- Табличная функция: список отгрузок в интервале дат
@@ -195,12 +696,34 @@ $$;
 -- VIEW: Объединение трех таблиц
 -- ============================================================================
 
+-- Количество по документу отгрузки за вычетом возвратов.
+-- net_base_qty - то же количество в единице детали, его можно суммировать
+CREATE VIEW v_shipment_net AS
+SELECT 
+    s.warehouse_no,
+    s.shipment_doc_no,
+    COALESCE(r.returned_qty, 0) AS returned_qty,
+    s.qty - COALESCE(r.returned_qty, 0) AS net_qty,
+    COALESCE(pu.factor, 1) AS unit_factor,
+    (s.qty - COALESCE(r.returned_qty, 0)) * COALESCE(pu.factor, 1) AS net_base_qty
+FROM shipments s
+LEFT JOIN part_units pu ON s.part_code = pu.part_code AND s.unit = pu.unit_code
+LEFT JOIN (
+    SELECT warehouse_no, shipment_doc_no, SUM(qty) AS returned_qty
+    FROM shipment_returns
+    GROUP BY warehouse_no, shipment_doc_no
+) r ON s.warehouse_no = r.warehouse_no AND s.shipment_doc_no = r.shipment_doc_no;
+
+-- Количество и сумма указываются за вычетом возвратов.
+-- qty - в единице отгрузки, base_qty - в единице детали, к которой относятся plan_price
+-- и effective_price (цена отгрузки с учетом прайс-листа и скидок).
+-- Цены и total_price - в валюте детали, total_price_rub - в рублях по курсу на дату отгрузки
 CREATE VIEW v_full_shipment_info AS
 SELECT 
     s.warehouse_no,
     s.shipment_doc_no,
     s.shipment_date,
-    s.qty,
+    n.net_qty AS qty,
     c.customer_id,
     c.name AS customer_name,
     c.address AS customer_address,
@@ -208,10 +731,22 @@ SELECT
     p.part_code,
     p.name AS part_name,
     p.part_type,
-    p.unit,
+    s.unit,
     p.plan_price,
-    (s.qty * p.plan_price) AS total_price
+    s.price AS effective_price,
+    ROUND(n.net_base_qty * s.price, 2) AS total_price,
+    s.status,
+    s.qty AS shipped_qty,
+    n.returned_qty,
+    n.net_base_qty AS base_qty,
+    p.unit AS base_unit,
+    ROUND(s.price * n.unit_factor, 2) AS unit_price,
+    p.currency,
+    c.currency AS customer_currency,
+    fn_exchange_rate(p.currency, s.shipment_date) AS exchange_rate,
+    ROUND(n.net_base_qty * s.price * fn_exchange_rate(p.currency, s.shipment_date), 2) AS total_price_rub
 FROM shipments s
+JOIN v_shipment_net n ON s.warehouse_no = n.warehouse_no AND s.shipment_doc_no = n.shipment_doc_no
 JOIN customers c ON s.customer_id = c.customer_id
 JOIN parts p ON s.part_code = p.part_code;
 
@@ -219,6 +754,43 @@ JOIN parts p ON s.part_code = p.part_code;
 -- ТЕСТОВЫЕ ДАННЫЕ
 -- ============================================================================
 
+-- Валюты и курсы ЦБ (на начало периодов; до следующей даты действует последний курс)
+INSERT INTO currencies (currency_code, name) VALUES
+('RUB', 'Российский рубль'),
+('USD', 'Доллар США'),
+('EUR', 'Евро'),
+('CNY', 'Китайский юань'),
+('KZT', 'Казахстанский тенге');
+
+INSERT INTO exchange_rates (currency_code, rate_date, rate) VALUES
+('USD', '2023-01-01', 70.3375),
+('USD', '2024-01-01', 89.6883),
+('USD', '2025-01-01', 101.6797),
+('USD', '2025-07-01', 78.5229),
+('EUR', '2023-01-01', 75.6553),
+('EUR', '2024-01-01', 99.1919),
+('EUR', '2025-01-01', 106.1028),
+('EUR', '2025-07-01', 92.0226),
+('CNY', '2023-01-01', 9.8949),
+('CNY', '2024-01-01', 12.5762),
+('CNY', '2025-01-01', 13.4272),
+('CNY', '2025-07-01', 10.9397),
+('KZT', '2023-01-01', 0.152010),
+('KZT', '2024-01-01', 0.195998),
+('KZT', '2025-01-01', 0.193670),
+('KZT', '2025-07-01', 0.151236);
+
+-- Единицы измерения
+INSERT INTO units (unit_code, name, base_unit, factor) VALUES
+('шт', 'штука', 'шт', 1),
+('компл', 'комплект', 'компл', 1),
+('кг', 'килограмм', 'кг', 1),
+('г', 'грамм', 'кг', 0.001),
+('т', 'тонна', 'кг', 1000),
+('м', 'метр', 'м', 1),
+('см', 'сантиметр', 'м', 0.01),
+('мм', 'миллиметр', 'м', 0.001);
+
 -- Детали (15 штук, разные типы и цены)
 INSERT INTO parts (part_code, part_type, name, unit, plan_price) VALUES
 ('D001', 'покупная', 'Болт М10', 'шт', 5.50),
@@ -237,20 +809,87 @@ INSERT INTO parts (part_code, part_type, name, unit, plan_price) VALUES
 ('D014', 'покупная', 'Смазка литиевая', 'кг', 320.00),
 ('D015', 'собственного производства', 'Вал-шестерня', 'шт', 450.00);
 
--- Покупатели (12 штук, разные города)
+-- Каждую деталь можно отгружать в ее собственной единице
+INSERT INTO part_units (part_code, unit_code, factor)
+SELECT part_code, unit, 1 FROM parts;
+
+-- Дополнительные единицы: мелкий крепеж поставляется комплектами, смазка - в граммах и тоннах
+INSERT INTO part_units (part_code, unit_code, factor) VALUES
+('D001', 'компл', 100),
+('D002', 'компл', 100),
+('D010', 'компл', 50),
+('D014', 'г', 0.001),
+('D014', 'т', 1000);
+
+-- Справочник городов
+INSERT INTO cities (name, region) VALUES
+('Казань', 'Республика Татарстан'),
+('Москва', 'Москва'),
+('Самара', 'Самарская область'),
+('Нижний Новгород', 'Нижегородская область'),
+('Екатеринбург', 'Свердловская область'),
+('Челябинск', 'Челябинская область');
+
+-- Покупатели (12 штук, разные города).
+-- Город и адрес введены как в исходном файле - одной строкой и в разном написании
 INSERT INTO customers (name, address, city) VALUES
 ('ООО "Техноком"', 'ул. Баумана, 15', 'Казань'),
 ('ЗАО "Механика"', 'пр. Ленина, 42', 'Москва'),
-('ИП Иванов С.П.', 'ул. Пушкина, 7', 'Казань'),
+('ИП Иванов С.П.', 'ул. Пушкина, 7', 'г. Казань'),
 ('ООО "СтройМаш"', 'ул. Советская, 123', 'Самара'),
 ('АО "ПромТех"', 'бул. Победы, 88', 'Казань'),
 ('ООО "МеталлПром"', 'пер. Заводской, 5', 'Нижний Новгород'),
 ('ООО "Автодеталь"', 'ул. Гагарина, 33', 'Москва'),
-('ИП Петров А.В.', 'ул. Кремлевская, 18', 'Казань'),
+('ИП Петров А.В.', 'ул. Кремлевская, 18', 'казань'),
 ('ООО "ТехСервис"', 'пр. Мира, 56', 'Екатеринбург'),
 ('АО "Завод Точмаш"', 'ул. Индустриальная, 10', 'Челябинск'),
 ('ООО "РемМаш"', 'ул. Московская, 78', 'Казань'),
-('ИП Сидоров К.Л.', 'пер. Солнечный, 3', 'Самара');
+('ИП Сидоров К.Л.', 'пер. Солнечный, 3', 'г.Самара '),
+-- Повторно заведенный покупатель 1 (дубль для объединения)
+('Техноком ООО', 'улица Баумана, д. 15', 'казань');
+
+-- Перенос на структурированный адрес: город - к названию из справочника
+-- (неизвестные города добавляются), адрес - на улицу и дом по последней запятой
+INSERT INTO cities (name)
+SELECT DISTINCT ON (fn_city_key(city)) btrim(regexp_replace(city, '\s+', ' ', 'g'))
+FROM customers
+WHERE fn_city_key(city) NOT IN (SELECT name_key FROM cities)
+ORDER BY fn_city_key(city), city;
+
+UPDATE customers c SET city = ct.name
+FROM cities ct
+WHERE ct.name_key = fn_city_key(c.city) AND c.city <> ct.name;
+
+UPDATE customers SET
+    street   = NULLIF(btrim(regexp_replace(address, ',[^,]*$', '')), ''),
+    building = NULLIF(btrim(substring(address FROM ',([^,]*)$')), '')
+WHERE address <> 'Не указан';
+
+ALTER TABLE customers ADD CONSTRAINT fk_customer_city
+    FOREIGN KEY (city) REFERENCES cities(name) ON UPDATE CASCADE;
+
+-- Почтовые индексы и контактные лица
+UPDATE customers SET postal_code = '420111' WHERE name = 'ООО "Техноком"';
+UPDATE customers SET postal_code = '125009' WHERE name = 'ЗАО "Механика"';
+UPDATE customers SET postal_code = '443099' WHERE name = 'ООО "СтройМаш"';
+UPDATE customers SET postal_code = '620014' WHERE name = 'ООО "ТехСервис"';
+
+INSERT INTO customer_contacts (customer_id, name, role, phone, email, is_primary) VALUES
+(1, 'Галиев Ринат', 'Директор', '+78432001020', 'director@technocom.ru', true),
+(1, 'Смирнова Ольга', 'Бухгалтер', '+78432001021', 'buh@technocom.ru', false),
+(2, 'Кузнецов Андрей', 'Снабжение', '+74951234567', NULL, true),
+(4, 'Морозова Елена', 'Менеджер', NULL, 'sales@stroymash.ru', true),
+(9, 'Волков Дмитрий', 'Закупки', '+73432556677', 'zakupki@techservice.ru', true);
+
+-- Покупатели, которым выставляются счета в валюте
+UPDATE customers SET currency = 'EUR' WHERE name = 'ООО "ТехСервис"';
+UPDATE customers SET currency = 'CNY' WHERE name = 'АО "Завод Точмаш"';
+
+-- Кредитные лимиты (в валюте покупателя)
+UPDATE customers SET credit_limit = 5000.00 WHERE name = 'ООО "Техноком"';
+UPDATE customers SET credit_limit = 3000.00 WHERE name = 'АО "ПромТех"';
+UPDATE customers SET credit_limit = 1000.00 WHERE name = 'ИП Иванов С.П.';
+UPDATE customers SET credit_limit = 50.00 WHERE name = 'ООО "ТехСервис"';
 
 -- Отгрузки (35+ записей, разные склады и годы)
 INSERT INTO shipments (warehouse_no, shipment_doc_no, customer_id, part_code, unit, qty, shipment_date) VALUES
@@ -292,12 +931,60 @@ INSERT INTO shipments (warehouse_no, shipment_doc_no, customer_id, part_code, un
 (4, 4004, 11, 'D011', 'шт', 6, '2025-04-12'),
 (5, 5007, 5, 'D015', 'шт', 2, '2025-05-08'),
 (1, 1008, 12, 'D009', 'шт', 80, '2025-06-20'),
-(2, 2006, 1, 'D010', 'шт', 300, '2025-07-15'),
+(2, 2006, 1, 'D010', 'компл', 6, '2025-07-15'),
 (3, 3006, 2, 'D012', 'шт', 25, '2025-08-28'),
 (4, 4005, 3, 'D013', 'шт', 10, '2025-09-10'),
 (5, 5008, 8, 'D003', 'шт', 4, '2025-10-05'),
 (1, 1009, 11, 'D004', 'шт', 2, '2025-11-18'),
 (2, 2007, 10, 'D006', 'шт', 7, '2025-12-02');
 
+-- Исторические отгрузки оформлены до появления прайс-листов - по плановой цене
+UPDATE shipments s SET price = p.plan_price FROM parts p WHERE s.part_code = p.part_code;
+ALTER TABLE shipments ALTER COLUMN price SET NOT NULL;
+
+-- Прайс-листы и скидки
+INSERT INTO price_lists (customer_id, part_code, price, valid_from, valid_to) VALUES
+(1, 'D001', 5.00, '2025-01-01', NULL),
+(1, 'D006', 110.00, '2025-01-01', '2025-12-31'),
+(5, 'D003', 140.00, '2025-06-01', NULL);
+
+INSERT INTO discount_rules (customer_id, city, part_code, percent, valid_from, valid_to) VALUES
+(5, NULL, NULL, 5.00, '2025-01-01', NULL),
+(NULL, 'Казань', NULL, 3.00, '2025-01-01', NULL),
+(NULL, 'Москва', 'D005', 7.50, '2025-01-01', '2025-12-31');
+
+INSERT INTO volume_discounts (part_code, min_qty, percent) VALUES
+('D001', 100, 2.00),
+('D001', 500, 5.00),
+(NULL, 1000, 3.00);
+
+-- Статусы отгрузок: прошлые документы доставлены, последние - в работе
+UPDATE shipments SET status = 'delivered' WHERE shipment_date < '2025-10-01';
+UPDATE shipments SET status = 'shipped'   WHERE shipment_date >= '2025-10-01' AND shipment_date < '2025-11-15';
+UPDATE shipments SET status = 'confirmed' WHERE shipment_date >= '2025-11-15';
+
+INSERT INTO shipment_status_history (warehouse_no, shipment_doc_no, from_status, to_status, changed_by, changed_at)
+SELECT warehouse_no, shipment_doc_no, NULL, status, 'system', shipment_date
+FROM shipments;
+
+-- Оплаты покупателей
+INSERT INTO payments (customer_id, payment_date, amount, currency, reference, created_by) VALUES
+(1, '2024-02-01', 550.00, 'RUB', 'п/п 118', 'system'),
+(1, '2025-03-01', 1500.00, 'RUB', 'п/п 342', 'system'),
+(2, '2024-03-15', 750.00, 'RUB', 'п/п 77', 'system'),
+(3, '2024-05-01', 850.00, 'RUB', 'п/п 205', 'system'),
+(5, '2024-07-01', 1420.00, 'RUB', 'п/п 19', 'system'),
+(6, '2024-09-10', 600.00, 'RUB', 'п/п 451', 'system'),
+(9, '2025-03-01', 0.50, 'EUR', 'SWIFT 2025-031', 'system');
+
+-- Возвраты (часть отгруженного вернулась покупателем)
+INSERT INTO shipment_returns (warehouse_no, shipment_doc_no, qty, return_date, reason, credit_amount, created_by) VALUES
+(1, 1006, 20, '2024-10-20', 'Брак резьбы', 110.00, 'system'),
+(2, 2004, 100, '2024-09-01', 'Излишки', 120.00, 'system'),
+(3, 3002, 1, '2025-04-02', 'Повреждение при транспортировке', 150.00, 'system');
+
+-- Загрузка тестовых данных не отменяется
+TRUNCATE change_log RESTART IDENTITY;
+
 COMMIT;
 
@@ -0,0 +1,75 @@
+// Command bench compares the query strategies of Task 1 (SQL and ORM) and
+// Task 3 (SQL, streaming record-based and the full-load record-based
+// baseline) on a synthetic dataset of configurable
+// volume. The dataset lives in a rolled-back transaction; the latency
+// percentiles, rows read and allocations are printed and saved for the
+// /bench page.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/student/my-kpfu-db-app/internal/config"
+	"github.com/student/my-kpfu-db-app/internal/database"
+	"github.com/student/my-kpfu-db-app/internal/domain"
+	"github.com/student/my-kpfu-db-app/internal/repository"
+)
+
+func main() {
+	var v domain.Volumes
+	flag.IntVar(&v.Customers, "customers", 1000, "number of generated customers")
+	flag.IntVar(&v.Parts, "parts", 200, "number of generated parts")
+	flag.IntVar(&v.Shipments, "shipments", 20000, "number of generated shipments")
+	iterations := flag.Int("n", 20, "runs per strategy")
+	seed := flag.Int64("seed", time.Now().UnixNano(), "dataset seed")
+	save := flag.Bool("save", true, "save the results for the /bench page")
+	flag.Parse()
+
+	if v.Customers <= 0 || v.Parts <= 0 || v.Shipments < 0 || *iterations <= 0 {
+		log.Fatal("customers, parts and n must be positive")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+
+	dbpool, err := database.NewConnection(cfg.DBURL)
+	if err != nil {
+		log.Fatalf("Could not connect to database: %v", err)
+	}
+	defer dbpool.Close()
+
+	gormDB, err := database.NewGormConnection(cfg.DBURL)
+	if err != nil {
+		log.Fatalf("Could not connect with GORM: %v", err)
+	}
+
+	repo := repository.New(dbpool, gormDB)
+	ctx := repository.WithUser(context.Background(), "bench")
+
+	fmt.Printf("Generating %d customers, %d parts, %d shipments (seed %d)\n",
+		v.Customers, v.Parts, v.Shipments, *seed)
+	run, err := repo.Benchmark(ctx, *seed, v, *iterations)
+	if err != nil {
+		log.Fatalf("Benchmark failed: %v", err)
+	}
+
+	fmt.Printf("%-16s %9s %9s %9s %9s %9s %8s %10s %12s %10s\n",
+		"strategy", "p50 ms", "p90 ms", "p99 ms", "max ms", "mean ms", "result", "rows read", "alloc bytes", "allocs")
+	for _, r := range run.Results {
+		fmt.Printf("%-16s %9.2f %9.2f %9.2f %9.2f %9.2f %8d %10d %12d %10d\n",
+			r.Strategy, r.P50MS, r.P90MS, r.P99MS, r.MaxMS, r.MeanMS, r.ResultRows, r.RowsRead, r.AllocBytes, r.Allocs)
+	}
+
+	if *save {
+		if err := repo.SaveBenchRun(ctx, run); err != nil {
+			log.Fatalf("Could not save results: %v", err)
+		}
+		fmt.Printf("Saved as run %d\n", run.RunID)
+	}
+}
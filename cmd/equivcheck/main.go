@@ -0,0 +1,74 @@
+// Command equivcheck loads random datasets into a rolled-back transaction
+// and checks that the twin implementations of Task 1 (SQL and ORM) and
+// Task 3 (SQL and record-based) return the same rows. It exits with status 1
+// if any comparison differs.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/student/my-kpfu-db-app/internal/config"
+	"github.com/student/my-kpfu-db-app/internal/database"
+	"github.com/student/my-kpfu-db-app/internal/repository"
+)
+
+func main() {
+	seed := flag.Int64("seed", time.Now().UnixNano(), "seed of the first dataset")
+	runs := flag.Int("runs", 10, "number of datasets")
+	size := flag.Int("size", 20, "customers and parts per dataset")
+	queries := flag.Int("queries", 5, "random Task 3 queries per dataset")
+	flag.Parse()
+	decimal.MarshalJSONWithoutQuotes = true
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+
+	dbpool, err := database.NewConnection(cfg.DBURL)
+	if err != nil {
+		log.Fatalf("Could not connect to database: %v", err)
+	}
+	defer dbpool.Close()
+
+	gormDB, err := database.NewGormConnection(cfg.DBURL)
+	if err != nil {
+		log.Fatalf("Could not connect with GORM: %v", err)
+	}
+
+	repo := repository.New(dbpool, gormDB)
+	ctx := repository.WithUser(context.Background(), "equivcheck")
+	results, err := repo.CheckRandom(ctx, *seed, *runs, *size, *queries)
+	if err != nil {
+		log.Fatalf("Check failed: %v", err)
+	}
+
+	failed := 0
+	for _, c := range results {
+		status := "ok  "
+		if !c.Equal {
+			status = "FAIL"
+			failed++
+		}
+		fmt.Printf("%s %-6s %4d rows %8.2f ms | %-6s %4d rows %8.2f ms | %s\n",
+			status, c.Left, c.LeftRows, c.LeftMS, c.Right, c.RightRows, c.RightMS, c.Query)
+		if !c.Equal {
+			diff, _ := json.MarshalIndent(map[string]any{
+				"missing": c.Missing, "extra": c.Extra, "different": c.Different,
+			}, "     ", "  ")
+			fmt.Printf("     %s\n", diff)
+		}
+	}
+
+	fmt.Printf("%d comparisons, %d failed (seed %d)\n", len(results), failed, *seed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
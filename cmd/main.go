@@ -1,19 +1,30 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"os"
 
 	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
 	"github.com/student/my-kpfu-db-app/internal/config"
 	"github.com/student/my-kpfu-db-app/internal/database"
+	"github.com/student/my-kpfu-db-app/internal/document"
+	"github.com/student/my-kpfu-db-app/internal/exchange"
 	"github.com/student/my-kpfu-db-app/internal/handler"
 	"github.com/student/my-kpfu-db-app/internal/repository"
 )
 
 func main() {
+	// Decimals are encoded as JSON numbers, as clients expect
+	decimal.MarshalJSONWithoutQuotes = true
+
 	// Load configuration
-	cfg := config.Load()
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
 	fmt.Printf("Connecting to database: %s\n", cfg.DBURL)
 
 	// Connect to the database (pgx)
@@ -22,7 +33,7 @@ func main() {
 		log.Fatalf("Could not connect to database: %v", err)
 	}
 	defer dbpool.Close()
-	
+
 	fmt.Println("Database connection (pgx) established successfully")
 
 	// Connect to the database (GORM) for ORM operations
@@ -30,19 +41,32 @@ func main() {
 	if err != nil {
 		log.Fatalf("Could not connect with GORM: %v", err)
 	}
-	
+
 	fmt.Println("Database connection (GORM) established successfully")
 
 	// Create repository and handler
 	repo := repository.New(dbpool, gormDB)
-	h := handler.New(repo)
+	store, err := repo.Store(cfg.RepositoryBackend)
+	if err != nil {
+		log.Fatalf("Could not create repository: %v", err)
+	}
+	fmt.Printf("Repository backend: %s\n", cfg.RepositoryBackend)
+	docs := document.NewRenderer(cfg.FontDir, cfg.CompanyName)
+	h := handler.New(repo, store, docs, cfg)
+
+	// Import exchange rates from a local file, if configured
+	if cfg.ExchangeRatesFile != "" {
+		if err := importExchangeRates(repo, cfg.ExchangeRatesFile); err != nil {
+			log.Fatalf("Could not import exchange rates: %v", err)
+		}
+	}
 
 	// Set up router
 	r := gin.Default()
-	
+
 	// Load HTML templates
 	r.LoadHTMLGlob("web/templates/*.html")
-	
+
 	// Register routes
 	h.RegisterRoutes(r)
 
@@ -54,3 +78,21 @@ func main() {
 	}
 }
 
+func importExchangeRates(repo *repository.Repository, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	rates, err := exchange.Parse(f)
+	if err != nil {
+		return err
+	}
+	imported, err := repo.ImportExchangeRates(context.Background(), rates)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Imported %d exchange rates from %s\n", imported, path)
+	return nil
+}
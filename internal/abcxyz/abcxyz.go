@@ -0,0 +1,142 @@
+// Package abcxyz classifies parts or customers by their share of the shipped
+// value (ABC analysis) and by the variability of their monthly demand (XYZ
+// analysis), and sums the classes up into the 3x3 ABC/XYZ matrix.
+package abcxyz
+
+import (
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/shopspring/decimal"
+	"github.com/student/my-kpfu-db-app/internal/domain"
+)
+
+var hundred = decimal.NewFromInt(100)
+
+// Item is the sales of one part or customer in the period. Monthly holds
+// the demand of every month of the period, zero for months without
+// shipments: the quantity of a part or the value bought by a customer.
+type Item struct {
+	Key     string
+	Name    string
+	Value   decimal.Decimal
+	Monthly []decimal.Decimal
+}
+
+// Classify assigns the classes and returns the items ordered by value, the
+// largest first (ties by key). Items without value are always in C.
+func Classify(items []Item, t domain.ABCXYZThresholds) []domain.ABCXYZItem {
+	sorted := slices.Clone(items)
+	slices.SortStableFunc(sorted, func(a, b Item) int {
+		if c := b.Value.Cmp(a.Value); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Key, b.Key)
+	})
+
+	total := decimal.Zero
+	for _, it := range sorted {
+		total = total.Add(it.Value)
+	}
+
+	result := make([]domain.ABCXYZItem, 0, len(sorted))
+	cumulative := decimal.Zero
+	for _, it := range sorted {
+		share := decimal.Zero
+		if total.IsPositive() {
+			share = it.Value.Div(total).Mul(hundred)
+		}
+		r := domain.ABCXYZItem{
+			Key:   it.Key,
+			Name:  it.Name,
+			Value: it.Value,
+			ABC:   abcClass(cumulative, it.Value, t),
+		}
+		cumulative = cumulative.Add(share)
+		r.Share, r.CumulativeShare = share.Round(2), cumulative.Round(2)
+
+		r.Qty, r.MeanQty, r.CV = variation(it.Monthly)
+		r.XYZ = xyzClass(r.CV, t)
+		result = append(result, r)
+	}
+	return result
+}
+
+// abcClass classifies by the cumulative share of the items before the item,
+// so the item crossing a bound still belongs to the upper class: the first
+// item is always in A.
+func abcClass(before, value decimal.Decimal, t domain.ABCXYZThresholds) domain.ABCClass {
+	switch {
+	case !value.IsPositive():
+		return domain.ClassC
+	case before.LessThan(t.A):
+		return domain.ClassA
+	case before.LessThan(t.B):
+		return domain.ClassB
+	default:
+		return domain.ClassC
+	}
+}
+
+// variation returns the total and mean monthly demand and the coefficient
+// of variation in percent: the population standard deviation over the mean.
+// The coefficient is nil when there is no demand.
+func variation(monthly []decimal.Decimal) (decimal.Decimal, decimal.Decimal, *decimal.Decimal) {
+	total := decimal.Zero
+	for _, q := range monthly {
+		total = total.Add(q)
+	}
+	if len(monthly) == 0 || !total.IsPositive() {
+		return total, decimal.Zero, nil
+	}
+	mean := total.Div(decimal.NewFromInt(int64(len(monthly))))
+
+	m := mean.InexactFloat64()
+	var sum float64
+	for _, q := range monthly {
+		d := q.InexactFloat64() - m
+		sum += d * d
+	}
+	cv := decimal.NewFromFloat(math.Sqrt(sum/float64(len(monthly))) / m * 100).Round(2)
+	return total, mean.Round(3), &cv
+}
+
+func xyzClass(cv *decimal.Decimal, t domain.ABCXYZThresholds) domain.XYZClass {
+	switch {
+	case cv == nil:
+		return domain.ClassZ
+	case cv.LessThanOrEqual(t.X):
+		return domain.ClassX
+	case cv.LessThanOrEqual(t.Y):
+		return domain.ClassY
+	default:
+		return domain.ClassZ
+	}
+}
+
+// Matrix counts the items and sums their value per pair of classes; every
+// cell is present, in A-X, A-Y, ..., C-Z order. Share is in percent of
+// total.
+func Matrix(items []domain.ABCXYZItem, total decimal.Decimal) []domain.ABCXYZCell {
+	cells := make([]domain.ABCXYZCell, 0, len(domain.ABCClasses)*len(domain.XYZClasses))
+	index := make(map[[2]string]int)
+	for _, abc := range domain.ABCClasses {
+		for _, xyz := range domain.XYZClasses {
+			index[[2]string{string(abc), string(xyz)}] = len(cells)
+			cells = append(cells, domain.ABCXYZCell{ABC: abc, XYZ: xyz})
+		}
+	}
+
+	for _, it := range items {
+		c := &cells[index[[2]string{string(it.ABC), string(it.XYZ)}]]
+		c.Items++
+		c.Value = c.Value.Add(it.Value)
+	}
+	if total.IsPositive() {
+		for i := range cells {
+			cells[i].Share = cells[i].Value.Div(total).Mul(hundred).Round(2)
+		}
+	}
+	return cells
+}
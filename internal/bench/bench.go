@@ -0,0 +1,91 @@
+// Package bench measures query strategies and summarizes the measurements
+// into latency percentiles, rows read and heap allocations.
+package bench
+
+import (
+	"math"
+	"runtime"
+	"sort"
+	"time"
+
+	"github.com/student/my-kpfu-db-app/internal/domain"
+)
+
+// Sample is one run of a strategy.
+type Sample struct {
+	Duration   time.Duration
+	ResultRows int
+	RowsRead   int64
+	AllocBytes uint64
+	Allocs     uint64
+}
+
+// Measure runs fn once. fn returns the number of result rows; rowsRead is a
+// counter the database layer increments for every row it reads, reset here
+// before the run. Allocations are process-wide, so nothing else should run
+// concurrently.
+func Measure(fn func() (int, error), rowsRead *int64) (Sample, error) {
+	var before, after runtime.MemStats
+	*rowsRead = 0
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	n, err := fn()
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	return Sample{
+		Duration:   elapsed,
+		ResultRows: n,
+		RowsRead:   *rowsRead,
+		AllocBytes: after.TotalAlloc - before.TotalAlloc,
+		Allocs:     after.Mallocs - before.Mallocs,
+	}, err
+}
+
+// Summarize computes the result of a strategy from its samples. Rows and
+// allocations are averaged over the samples.
+func Summarize(strategy string, samples []Sample) domain.BenchResult {
+	res := domain.BenchResult{Strategy: strategy}
+	if len(samples) == 0 {
+		return res
+	}
+
+	ms := make([]float64, len(samples))
+	var total float64
+	var rowsRead int64
+	var allocBytes, allocs uint64
+	for i, s := range samples {
+		ms[i] = float64(s.Duration.Microseconds()) / 1000
+		total += ms[i]
+		rowsRead += s.RowsRead
+		allocBytes += s.AllocBytes
+		allocs += s.Allocs
+	}
+	sort.Float64s(ms)
+
+	n := len(samples)
+	res.MinMS = ms[0]
+	res.P50MS = Percentile(ms, 50)
+	res.P90MS = Percentile(ms, 90)
+	res.P99MS = Percentile(ms, 99)
+	res.MaxMS = ms[n-1]
+	res.MeanMS = math.Round(total/float64(n)*1000) / 1000
+	res.ResultRows = samples[n-1].ResultRows
+	res.RowsRead = rowsRead / int64(n)
+	res.AllocBytes = allocBytes / uint64(n)
+	res.Allocs = allocs / uint64(n)
+	return res
+}
+
+// Percentile returns the p-th percentile of sorted values by the nearest
+// rank method.
+func Percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
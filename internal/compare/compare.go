@@ -0,0 +1,83 @@
+// Package compare checks that twin implementations of a query (SQL against
+// ORM or record-based) return the same rows, and generates random datasets
+// and queries to run the check on.
+package compare
+
+import (
+	"fmt"
+
+	"github.com/student/my-kpfu-db-app/internal/domain"
+)
+
+// Diff fills the row counts and differences of c. Rows are compared as
+// multisets of value; rows left unmatched on both sides are paired by key
+// into Different. value must include key.
+func Diff[T any](c *domain.Comparison, left, right []T, key, value func(T) string) {
+	c.LeftRows, c.RightRows = len(left), len(right)
+	c.Missing, c.Extra, c.Different = []any{}, []any{}, []domain.RowDiff{}
+
+	pool := make(map[string][]int)
+	for i, row := range left {
+		pool[value(row)] = append(pool[value(row)], i)
+	}
+	matched := make([]bool, len(left))
+	var extra []T
+	for _, row := range right {
+		v := value(row)
+		if idx := pool[v]; len(idx) > 0 {
+			matched[idx[0]] = true
+			pool[v] = idx[1:]
+			continue
+		}
+		extra = append(extra, row)
+	}
+
+	// Оставшиеся строки с одинаковым ключом считаются измененными
+	byKey := make(map[string][]int)
+	for i, row := range extra {
+		byKey[key(row)] = append(byKey[key(row)], i)
+	}
+	paired := make([]bool, len(extra))
+	for i, row := range left {
+		if matched[i] {
+			continue
+		}
+		k := key(row)
+		if idx := byKey[k]; len(idx) > 0 {
+			paired[idx[0]] = true
+			byKey[k] = idx[1:]
+			c.Different = append(c.Different, domain.RowDiff{Key: k, Left: row, Right: extra[idx[0]]})
+			continue
+		}
+		c.Missing = append(c.Missing, row)
+	}
+	for i, row := range extra {
+		if !paired[i] {
+			c.Extra = append(c.Extra, row)
+		}
+	}
+
+	c.Equal = len(c.Missing) == 0 && len(c.Extra) == 0 && len(c.Different) == 0
+}
+
+// Task1Key identifies a Task 1 row; shipments have no other identity in
+// the result.
+func Task1Key(r domain.Task1Result) string {
+	return fmt.Sprintf("%d|%s|%s|%s", r.WarehouseNo, r.PartCode, r.ShipmentDate.Format("2006-01-02"), r.CustomerName)
+}
+
+// Task1Value is the canonical form of a Task 1 row. Dates are compared by
+// day and quantities by value, since drivers differ in time zones and scale.
+func Task1Value(r domain.Task1Result) string {
+	return Task1Key(r) + "|" + r.Qty.String()
+}
+
+// Task3Key identifies a Task 3 row.
+func Task3Key(r domain.Task3Result) string {
+	return fmt.Sprint(r.CustomerID)
+}
+
+// Task3Value is the canonical form of a Task 3 row.
+func Task3Value(r domain.Task3Result) string {
+	return fmt.Sprintf("%d|%s|%s", r.CustomerID, r.CustomerName, r.CustomerCity)
+}
@@ -0,0 +1,139 @@
+package compare
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/student/my-kpfu-db-app/internal/domain"
+)
+
+type row struct {
+	id  int
+	val string
+}
+
+func rowKey(r row) string   { return fmt.Sprint(r.id) }
+func rowValue(r row) string { return fmt.Sprintf("%d|%s", r.id, r.val) }
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name        string
+		left, right []row
+
+		equal     bool
+		missing   []any
+		extra     []any
+		different []domain.RowDiff
+	}{
+		{
+			name:  "both empty",
+			equal: true,
+		},
+		{
+			name:  "same rows in another order",
+			left:  []row{{1, "a"}, {2, "b"}, {3, "c"}},
+			right: []row{{3, "c"}, {1, "a"}, {2, "b"}},
+			equal: true,
+		},
+		{
+			name:    "missing row",
+			left:    []row{{1, "a"}, {2, "b"}},
+			right:   []row{{1, "a"}},
+			missing: []any{row{2, "b"}},
+		},
+		{
+			name:  "extra row",
+			left:  []row{{1, "a"}},
+			right: []row{{1, "a"}, {2, "b"}},
+			extra: []any{row{2, "b"}},
+		},
+		{
+			name:      "different value for the same key",
+			left:      []row{{1, "a"}, {2, "b"}},
+			right:     []row{{1, "a"}, {2, "x"}},
+			different: []domain.RowDiff{{Key: "2", Left: row{2, "b"}, Right: row{2, "x"}}},
+		},
+		{
+			name:  "duplicates with the same multiplicity",
+			left:  []row{{1, "a"}, {1, "a"}, {2, "b"}},
+			right: []row{{2, "b"}, {1, "a"}, {1, "a"}},
+			equal: true,
+		},
+		{
+			name:    "duplicate missing on the right",
+			left:    []row{{1, "a"}, {1, "a"}},
+			right:   []row{{1, "a"}},
+			missing: []any{row{1, "a"}},
+		},
+		{
+			name:  "duplicate extra on the right",
+			left:  []row{{1, "a"}},
+			right: []row{{1, "a"}, {1, "a"}},
+			extra: []any{row{1, "a"}},
+		},
+		{
+			name:      "duplicate key paired once",
+			left:      []row{{1, "a"}, {1, "b"}},
+			right:     []row{{1, "c"}},
+			different: []domain.RowDiff{{Key: "1", Left: row{1, "a"}, Right: row{1, "c"}}},
+			missing:   []any{row{1, "b"}},
+		},
+		{
+			name:      "missing, extra and different together",
+			left:      []row{{1, "a"}, {2, "b"}, {3, "c"}},
+			right:     []row{{2, "x"}, {3, "c"}, {4, "d"}},
+			missing:   []any{row{1, "a"}},
+			extra:     []any{row{4, "d"}},
+			different: []domain.RowDiff{{Key: "2", Left: row{2, "b"}, Right: row{2, "x"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c domain.Comparison
+			Diff(&c, tt.left, tt.right, rowKey, rowValue)
+
+			if c.LeftRows != len(tt.left) || c.RightRows != len(tt.right) {
+				t.Errorf("rows = %d/%d, want %d/%d", c.LeftRows, c.RightRows, len(tt.left), len(tt.right))
+			}
+			if c.Equal != tt.equal {
+				t.Errorf("Equal = %v, want %v", c.Equal, tt.equal)
+			}
+			// Пустые различия - пустые срезы, а не nil: так они выводятся в JSON как []
+			if tt.missing == nil {
+				tt.missing = []any{}
+			}
+			if tt.extra == nil {
+				tt.extra = []any{}
+			}
+			if tt.different == nil {
+				tt.different = []domain.RowDiff{}
+			}
+			if !reflect.DeepEqual(c.Missing, tt.missing) {
+				t.Errorf("Missing = %v, want %v", c.Missing, tt.missing)
+			}
+			if !reflect.DeepEqual(c.Extra, tt.extra) {
+				t.Errorf("Extra = %v, want %v", c.Extra, tt.extra)
+			}
+			if !reflect.DeepEqual(c.Different, tt.different) {
+				t.Errorf("Different = %v, want %v", c.Different, tt.different)
+			}
+		})
+	}
+}
+
+func TestGenerateIsDeterministic(t *testing.T) {
+	a, b := Random(42, 10), Random(42, 10)
+	if !reflect.DeepEqual(a, b) {
+		t.Fatal("the same seed gave different datasets")
+	}
+	if len(a.Customers) != 10 || len(a.Parts) != 10 || len(a.Shipments) != 50 {
+		t.Fatalf("got %d customers, %d parts, %d shipments", len(a.Customers), len(a.Parts), len(a.Shipments))
+	}
+	for _, s := range a.Shipments {
+		if s.CustomerID < 0 || s.CustomerID >= len(a.Customers) {
+			t.Fatalf("shipment customer index %d out of range", s.CustomerID)
+		}
+	}
+}
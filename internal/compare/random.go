@@ -0,0 +1,125 @@
+package compare
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/student/my-kpfu-db-app/internal/domain"
+)
+
+// Dataset is a random set of cities, customers, parts and shipments. Customer
+// IDs and shipment document numbers are indexes: the database assigns the
+// real ones when the dataset is loaded. Shipment.CustomerID is an index into
+// Customers.
+type Dataset struct {
+	Cities    []string
+	Customers []domain.Customer
+	Parts     []domain.Part
+	Shipments []domain.Shipment
+}
+
+// Random generates a dataset of size customers and parts and five shipments
+// per customer from seed; the same seed gives the same dataset.
+func Random(seed int64, size int) Dataset {
+	return Generate(seed, domain.Volumes{Customers: size, Parts: size, Shipments: size * 5})
+}
+
+// Generate generates a dataset of the given volumes from seed. Names are
+// unique per seed, so the dataset can be loaded next to existing data. There
+// is a city per 20 customers, but at least 3.
+func Generate(seed int64, v domain.Volumes) Dataset {
+	rng := rand.New(rand.NewSource(seed))
+	var ds Dataset
+
+	for i := 0; i < max(3, v.Customers/20); i++ {
+		ds.Cities = append(ds.Cities, fmt.Sprintf("Тестоград %d-%d", seed, i))
+	}
+	for i := 0; i < v.Customers; i++ {
+		ds.Customers = append(ds.Customers, domain.Customer{
+			CustomerID: i,
+			// Повторяющиеся имена проверяют сравнение с учетом кратности
+			Name:     fmt.Sprintf("Покупатель %d-%d", seed, rng.Intn(v.Customers)),
+			City:     ds.Cities[rng.Intn(len(ds.Cities))],
+			Currency: "RUB",
+		})
+	}
+	for i := 0; i < v.Parts; i++ {
+		ds.Parts = append(ds.Parts, domain.Part{
+			PartCode:  fmt.Sprintf("RND%d-%d", seed, i),
+			PartType:  domain.PartTypes[rng.Intn(len(domain.PartTypes))],
+			Name:      fmt.Sprintf("Деталь %d", i),
+			Unit:      "шт",
+			PlanPrice: decimal.New(rng.Int63n(30000), -2),
+			Currency:  "RUB",
+		})
+	}
+	if len(ds.Customers) == 0 || len(ds.Parts) == 0 {
+		return ds
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	for i := 0; i < v.Shipments; i++ {
+		part := ds.Parts[rng.Intn(len(ds.Parts))]
+		ds.Shipments = append(ds.Shipments, domain.Shipment{
+			WarehouseNo:   1 + rng.Intn(6),
+			ShipmentDocNo: i,
+			CustomerID:    rng.Intn(len(ds.Customers)),
+			PartCode:      part.PartCode,
+			Unit:          part.Unit,
+			Qty:           decimal.New(1+rng.Int63n(2000), -2),
+			Price:         part.PlanPrice,
+			ShipmentDate:  today.AddDate(0, 0, -rng.Intn(730)),
+			Status:        domain.ShipmentStatuses[rng.Intn(len(domain.ShipmentStatuses))],
+		})
+	}
+	return ds
+}
+
+// RandomTask3Query generates a valid Task 3 query with random quantifiers
+// and predicates.
+func RandomTask3Query(rng *rand.Rand) domain.Task3Query {
+	q := domain.Task3Query{
+		Parts:     domain.PartQuantifiers[rng.Intn(len(domain.PartQuantifiers))],
+		Shipments: domain.ShipmentQuantifiers[rng.Intn(len(domain.ShipmentQuantifiers))],
+	}
+	if rng.Intn(2) == 0 {
+		p := decimal.NewFromInt(rng.Int63n(200))
+		q.Part.PriceAbove = &p
+	}
+	if rng.Intn(3) == 0 {
+		p := decimal.NewFromInt(100 + rng.Int63n(200))
+		q.Part.PriceBelow = &p
+	}
+	if rng.Intn(3) == 0 {
+		q.Part.PartTypes = []string{domain.PartTypes[rng.Intn(len(domain.PartTypes))]}
+	}
+	for w := 1; w <= 6; w++ {
+		if rng.Intn(3) == 0 {
+			q.Shipment.Warehouses = append(q.Shipment.Warehouses, w)
+		}
+	}
+	if rng.Intn(2) == 0 {
+		from := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -rng.Intn(730))
+		to := from.AddDate(0, 0, rng.Intn(365))
+		q.Shipment.DateFrom, q.Shipment.DateTo = &from, &to
+	}
+	return q
+}
+
+// CitySpelling returns a random way to type city, as accepted by Task 1:
+// with the "г." prefix, in another case or with extra spaces.
+func CitySpelling(rng *rand.Rand, city string) string {
+	switch rng.Intn(4) {
+	case 0:
+		return "г. " + city
+	case 1:
+		return "  " + city + " "
+	case 2:
+		return strings.ToUpper(city)
+	default:
+		return city
+	}
+}
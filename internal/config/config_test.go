@@ -0,0 +1,39 @@
+package config
+
+import "testing"
+
+func TestLoadVATRate(t *testing.T) {
+	tests := []struct {
+		env     string
+		want    string
+		wantErr bool
+	}{
+		{env: "", want: "20"},
+		{env: "0", want: "0"},
+		{env: "10", want: "10"},
+		{env: "12.25", want: "12.25"},
+		{env: "100", want: "100"},
+		{env: "-1", wantErr: true},
+		{env: "100.01", wantErr: true},
+		{env: "20.125", wantErr: true},
+		{env: "двадцать", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("VAT_RATE", tt.env)
+		cfg, err := Load()
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("VAT_RATE=%q: got %s, want an error", tt.env, cfg.VATRate)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("VAT_RATE=%q: %v", tt.env, err)
+			continue
+		}
+		if cfg.VATRate.String() != tt.want {
+			t.Errorf("VAT_RATE=%q: got %s, want %s", tt.env, cfg.VATRate, tt.want)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+// Package dedup finds customers that are likely the same company entered
+// more than once: names are compared after dropping legal forms, quotes and
+// punctuation, addresses after dropping street type abbreviations.
+package dedup
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/student/my-kpfu-db-app/internal/domain"
+)
+
+// DefaultThreshold is the name similarity from which two customers are
+// reported as possible duplicates.
+const DefaultThreshold = 0.8
+
+// legalForms are dropped from names: "ООО Техноком" and "Техноком" are the
+// same company.
+var legalForms = map[string]bool{
+	"ооо": true, "зао": true, "оао": true, "пао": true, "ао": true, "ип": true,
+	"нко": true, "тоо": true, "llc": true, "ltd": true,
+}
+
+// addressWords are street type and building abbreviations dropped from
+// addresses: "ул. Баумана, 15" and "улица Баумана, д. 15" are the same.
+var addressWords = map[string]bool{
+	"ул": true, "улица": true, "пр": true, "пр-т": true, "проспект": true,
+	"пер": true, "переулок": true, "бул": true, "бульвар": true, "б-р": true,
+	"ш": true, "шоссе": true, "пл": true, "площадь": true, "д": true, "дом": true,
+}
+
+// NormalizeName reduces a company name to lower-case words without legal
+// forms, quotes and punctuation.
+func NormalizeName(name string) string {
+	return normalize(name, legalForms)
+}
+
+// NormalizeAddress reduces a street address to lower-case words without
+// street type abbreviations and punctuation.
+func NormalizeAddress(address string) string {
+	if address == domain.NoAddress {
+		return ""
+	}
+	return normalize(address, addressWords)
+}
+
+func normalize(s string, drop map[string]bool) string {
+	s = strings.ReplaceAll(strings.ToLower(s), "ё", "е")
+	words := strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-'
+	})
+	kept := words[:0]
+	for _, w := range words {
+		if !drop[w] {
+			kept = append(kept, w)
+		}
+	}
+	return strings.Join(kept, " ")
+}
+
+// Similarity returns 1 - edit distance / length of the longer string, from
+// 0 for nothing in common to 1 for equal strings.
+func Similarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longer := max(len(ra), len(rb))
+	if longer == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longer)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// Find compares every pair of customers and returns the pairs whose names
+// are at least threshold similar, or which share city and address. In each
+// pair Customer is the earlier record, suggested to survive the merge.
+// Pairs are sorted by similarity, most similar first.
+func Find(customers []domain.Customer, threshold float64) []domain.DuplicateCandidate {
+	type prepared struct {
+		name, address string
+	}
+	norm := make([]prepared, len(customers))
+	for i, c := range customers {
+		norm[i] = prepared{NormalizeName(c.Name), NormalizeAddress(c.Address)}
+	}
+
+	candidates := []domain.DuplicateCandidate{}
+	for i := range customers {
+		for j := i + 1; j < len(customers); j++ {
+			a, b := customers[i], customers[j]
+			if b.CustomerID < a.CustomerID {
+				a, b = b, a
+			}
+			d := domain.DuplicateCandidate{
+				Customer:       a,
+				Duplicate:      b,
+				NameSimilarity: Similarity(norm[i].name, norm[j].name),
+				SameCity:       a.City == b.City,
+				SameAddress:    norm[i].address != "" && norm[i].address == norm[j].address,
+			}
+			if d.NameSimilarity >= threshold || (d.SameCity && d.SameAddress) {
+				candidates = append(candidates, d)
+			}
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.NameSimilarity != b.NameSimilarity {
+			return a.NameSimilarity > b.NameSimilarity
+		}
+		if a.Customer.CustomerID != b.Customer.CustomerID {
+			return a.Customer.CustomerID < b.Customer.CustomerID
+		}
+		return a.Duplicate.CustomerID < b.Duplicate.CustomerID
+	})
+	return candidates
+}
@@ -0,0 +1,107 @@
+package document
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/shopspring/decimal"
+	"github.com/student/my-kpfu-db-app/internal/domain"
+)
+
+// Invoice renders a customer invoice with one line per billed shipment.
+// The amount in words is printed for ruble invoices only.
+func (r *Renderer) Invoice(w io.Writer, inv *domain.Invoice) error {
+	pdf, err := r.newPDF("P")
+	if err != nil {
+		return err
+	}
+	pdf.AddPage()
+
+	pdf.SetFont(fontFamily, "B", 14)
+	title := fmt.Sprintf("СЧЕТ № %s от %s", inv.Number, inv.IssueDate.Format("02.01.2006"))
+	pdf.CellFormat(0, 8, title, "", 1, "C", false, 0, "")
+	if inv.Status == domain.InvoiceVoid {
+		pdf.SetTextColor(200, 0, 0)
+		pdf.CellFormat(0, 7, "АННУЛИРОВАН", "", 1, "C", false, 0, "")
+		pdf.SetTextColor(0, 0, 0)
+	}
+	pdf.SetFont(fontFamily, "", 9)
+	period := fmt.Sprintf("за период с %s по %s",
+		inv.PeriodFrom.Format("02.01.2006"), inv.PeriodTo.Format("02.01.2006"))
+	pdf.CellFormat(0, 6, period, "", 1, "C", false, 0, "")
+	pdf.Ln(4)
+
+	r.labelled(pdf, "Поставщик:", r.supplier)
+	r.labelled(pdf, "Покупатель:", inv.CustomerName)
+	r.labelled(pdf, "Адрес:", joinNonEmpty(", ", inv.CustomerCity, inv.CustomerAddress))
+	r.labelled(pdf, "Валюта:", inv.Currency)
+	pdf.Ln(4)
+
+	widths := []float64{8, 24, 20, 56, 14, 22, 22, 24}
+	headers := []string{"№", "Документ", "Дата", "Наименование", "Ед.", "Кол-во", "Цена", "Сумма"}
+	aligns := []string{"C", "L", "C", "L", "C", "R", "R", "R"}
+
+	pdf.SetFont(fontFamily, "B", 8)
+	pdf.SetFillColor(230, 230, 230)
+	for i, h := range headers {
+		pdf.CellFormat(widths[i], 7, h, "1", 0, "C", true, 0, "")
+	}
+	pdf.Ln(-1)
+
+	pdf.SetFont(fontFamily, "", 8)
+	for _, line := range inv.Lines {
+		cells := []string{
+			fmt.Sprint(line.LineNo),
+			fmt.Sprintf("%d/%d", line.WarehouseNo, line.ShipmentDocNo),
+			line.ShipmentDate.Format("02.01.2006"),
+			line.PartCode + " " + line.PartName,
+			line.Unit,
+			formatNumber(line.Qty), formatNumber(line.Price), formatNumber(line.Amount),
+		}
+		for j, cell := range cells {
+			pdf.CellFormat(widths[j], 6, cell, "1", 0, aligns[j], false, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+
+	var labelWidth float64
+	for _, width := range widths[:len(widths)-1] {
+		labelWidth += width
+	}
+	totals := []struct {
+		label string
+		value decimal.Decimal
+	}{
+		{"Итого без НДС:", inv.NetAmount},
+		{fmt.Sprintf("НДС %s%%:", inv.VATRate), inv.VATAmount},
+		{"Всего к оплате:", inv.TotalAmount},
+	}
+	pdf.SetFont(fontFamily, "B", 9)
+	for _, t := range totals {
+		pdf.CellFormat(labelWidth, 6, t.label, "", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[len(widths)-1], 6, formatNumber(t.value), "1", 1, "R", false, 0, "")
+	}
+	pdf.Ln(4)
+
+	pdf.SetFont(fontFamily, "", 9)
+	currency := inv.Currency
+	if currency == domain.BaseCurrency {
+		currency = "руб."
+	}
+	pdf.MultiCell(0, 6, fmt.Sprintf("Всего наименований %d, на сумму %s %s", len(inv.Lines), formatNumber(inv.TotalAmount), currency), "", "L", false)
+	if inv.Currency == domain.BaseCurrency {
+		words, err := AmountInWords(inv.TotalAmount)
+		if err != nil {
+			return err
+		}
+		pdf.SetFont(fontFamily, "B", 9)
+		pdf.MultiCell(0, 6, words, "", "L", false)
+	}
+	pdf.Ln(12)
+
+	pdf.SetFont(fontFamily, "", 9)
+	pdf.CellFormat(95, 6, "Руководитель ____________________", "", 0, "L", false, 0, "")
+	pdf.CellFormat(0, 6, "Бухгалтер ____________________", "", 1, "L", false, 0, "")
+
+	return pdf.Output(w)
+}
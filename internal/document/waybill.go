@@ -0,0 +1,171 @@
+// Package document renders printable shipping paperwork as PDF.
+// Everything is generated in-process with fpdf; the only external input
+// is a TrueType font with Cyrillic glyphs.
+package document
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/shopspring/decimal"
+	"github.com/student/my-kpfu-db-app/internal/domain"
+)
+
+const fontFamily = "DejaVu"
+
+// Renderer produces PDF documents.
+type Renderer struct {
+	fontDir  string
+	supplier string
+}
+
+// NewRenderer creates a Renderer that loads DejaVuSans.ttf and
+// DejaVuSans-Bold.ttf from fontDir; supplier is printed as the shipper.
+func NewRenderer(fontDir, supplier string) *Renderer {
+	return &Renderer{fontDir: fontDir, supplier: supplier}
+}
+
+// newPDF creates an A4 document with the Cyrillic fonts registered.
+func (r *Renderer) newPDF(orientation string) (*fpdf.Fpdf, error) {
+	pdf := fpdf.New(orientation, "mm", "A4", "")
+	for style, file := range map[string]string{"": "DejaVuSans.ttf", "B": "DejaVuSans-Bold.ttf"} {
+		data, err := os.ReadFile(filepath.Join(r.fontDir, file))
+		if err != nil {
+			return nil, fmt.Errorf("load font: %w", err)
+		}
+		pdf.AddUTF8FontFromBytes(fontFamily, style, data)
+	}
+	pdf.SetMargins(10, 10, 10)
+	pdf.SetAutoPageBreak(true, 15)
+	return pdf, pdf.Error()
+}
+
+// Waybill renders a ТОРГ-12-style consignment note for one shipment
+// document. Lines share warehouse, document number, date and customer.
+// Prices are printed in rubles at the shipment date rate.
+func (r *Renderer) Waybill(w io.Writer, lines []domain.FullShipmentInfo) error {
+	if len(lines) == 0 {
+		return fmt.Errorf("waybill has no lines")
+	}
+	head := lines[0]
+
+	pdf, err := r.newPDF("L")
+	if err != nil {
+		return err
+	}
+	pdf.AddPage()
+
+	pdf.SetFont(fontFamily, "", 7)
+	pdf.CellFormat(0, 4, "Унифицированная форма № ТОРГ-12", "", 1, "R", false, 0, "")
+
+	pdf.SetFont(fontFamily, "", 9)
+	r.labelled(pdf, "Грузоотправитель / Поставщик:", fmt.Sprintf("%s, склад № %d", r.supplier, head.WarehouseNo))
+	r.labelled(pdf, "Грузополучатель / Плательщик:", head.CustomerName)
+	r.labelled(pdf, "Адрес:", joinNonEmpty(", ", head.CustomerCity, head.CustomerAddress))
+	pdf.Ln(4)
+
+	pdf.SetFont(fontFamily, "B", 13)
+	title := fmt.Sprintf("ТОВАРНАЯ НАКЛАДНАЯ № %d от %s", head.ShipmentDocNo, head.ShipmentDate.Format("02.01.2006"))
+	pdf.CellFormat(0, 8, title, "", 1, "C", false, 0, "")
+	pdf.Ln(2)
+
+	widths := []float64{10, 25, 112, 20, 30, 40, 40}
+	headers := []string{"№", "Код", "Наименование", "Ед. изм.", "Количество", "Цена, руб.", "Сумма, руб."}
+	aligns := []string{"C", "L", "L", "C", "R", "R", "R"}
+
+	pdf.SetFont(fontFamily, "B", 9)
+	pdf.SetFillColor(230, 230, 230)
+	for i, h := range headers {
+		pdf.CellFormat(widths[i], 7, h, "1", 0, "C", true, 0, "")
+	}
+	pdf.Ln(-1)
+
+	pdf.SetFont(fontFamily, "", 9)
+	var totalQty, total decimal.Decimal
+	for i, line := range lines {
+		price := domain.RoundMoney(line.UnitPrice.Mul(line.ExchangeRate))
+		amount := domain.RoundMoney(line.ShippedQty.Mul(price))
+		totalQty = totalQty.Add(line.ShippedQty)
+		total = total.Add(amount)
+
+		cells := []string{
+			fmt.Sprint(i + 1), line.PartCode, line.PartName, line.Unit,
+			formatNumber(line.ShippedQty), formatNumber(price), formatNumber(amount),
+		}
+		for j, cell := range cells {
+			pdf.CellFormat(widths[j], 7, cell, "1", 0, aligns[j], false, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+
+	pdf.SetFont(fontFamily, "B", 9)
+	labelWidth := widths[0] + widths[1] + widths[2] + widths[3]
+	pdf.CellFormat(labelWidth, 7, "Итого", "1", 0, "R", false, 0, "")
+	pdf.CellFormat(widths[4], 7, formatNumber(totalQty), "1", 0, "R", false, 0, "")
+	pdf.CellFormat(widths[5], 7, "X", "1", 0, "C", false, 0, "")
+	pdf.CellFormat(widths[6], 7, formatNumber(total), "1", 1, "R", false, 0, "")
+	pdf.Ln(4)
+
+	pdf.SetFont(fontFamily, "", 9)
+	pdf.CellFormat(0, 6, fmt.Sprintf("Всего наименований: %d, на сумму %s руб.", len(lines), formatNumber(total)), "", 1, "L", false, 0, "")
+	words, err := AmountInWords(total)
+	if err != nil {
+		return err
+	}
+	pdf.SetFont(fontFamily, "B", 9)
+	pdf.MultiCell(0, 6, "Всего отпущено на сумму: "+words, "", "L", false)
+	pdf.Ln(10)
+
+	pdf.SetFont(fontFamily, "", 9)
+	pdf.CellFormat(135, 6, "Отпуск груза разрешил ____________________ / ____________", "", 0, "L", false, 0, "")
+	pdf.CellFormat(0, 6, "Груз принял ____________________ / ____________", "", 1, "L", false, 0, "")
+	pdf.Ln(3)
+	pdf.CellFormat(135, 6, "Отпуск груза произвел ____________________ / ____________", "", 0, "L", false, 0, "")
+	pdf.CellFormat(0, 6, "Груз получил грузополучатель ____________________ / ____________", "", 1, "L", false, 0, "")
+	pdf.Ln(3)
+	pdf.CellFormat(135, 6, "М.П.", "", 0, "L", false, 0, "")
+	pdf.CellFormat(0, 6, "М.П.", "", 1, "L", false, 0, "")
+
+	return pdf.Output(w)
+}
+
+func (r *Renderer) labelled(pdf *fpdf.Fpdf, label, value string) {
+	pdf.SetFont(fontFamily, "B", 9)
+	pdf.CellFormat(65, 6, label, "", 0, "L", false, 0, "")
+	pdf.SetFont(fontFamily, "", 9)
+	pdf.CellFormat(0, 6, value, "B", 1, "L", false, 0, "")
+}
+
+// formatNumber formats a value with two decimals, a space as the thousands
+// separator and a decimal comma: 1 649,99.
+func formatNumber(v decimal.Decimal) string {
+	s := v.StringFixed(2)
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	intPart, frac := s[:len(s)-3], s[len(s)-2:]
+
+	var b strings.Builder
+	for i, d := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteRune(' ')
+		}
+		b.WriteRune(d)
+	}
+	return sign + b.String() + "," + frac
+}
+
+func joinNonEmpty(sep string, parts ...string) string {
+	var out []string
+	for _, p := range parts {
+		if p != "" {
+			out = append(out, p)
+		}
+	}
+	return strings.Join(out, sep)
+}
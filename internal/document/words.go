@@ -0,0 +1,135 @@
+package document
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+	"github.com/student/my-kpfu-db-app/internal/domain"
+)
+
+var (
+	unitsMasc = []string{"", "один", "два", "три", "четыре", "пять", "шесть", "семь", "восемь", "девять"}
+	unitsFem  = []string{"", "одна", "две", "три", "четыре", "пять", "шесть", "семь", "восемь", "девять"}
+	teens     = []string{"десять", "одиннадцать", "двенадцать", "тринадцать", "четырнадцать",
+		"пятнадцать", "шестнадцать", "семнадцать", "восемнадцать", "девятнадцать"}
+	tens = []string{"", "", "двадцать", "тридцать", "сорок", "пятьдесят",
+		"шестьдесят", "семьдесят", "восемьдесят", "девяносто"}
+	hundreds = []string{"", "сто", "двести", "триста", "четыреста", "пятьсот",
+		"шестьсот", "семьсот", "восемьсот", "девятьсот"}
+)
+
+// scale describes a group of three digits: its word forms for 1, 2-4 and 5+
+// and the grammatical gender of the numeral before it.
+type scale struct {
+	forms    [3]string
+	feminine bool
+}
+
+var scales = []scale{
+	{forms: [3]string{"", "", ""}},
+	{forms: [3]string{"тысяча", "тысячи", "тысяч"}, feminine: true},
+	{forms: [3]string{"миллион", "миллиона", "миллионов"}},
+	{forms: [3]string{"миллиард", "миллиарда", "миллиардов"}},
+	{forms: [3]string{"триллион", "триллиона", "триллионов"}},
+}
+
+// maxAmount is the first amount that cannot be spelled with scales: 10^15.
+var maxAmount = decimal.New(1, int32(3*len(scales)))
+
+// AmountInWords spells a ruble amount in Russian the way it is written on
+// shipping documents: "Одна тысяча шестьсот сорок девять рублей 99 копеек".
+// Negative amounts and amounts of maxAmount or more are rejected rather than
+// printed wrong.
+func AmountInWords(amount decimal.Decimal) (string, error) {
+	amount = domain.RoundMoney(amount)
+	if amount.IsNegative() {
+		return "", fmt.Errorf("amount in words: negative amount %s", amount)
+	}
+	if amount.GreaterThanOrEqual(maxAmount) {
+		return "", fmt.Errorf("amount in words: %s is not below %s", amount, maxAmount)
+	}
+	cents := amount.Shift(2).IntPart()
+	rubles, kopecks := cents/100, cents%100
+
+	words := numberInWords(rubles, false)
+	text := fmt.Sprintf("%s %s %02d %s", words,
+		plural(rubles, "рубль", "рубля", "рублей"),
+		kopecks, plural(kopecks, "копейка", "копейки", "копеек"))
+	return capitalize(text), nil
+}
+
+// numberInWords spells an integer in [0, maxAmount); feminine selects
+// "одна/две" for the last group (used with feminine nouns).
+func numberInWords(n int64, feminine bool) string {
+	if n == 0 {
+		return "ноль"
+	}
+
+	var groups []string
+	for i := 0; n > 0; i++ {
+		triad := n % 1000
+		n /= 1000
+		if triad == 0 {
+			continue
+		}
+
+		fem := scales[i].feminine || (i == 0 && feminine)
+		group := triadInWords(triad, fem)
+		if i > 0 {
+			f := scales[i].forms
+			group = append(group, plural(triad, f[0], f[1], f[2]))
+		}
+		groups = append([]string{strings.Join(group, " ")}, groups...)
+	}
+	return strings.Join(groups, " ")
+}
+
+func triadInWords(n int64, feminine bool) []string {
+	var words []string
+	if h := n / 100; h > 0 {
+		words = append(words, hundreds[h])
+	}
+
+	rest := n % 100
+	switch {
+	case rest >= 10 && rest < 20:
+		words = append(words, teens[rest-10])
+	default:
+		if t := rest / 10; t > 0 {
+			words = append(words, tens[t])
+		}
+		if u := rest % 10; u > 0 {
+			if feminine {
+				words = append(words, unitsFem[u])
+			} else {
+				words = append(words, unitsMasc[u])
+			}
+		}
+	}
+	return words
+}
+
+// plural picks the Russian noun form agreeing with n.
+func plural(n int64, one, few, many string) string {
+	n %= 100
+	if n >= 11 && n <= 14 {
+		return many
+	}
+	switch n % 10 {
+	case 1:
+		return one
+	case 2, 3, 4:
+		return few
+	default:
+		return many
+	}
+}
+
+func capitalize(s string) string {
+	r := []rune(s)
+	if len(r) == 0 {
+		return s
+	}
+	return strings.ToUpper(string(r[0])) + string(r[1:])
+}
@@ -0,0 +1,67 @@
+package document
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestAmountInWords(t *testing.T) {
+	tests := []struct {
+		amount string
+		want   string
+	}{
+		{"0", "Ноль рублей 00 копеек"},
+		{"1", "Один рубль 00 копеек"},
+		{"2", "Два рубля 00 копеек"},
+		{"5", "Пять рублей 00 копеек"},
+		{"11", "Одиннадцать рублей 00 копеек"},
+		{"12", "Двенадцать рублей 00 копеек"},
+		{"13", "Тринадцать рублей 00 копеек"},
+		{"14", "Четырнадцать рублей 00 копеек"},
+		{"15", "Пятнадцать рублей 00 копеек"},
+		{"16", "Шестнадцать рублей 00 копеек"},
+		{"17", "Семнадцать рублей 00 копеек"},
+		{"18", "Восемнадцать рублей 00 копеек"},
+		{"19", "Девятнадцать рублей 00 копеек"},
+		{"21", "Двадцать один рубль 00 копеек"},
+		{"112", "Сто двенадцать рублей 00 копеек"},
+		{"1000", "Одна тысяча рублей 00 копеек"},
+		{"2000", "Две тысячи рублей 00 копеек"},
+		{"11000", "Одиннадцать тысяч рублей 00 копеек"},
+		{"1649.99", "Одна тысяча шестьсот сорок девять рублей 99 копеек"},
+		{"1000000", "Один миллион рублей 00 копеек"},
+		{"5001000", "Пять миллионов одна тысяча рублей 00 копеек"},
+		{"2000000000", "Два миллиарда рублей 00 копеек"},
+		{"1000000000000", "Один триллион рублей 00 копеек"},
+		{"999999999999999.99", "Девятьсот девяносто девять триллионов девятьсот девяносто девять миллиардов " +
+			"девятьсот девяносто девять миллионов девятьсот девяносто девять тысяч " +
+			"девятьсот девяносто девять рублей 99 копеек"},
+		{"0.01", "Ноль рублей 01 копейка"},
+		{"0.02", "Ноль рублей 02 копейки"},
+		{"0.05", "Ноль рублей 05 копеек"},
+		{"0.11", "Ноль рублей 11 копеек"},
+		{"0.21", "Ноль рублей 21 копейка"},
+		{"0.24", "Ноль рублей 24 копейки"},
+		{"10.999", "Одиннадцать рублей 00 копеек"},
+	}
+
+	for _, tt := range tests {
+		got, err := AmountInWords(decimal.RequireFromString(tt.amount))
+		if err != nil {
+			t.Errorf("AmountInWords(%s): %v", tt.amount, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("AmountInWords(%s) = %q, want %q", tt.amount, got, tt.want)
+		}
+	}
+}
+
+func TestAmountInWordsRejectsOutOfRange(t *testing.T) {
+	for _, amount := range []string{"-0.01", "-1649.99", "1000000000000000", "999999999999999.995", "1e20"} {
+		if got, err := AmountInWords(decimal.RequireFromString(amount)); err == nil {
+			t.Errorf("AmountInWords(%s) = %q, want an error", amount, got)
+		}
+	}
+}
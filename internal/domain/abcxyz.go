@@ -0,0 +1,151 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// ABCXYZSubject is what is classified: parts or customers.
+type ABCXYZSubject string
+
+const (
+	SubjectParts     ABCXYZSubject = "part"
+	SubjectCustomers ABCXYZSubject = "customer"
+)
+
+// ABCXYZSubjects lists the subjects, the default first.
+var ABCXYZSubjects = []ABCXYZSubject{SubjectParts, SubjectCustomers}
+
+var abcxyzSubjectLabels = map[ABCXYZSubject]string{
+	SubjectParts:     "Детали",
+	SubjectCustomers: "Покупатели",
+}
+
+// Valid reports whether s is a known subject.
+func (s ABCXYZSubject) Valid() bool {
+	_, ok := abcxyzSubjectLabels[s]
+	return ok
+}
+
+// Label returns the human-readable name of the subject.
+func (s ABCXYZSubject) Label() string {
+	return abcxyzSubjectLabels[s]
+}
+
+// ByValue reports whether the monthly demand of s is measured by value
+// rather than quantity: a customer buys parts in different units, whose
+// quantities cannot be added up.
+func (s ABCXYZSubject) ByValue() bool {
+	return s == SubjectCustomers
+}
+
+// ABCClass ranks an item by its contribution to the shipped value: A items
+// bring the bulk of it, C items the tail.
+type ABCClass string
+
+// XYZClass ranks an item by the stability of its monthly quantity: X is
+// steady, Z erratic or without demand.
+type XYZClass string
+
+const (
+	ClassA ABCClass = "A"
+	ClassB ABCClass = "B"
+	ClassC ABCClass = "C"
+
+	ClassX XYZClass = "X"
+	ClassY XYZClass = "Y"
+	ClassZ XYZClass = "Z"
+)
+
+// ABCClasses and XYZClasses list the classes in matrix order.
+var (
+	ABCClasses = []ABCClass{ClassA, ClassB, ClassC}
+	XYZClasses = []XYZClass{ClassX, ClassY, ClassZ}
+)
+
+// ABCXYZThresholds are the class bounds, in percent. An item is in A while
+// the cumulative share of the items before it is below A, in B while it is
+// below B, and in C after that. An item is in X when the coefficient of
+// variation of its monthly quantity is at most X, in Y when at most Y.
+type ABCXYZThresholds struct {
+	A decimal.Decimal `json:"a"`
+	B decimal.Decimal `json:"b"`
+	X decimal.Decimal `json:"x"`
+	Y decimal.Decimal `json:"y"`
+}
+
+// DefaultABCXYZThresholds returns the usual 80/95 and 10/25 bounds.
+func DefaultABCXYZThresholds() ABCXYZThresholds {
+	return ABCXYZThresholds{
+		A: decimal.NewFromInt(80),
+		B: decimal.NewFromInt(95),
+		X: decimal.NewFromInt(10),
+		Y: decimal.NewFromInt(25),
+	}
+}
+
+// Validate checks that 0 < A < B <= 100 and 0 < X < Y.
+func (t ABCXYZThresholds) Validate() error {
+	if !t.A.IsPositive() || !t.A.LessThan(t.B) || t.B.GreaterThan(decimal.NewFromInt(100)) {
+		return errors.New("ABC thresholds must satisfy 0 < a < b <= 100")
+	}
+	if !t.X.IsPositive() || !t.X.LessThan(t.Y) {
+		return errors.New("XYZ thresholds must satisfy 0 < x < y")
+	}
+	return nil
+}
+
+// ABCXYZItem is the classification of one part or customer. Value is the
+// shipped value in the base currency and Qty the demand: the quantity in
+// the unit of the part, or the value for customers (see ABCXYZSubject.ByValue);
+// Share and CumulativeShare are in percent of the total value. MeanQty is
+// the average monthly demand and CV the coefficient of variation of the
+// monthly demand in percent, nil without demand.
+type ABCXYZItem struct {
+	Key             string           `json:"key"`
+	Name            string           `json:"name"`
+	Value           decimal.Decimal  `json:"value"`
+	Share           decimal.Decimal  `json:"share"`
+	CumulativeShare decimal.Decimal  `json:"cumulative_share"`
+	Qty             decimal.Decimal  `json:"qty"`
+	MeanQty         decimal.Decimal  `json:"mean_qty"`
+	CV              *decimal.Decimal `json:"cv"`
+	ABC             ABCClass         `json:"abc"`
+	XYZ             XYZClass         `json:"xyz"`
+}
+
+// ABCXYZCell is one cell of the classification matrix.
+type ABCXYZCell struct {
+	ABC   ABCClass        `json:"abc"`
+	XYZ   XYZClass        `json:"xyz"`
+	Items int             `json:"items"`
+	Value decimal.Decimal `json:"value"`
+	Share decimal.Decimal `json:"share"`
+}
+
+// ABCXYZSnapshot is the classification of the sales in the months
+// [From, To); both bounds are first days of months. Items are ordered by value, the matrix by class. SnapshotID
+// is zero until the snapshot is saved.
+type ABCXYZSnapshot struct {
+	SnapshotID int64            `json:"snapshot_id"`
+	Subject    ABCXYZSubject    `json:"subject"`
+	From       time.Time        `json:"from"`
+	To         time.Time        `json:"to"`
+	Months     int              `json:"months"`
+	Thresholds ABCXYZThresholds `json:"thresholds"`
+	Total      decimal.Decimal  `json:"total"`
+	CreatedAt  time.Time        `json:"created_at"`
+	CreatedBy  string           `json:"created_by"`
+	Items      []ABCXYZItem     `json:"items,omitempty"`
+	Matrix     []ABCXYZCell     `json:"matrix,omitempty"`
+}
+
+// MonthPeriod returns the half-open range from the first day of the month
+// of from to the first day of the month after to.
+func MonthPeriod(from, to time.Time) (time.Time, time.Time) {
+	start := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(to.Year(), to.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, 1, 0)
+	return start, end
+}
@@ -0,0 +1,78 @@
+package domain
+
+import (
+	"strings"
+	"unicode"
+)
+
+// NoAddress is stored when a customer's street address is unknown.
+const NoAddress = "Не указан"
+
+// City is an entry of the cities reference. Customers and discount rules
+// refer to it by the canonical Name; lookups by user input go through a
+// normalized key, so "г. Казань", "казань" and "Казань" are the same city.
+type City struct {
+	Name   string `json:"name"`
+	Region string `json:"region"`
+}
+
+// Contact is a person to reach at a customer. At least one of Phone and
+// Email is set; Phone is stored as + and digits.
+type Contact struct {
+	ContactID  int64  `json:"contact_id"`
+	CustomerID int    `json:"customer_id"`
+	Name       string `json:"name"`
+	Role       string `json:"role"`
+	Phone      string `json:"phone"`
+	Email      string `json:"email"`
+	IsPrimary  bool   `json:"is_primary"`
+}
+
+// StreetAddress joins street and building into the one-line address used on
+// printed documents.
+func StreetAddress(street, building string) string {
+	street, building = strings.TrimSpace(street), strings.TrimSpace(building)
+	switch {
+	case street == "":
+		return NoAddress
+	case building == "":
+		return street
+	}
+	return street + ", " + building
+}
+
+// SplitStreetAddress splits a free-text "street, building" address at the
+// last comma. It is the inverse of StreetAddress and is used for clients that
+// still send the address as one line.
+func SplitStreetAddress(address string) (street, building string) {
+	address = strings.TrimSpace(address)
+	if address == "" || address == NoAddress {
+		return "", ""
+	}
+	i := strings.LastIndex(address, ",")
+	if i < 0 {
+		return address, ""
+	}
+	return strings.TrimSpace(address[:i]), strings.TrimSpace(address[i+1:])
+}
+
+// NormalizePhone keeps the digits of a phone number and writes Russian
+// numbers in the +7XXXXXXXXXX form; 8XXXXXXXXXX and 10-digit numbers are
+// treated as Russian.
+func NormalizePhone(phone string) string {
+	digits := strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, phone)
+	switch {
+	case digits == "":
+		return ""
+	case len(digits) == 10:
+		digits = "7" + digits
+	case len(digits) == 11 && digits[0] == '8':
+		digits = "7" + digits[1:]
+	}
+	return "+" + digits
+}
@@ -0,0 +1,39 @@
+package domain
+
+import "time"
+
+// Volumes is the size of a generated dataset.
+type Volumes struct {
+	Customers int `json:"customers"`
+	Parts     int `json:"parts"`
+	Shipments int `json:"shipments"`
+}
+
+// BenchResult summarizes the runs of one query strategy. Latencies are in
+// milliseconds. ResultRows is what the strategy returned, RowsRead what it
+// read from the database; RowsRead, AllocBytes and Allocs are per run.
+type BenchResult struct {
+	Strategy   string  `json:"strategy"`
+	MinMS      float64 `json:"min_ms"`
+	P50MS      float64 `json:"p50_ms"`
+	P90MS      float64 `json:"p90_ms"`
+	P99MS      float64 `json:"p99_ms"`
+	MaxMS      float64 `json:"max_ms"`
+	MeanMS     float64 `json:"mean_ms"`
+	ResultRows int     `json:"result_rows"`
+	RowsRead   int64   `json:"rows_read"`
+	AllocBytes uint64  `json:"alloc_bytes"`
+	Allocs     uint64  `json:"allocs"`
+}
+
+// BenchRun is one benchmark: every strategy run Iterations times over a
+// dataset generated from Seed with the given Volumes.
+type BenchRun struct {
+	RunID      int64         `json:"run_id"`
+	RunAt      time.Time     `json:"run_at"`
+	RunBy      string        `json:"run_by"`
+	Seed       int64         `json:"seed"`
+	Volumes    Volumes       `json:"volumes"`
+	Iterations int           `json:"iterations"`
+	Results    []BenchResult `json:"results"`
+}
@@ -0,0 +1,31 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ChangeEntry is one row changed by an operation: its image before and after
+// the change as JSON objects of the table's columns. Before is empty for an
+// insert and After for a delete.
+type ChangeEntry struct {
+	LogID     int64           `json:"log_id"`
+	Table     string          `json:"table"`
+	Operation string          `json:"operation"`
+	Before    json.RawMessage `json:"before,omitempty"`
+	After     json.RawMessage `json:"after,omitempty"`
+}
+
+// Change is a logged write operation: all rows one transaction changed in
+// parts, customers and shipments, including rows changed by cascades and
+// triggers. IsUndo marks changes made by undoing another change; UndoneBy
+// and UndoneAt are set once the change itself has been undone.
+type Change struct {
+	ChangeID  int64         `json:"change_id"`
+	ChangedBy string        `json:"changed_by"`
+	ChangedAt time.Time     `json:"changed_at"`
+	IsUndo    bool          `json:"is_undo"`
+	UndoneBy  *string       `json:"undone_by,omitempty"`
+	UndoneAt  *time.Time    `json:"undone_at,omitempty"`
+	Entries   []ChangeEntry `json:"entries"`
+}
@@ -0,0 +1,27 @@
+package domain
+
+// RowDiff is a row both implementations returned under the same key but
+// with different values.
+type RowDiff struct {
+	Key   string `json:"key"`
+	Left  any    `json:"left"`
+	Right any    `json:"right"`
+}
+
+// Comparison reports whether two implementations of a query agree. Rows are
+// compared as multisets, ignoring order: Missing rows were returned only by
+// the left implementation, Extra only by the right one, and Different pairs
+// rows that share a key but differ in values. Times are in milliseconds.
+type Comparison struct {
+	Query     string    `json:"query"`
+	Left      string    `json:"left"`
+	Right     string    `json:"right"`
+	LeftRows  int       `json:"left_rows"`
+	RightRows int       `json:"right_rows"`
+	LeftMS    float64   `json:"left_ms"`
+	RightMS   float64   `json:"right_ms"`
+	Equal     bool      `json:"equal"`
+	Missing   []any     `json:"missing"`
+	Extra     []any     `json:"extra"`
+	Different []RowDiff `json:"different"`
+}
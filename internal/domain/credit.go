@@ -0,0 +1,55 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Payment is money received from a customer against their balance.
+type Payment struct {
+	PaymentID   int64           `json:"payment_id"`
+	CustomerID  int             `json:"customer_id"`
+	PaymentDate time.Time       `json:"payment_date"`
+	Amount      decimal.Decimal `json:"amount"`
+	Currency    string          `json:"currency"`
+	Reference   string          `json:"reference"`
+	CreatedBy   string          `json:"created_by"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// CustomerBalance is what a customer owes, in the customer's Currency.
+//
+// ShippedValue covers shipped and delivered documents net of returns, valued
+// like the customer shipment summary. Balance is ShippedValue less payments.
+// OpenValue covers documents not shipped yet; Exposure = Balance + OpenValue
+// is what the credit limit is checked against; Available is the credit left
+// (negative when over limit). A nil CreditLimit means no limit.
+type CustomerBalance struct {
+	CustomerID      int              `json:"customer_id"`
+	CustomerName    string           `json:"customer_name"`
+	CustomerCity    string           `json:"customer_city"`
+	Currency        string           `json:"currency"`
+	CreditLimit     *decimal.Decimal `json:"credit_limit"`
+	ShippedValue    decimal.Decimal  `json:"shipped_value"`
+	PaidAmount      decimal.Decimal  `json:"paid_amount"`
+	Balance         decimal.Decimal  `json:"balance"`
+	OpenValue       decimal.Decimal  `json:"open_value"`
+	Exposure        decimal.Decimal  `json:"exposure"`
+	Available       *decimal.Decimal `json:"available"`
+	OverLimit       bool             `json:"over_limit"`
+	LastPaymentDate *time.Time       `json:"last_payment_date,omitempty"`
+}
+
+// Calculate derives Balance, Exposure, Available and OverLimit from the
+// shipped, open and paid amounts.
+func (b *CustomerBalance) Calculate() {
+	b.Balance = b.ShippedValue.Sub(b.PaidAmount)
+	b.Exposure = b.Balance.Add(b.OpenValue)
+	b.Available = nil
+	if b.CreditLimit != nil {
+		available := b.CreditLimit.Sub(b.Exposure)
+		b.Available = &available
+	}
+	b.OverLimit = b.Available != nil && b.Available.IsNegative()
+}
@@ -0,0 +1,25 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// BaseCurrency is the currency exchange rates are quoted against and the
+// default currency of prices, customers and reports.
+const BaseCurrency = "RUB"
+
+// Currency is an ISO 4217 currency known to the application.
+type Currency struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+// ExchangeRate is the price of one unit of Currency in BaseCurrency
+// set for Date.
+type ExchangeRate struct {
+	Currency string          `json:"currency"`
+	Date     time.Time       `json:"date"`
+	Rate     decimal.Decimal `json:"rate"`
+}
@@ -0,0 +1,161 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Granularity is the length of a period of the sales time series.
+type Granularity string
+
+const (
+	GranularityDay     Granularity = "day"
+	GranularityWeek    Granularity = "week"
+	GranularityMonth   Granularity = "month"
+	GranularityQuarter Granularity = "quarter"
+)
+
+// Granularities lists the supported granularities, shortest first.
+var Granularities = []Granularity{GranularityDay, GranularityWeek, GranularityMonth, GranularityQuarter}
+
+var granularityLabels = map[Granularity]string{
+	GranularityDay:     "по дням",
+	GranularityWeek:    "по неделям",
+	GranularityMonth:   "по месяцам",
+	GranularityQuarter: "по кварталам",
+}
+
+// granularityIntervals are the PostgreSQL intervals between period starts.
+var granularityIntervals = map[Granularity]string{
+	GranularityDay:     "1 day",
+	GranularityWeek:    "1 week",
+	GranularityMonth:   "1 month",
+	GranularityQuarter: "3 months",
+}
+
+// Valid reports whether g is a known granularity.
+func (g Granularity) Valid() bool {
+	_, ok := granularityLabels[g]
+	return ok
+}
+
+// Label returns the human-readable name of the granularity.
+func (g Granularity) Label() string {
+	return granularityLabels[g]
+}
+
+// Interval returns the PostgreSQL interval of one period; periods start
+// where date_trunc(g) puts them, weeks on Mondays.
+func (g Granularity) Interval() string {
+	return granularityIntervals[g]
+}
+
+// SalesDimension is an attribute sales are broken down by.
+type SalesDimension string
+
+const (
+	DimensionWarehouse SalesDimension = "warehouse"
+	DimensionCity      SalesDimension = "city"
+	DimensionPartType  SalesDimension = "part_type"
+	DimensionCustomer  SalesDimension = "customer"
+	DimensionPart      SalesDimension = "part"
+)
+
+// SalesDimensions lists the dimensions the dashboard can be broken down by,
+// the default first. Parts are shown only as a top list.
+var SalesDimensions = []SalesDimension{DimensionWarehouse, DimensionCity, DimensionPartType, DimensionCustomer}
+
+var salesDimensionLabels = map[SalesDimension]string{
+	DimensionWarehouse: "по складам",
+	DimensionCity:      "по городам",
+	DimensionPartType:  "по типам деталей",
+	DimensionCustomer:  "по покупателям",
+	DimensionPart:      "по деталям",
+}
+
+// Valid reports whether d is a known dimension.
+func (d SalesDimension) Valid() bool {
+	_, ok := salesDimensionLabels[d]
+	return ok
+}
+
+// Label returns the human-readable name of the dimension.
+func (d SalesDimension) Label() string {
+	return salesDimensionLabels[d]
+}
+
+// DashboardFilter selects the sales of the dashboard: the half-open date
+// range [From, To), the series granularity, the breakdown dimension, the
+// length of the top lists and the currency values are reported in.
+type DashboardFilter struct {
+	From        time.Time      `json:"from"`
+	To          time.Time      `json:"to"`
+	Granularity Granularity    `json:"granularity"`
+	By          SalesDimension `json:"by"`
+	TopN        int            `json:"top"`
+	Currency    string         `json:"currency"`
+}
+
+// PreviousFrom returns the start of the previous period of the same length,
+// [PreviousFrom, From), that growth is measured against.
+func (f DashboardFilter) PreviousFrom() time.Time {
+	return f.From.Add(-f.To.Sub(f.From))
+}
+
+// SalesTotals are the sales of the whole period. Growth is the change of
+// Value against the previous period in percent, nil without previous sales.
+// There is no total quantity: parts are measured in different units.
+type SalesTotals struct {
+	Shipments int              `json:"shipments"`
+	Value     decimal.Decimal  `json:"value"`
+	PrevValue decimal.Decimal  `json:"prev_value"`
+	Growth    *decimal.Decimal `json:"growth"`
+}
+
+// SalesPoint is one period of a sales value time series. Growth is against
+// the preceding period in percent, nil for the first period or after a period
+// without sales; the cumulative total runs from the start of the range.
+type SalesPoint struct {
+	Period          time.Time        `json:"period"`
+	Value           decimal.Decimal  `json:"value"`
+	ValueGrowth     *decimal.Decimal `json:"value_growth"`
+	CumulativeValue decimal.Decimal  `json:"cumulative_value"`
+}
+
+// SalesShare is the sales of one value of a dimension: its rank by Value,
+// share of the period's Value in percent and growth against the previous
+// period. Qty in Unit is set only for parts; other dimensions mix units.
+type SalesShare struct {
+	Rank      int              `json:"rank"`
+	Key       string           `json:"key"`
+	Name      string           `json:"name"`
+	Qty       *decimal.Decimal `json:"qty"`
+	Unit      string           `json:"unit,omitempty"`
+	Value     decimal.Decimal  `json:"value"`
+	Share     decimal.Decimal  `json:"share"`
+	PrevValue decimal.Decimal  `json:"prev_value"`
+	Growth    *decimal.Decimal `json:"growth"`
+}
+
+// SalesSeries is the time series of one value of a dimension.
+type SalesSeries struct {
+	Key    string       `json:"key"`
+	Name   string       `json:"name"`
+	Points []SalesPoint `json:"points"`
+}
+
+// Dashboard is the sales overview. Sales are shipped and delivered
+// shipments net of returns; Value is in Currency at the shipment date
+// rate, quantities are given only for TopParts. Breakdown covers every
+// value of Filter.By, BreakdownSeries only its top Filter.TopN values.
+type Dashboard struct {
+	Filter          DashboardFilter `json:"filter"`
+	Currency        string          `json:"currency"`
+	Totals          SalesTotals     `json:"totals"`
+	Series          []SalesPoint    `json:"series"`
+	Breakdown       []SalesShare    `json:"breakdown"`
+	BreakdownSeries []SalesSeries   `json:"breakdown_series"`
+	TopParts        []SalesShare    `json:"top_parts"`
+	TopCustomers    []SalesShare    `json:"top_customers"`
+}
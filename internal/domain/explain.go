@@ -0,0 +1,39 @@
+package domain
+
+// PlanNode is a node of an executed query plan. Rows are per loop, as
+// PostgreSQL reports them; TotalMS covers all loops and SelfMS excludes the
+// time of child nodes. EstimateError is how many times the planner's row
+// estimate was off, in either direction.
+type PlanNode struct {
+	NodeType      string      `json:"node_type"`
+	Relationship  string      `json:"relationship,omitempty"`
+	Relation      string      `json:"relation,omitempty"`
+	Alias         string      `json:"alias,omitempty"`
+	Index         string      `json:"index,omitempty"`
+	Condition     string      `json:"condition,omitempty"`
+	TotalCost     float64     `json:"total_cost"`
+	PlanRows      float64     `json:"plan_rows"`
+	ActualRows    float64     `json:"actual_rows"`
+	Loops         float64     `json:"loops"`
+	TotalMS       float64     `json:"total_ms"`
+	SelfMS        float64     `json:"self_ms"`
+	SharedHit     int64       `json:"shared_hit"`
+	SharedRead    int64       `json:"shared_read"`
+	EstimateError float64     `json:"estimate_error"`
+	SeqScan       bool        `json:"seq_scan"`
+	Misestimate   bool        `json:"misestimate"`
+	Expensive     bool        `json:"expensive"`
+	Children      []*PlanNode `json:"children,omitempty"`
+}
+
+// Plan is the EXPLAIN ANALYZE result of a report query. Warnings list the
+// highlighted nodes in plain words.
+type Plan struct {
+	Report      string    `json:"report"`
+	SQL         string    `json:"sql"`
+	Args        []any     `json:"args"`
+	PlanningMS  float64   `json:"planning_ms"`
+	ExecutionMS float64   `json:"execution_ms"`
+	Root        *PlanNode `json:"root"`
+	Warnings    []string  `json:"warnings"`
+}
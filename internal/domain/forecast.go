@@ -0,0 +1,74 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// ForecastMethod is a method of forecasting monthly demand.
+type ForecastMethod string
+
+const (
+	MethodMovingAverage        ForecastMethod = "moving_average"
+	MethodExponentialSmoothing ForecastMethod = "exponential_smoothing"
+	MethodHoltWinters          ForecastMethod = "holt_winters"
+)
+
+// ForecastMethods lists the methods, simplest first.
+var ForecastMethods = []ForecastMethod{MethodMovingAverage, MethodExponentialSmoothing, MethodHoltWinters}
+
+var forecastMethodLabels = map[ForecastMethod]string{
+	MethodMovingAverage:        "Скользящее среднее",
+	MethodExponentialSmoothing: "Экспоненциальное сглаживание",
+	MethodHoltWinters:          "Хольт-Уинтерс",
+}
+
+// Label returns the human-readable name of the method.
+func (m ForecastMethod) Label() string {
+	return forecastMethodLabels[m]
+}
+
+// ForecastQuery selects the shipments to forecast: of one part, at one
+// warehouse or at all of them. A part is required, since parts are measured
+// in different units. Horizon is the number of months to forecast, Holdout
+// the number of the last months of history the methods are measured on.
+type ForecastQuery struct {
+	PartCode    string `json:"part"`
+	WarehouseNo *int   `json:"warehouse,omitempty"`
+	Horizon     int    `json:"horizon"`
+	Holdout     int    `json:"holdout"`
+}
+
+// ForecastPoint is the quantity of one month.
+type ForecastPoint struct {
+	Month time.Time       `json:"month"`
+	Qty   decimal.Decimal `json:"qty"`
+}
+
+// ForecastModel is the result of one method. Params are the fitted
+// parameters. Holdout is the forecast of the holdout months made from the
+// history before them, and MAPE its mean absolute percentage error over the
+// months with shipments, nil when there are none or the history is too short
+// to fit the method without the holdout. Forecast continues the whole
+// history.
+type ForecastModel struct {
+	Method   ForecastMethod     `json:"method"`
+	Params   map[string]float64 `json:"params"`
+	MAPE     *decimal.Decimal   `json:"mape"`
+	Holdout  []ForecastPoint    `json:"holdout"`
+	Forecast []ForecastPoint    `json:"forecast"`
+}
+
+// Forecast is the monthly shipped quantity of a part, in its unit, and its
+// forecast. History runs from the first month with shipments to the last
+// complete month. Models holds the methods the history is long enough for;
+// Best is the one with the lowest MAPE.
+type Forecast struct {
+	Query    ForecastQuery   `json:"query"`
+	PartName string          `json:"part_name"`
+	Unit     string          `json:"unit"`
+	History  []ForecastPoint `json:"history"`
+	Models   []ForecastModel `json:"models"`
+	Best     ForecastMethod  `json:"best,omitempty"`
+}
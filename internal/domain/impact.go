@@ -0,0 +1,17 @@
+package domain
+
+// TableImpact is the number of rows an operation inserts, updates and deletes
+// in one table, counting rows changed by triggers and cascades.
+type TableImpact struct {
+	Table    string `json:"table"`
+	Inserted int64  `json:"inserted"`
+	Updated  int64  `json:"updated"`
+	Deleted  int64  `json:"deleted"`
+}
+
+// Impact is the report of a dry run: the operation was executed and rolled
+// back, Tables lists the tables it would change.
+type Impact struct {
+	DryRun bool          `json:"dry_run"`
+	Tables []TableImpact `json:"tables"`
+}
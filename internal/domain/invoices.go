@@ -0,0 +1,70 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// maxVATRate bounds the VAT percentage of an invoice.
+var maxVATRate = decimal.NewFromInt(100)
+
+// ValidVATRate reports whether rate is a VAT percentage between 0 and 100
+// with at most two decimals.
+func ValidVATRate(rate decimal.Decimal) bool {
+	return !rate.IsNegative() && !rate.GreaterThan(maxVATRate) && rate.Equal(rate.Round(2))
+}
+
+// InvoiceStatus is the state of a customer invoice.
+type InvoiceStatus string
+
+const (
+	InvoiceIssued InvoiceStatus = "issued"
+	InvoiceVoid   InvoiceStatus = "void"
+)
+
+// Invoice bills a customer for shipments made over a period. Each shipment
+// appears on at most one issued invoice. Amounts are in the customer's
+// currency, converted at each shipment date's rate.
+type Invoice struct {
+	InvoiceID       int64           `json:"invoice_id"`
+	Number          string          `json:"number"`
+	CustomerID      int             `json:"customer_id"`
+	CustomerName    string          `json:"customer_name"`
+	CustomerAddress string          `json:"customer_address"`
+	CustomerCity    string          `json:"customer_city"`
+	PeriodFrom      time.Time       `json:"period_from"`
+	PeriodTo        time.Time       `json:"period_to"`
+	IssueDate       time.Time       `json:"issue_date"`
+	Currency        string          `json:"currency"`
+	VATRate         decimal.Decimal `json:"vat_rate"`
+	NetAmount       decimal.Decimal `json:"net_amount"`
+	VATAmount       decimal.Decimal `json:"vat_amount"`
+	TotalAmount     decimal.Decimal `json:"total_amount"`
+	Status          InvoiceStatus   `json:"status"`
+	CreatedBy       string          `json:"created_by"`
+	CreatedAt       time.Time       `json:"created_at"`
+	VoidedBy        *string         `json:"voided_by,omitempty"`
+	VoidedAt        *time.Time      `json:"voided_at,omitempty"`
+	Lines           []InvoiceLine   `json:"lines,omitempty"`
+}
+
+// InvoiceLine is a shipment billed on an invoice, frozen at issue time.
+type InvoiceLine struct {
+	LineNo        int             `json:"line_no"`
+	WarehouseNo   int             `json:"warehouse_no"`
+	ShipmentDocNo int             `json:"shipment_doc_no"`
+	ShipmentDate  time.Time       `json:"shipment_date"`
+	PartCode      string          `json:"part_code"`
+	PartName      string          `json:"part_name"`
+	Unit          string          `json:"unit"`
+	Qty           decimal.Decimal `json:"qty"`
+	Price         decimal.Decimal `json:"price"`
+	Amount        decimal.Decimal `json:"amount"`
+}
+
+// InvoiceNumber formats the sequential number of an invoice within a year.
+func InvoiceNumber(year, seq int) string {
+	return fmt.Sprintf("%d-%06d", year, seq)
+}
@@ -0,0 +1,20 @@
+package domain
+
+// DuplicateCandidate is a pair of customers that may be the same company.
+// Customer is the earlier record, suggested to survive a merge.
+// NameSimilarity compares the normalized names, from 0 to 1.
+type DuplicateCandidate struct {
+	Customer       Customer `json:"customer"`
+	Duplicate      Customer `json:"duplicate"`
+	NameSimilarity float64  `json:"name_similarity"`
+	SameCity       bool     `json:"same_city"`
+	SameAddress    bool     `json:"same_address"`
+}
+
+// MergeResult reports a merge of DuplicateID into SurvivorID: Moved is the
+// number of rows repointed to the survivor, by table.
+type MergeResult struct {
+	SurvivorID  int              `json:"survivor_id"`
+	DuplicateID int              `json:"duplicate_id"`
+	Moved       map[string]int64 `json:"moved"`
+}
@@ -1,70 +1,125 @@
 package domain
 
-import "time"
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
 
 // Part represents a part/detail in the database.
+// PlanPrice is in Currency. DeletedAt is set while the part is in the trash.
 type Part struct {
-	PartCode  string  `json:"part_code"`
-	PartType  string  `json:"part_type"`
-	Name      string  `json:"name"`
-	Unit      string  `json:"unit"`
-	PlanPrice float64 `json:"plan_price"`
+	PartCode  string          `json:"part_code"`
+	PartType  string          `json:"part_type"`
+	Name      string          `json:"name"`
+	Unit      string          `json:"unit"`
+	PlanPrice decimal.Decimal `json:"plan_price"`
+	Currency  string          `json:"currency"`
+	DeletedAt *time.Time      `json:"deleted_at,omitempty"`
+	DeletedBy string          `json:"deleted_by,omitempty"`
 }
 
 // Customer represents a customer in the database.
+// City is the canonical name from the cities reference and Region comes from
+// it. Address is Street and Building on one line; clients may send just
+// Address, which is then split into the two.
+// Currency is the currency the customer is billed in; CreditLimit is in the
+// same currency, nil meaning no limit. DeletedAt is set while the customer is
+// in the trash.
 type Customer struct {
-	CustomerID int    `json:"customer_id"`
-	Name       string `json:"name"`
-	Address    string `json:"address"`
-	City       string `json:"city"`
+	CustomerID  int              `json:"customer_id"`
+	Name        string           `json:"name"`
+	Address     string           `json:"address"`
+	City        string           `json:"city"`
+	Region      string           `json:"region"`
+	PostalCode  string           `json:"postal_code"`
+	Street      string           `json:"street"`
+	Building    string           `json:"building"`
+	Currency    string           `json:"currency"`
+	CreditLimit *decimal.Decimal `json:"credit_limit"`
+	DeletedAt   *time.Time       `json:"deleted_at,omitempty"`
+	DeletedBy   string           `json:"deleted_by,omitempty"`
 }
 
 // Shipment represents a shipment record in the database.
+// Price is the effective price per unit of the part, in the part's currency.
+// It is resolved by the pricing engine when the shipment is recorded and
+// updated; a price sent by the client is ignored.
 type Shipment struct {
-	WarehouseNo   int       `json:"warehouse_no"`
-	ShipmentDocNo int       `json:"shipment_doc_no"`
-	CustomerID    int       `json:"customer_id"`
-	PartCode      string    `json:"part_code"`
-	Unit          string    `json:"unit"`
-	Qty           float64   `json:"qty"`
-	ShipmentDate  time.Time `json:"shipment_date"`
+	WarehouseNo   int             `json:"warehouse_no"`
+	ShipmentDocNo int             `json:"shipment_doc_no"`
+	CustomerID    int             `json:"customer_id"`
+	PartCode      string          `json:"part_code"`
+	Unit          string          `json:"unit"`
+	Qty           decimal.Decimal `json:"qty"`
+	Price         decimal.Decimal `json:"price"`
+	ShipmentDate  time.Time       `json:"shipment_date"`
+	Status        ShipmentStatus  `json:"status"`
 }
 
 // FullShipmentInfo represents the VIEW combining all three tables.
+// Qty and TotalPrice are net of customer returns. Qty is in the shipment
+// unit, BaseQty in the part's unit that PlanPrice refers to; UnitPrice is the
+// price per shipment unit.
+//
+// EffectivePrice is the price per part unit the shipment was recorded at,
+// after price lists and discounts; UnitPrice and TotalPrice are based on it.
+// PlanPrice, EffectivePrice, UnitPrice and TotalPrice are in the part's Currency;
+// TotalPriceRUB is converted at the shipment date rate, and ReportTotal is
+// TotalPriceRUB converted to the requested ReportCurrency the same way.
 type FullShipmentInfo struct {
-	WarehouseNo     int       `json:"warehouse_no"`
-	ShipmentDocNo   int       `json:"shipment_doc_no"`
-	ShipmentDate    time.Time `json:"shipment_date"`
-	Qty             float64   `json:"qty"`
-	CustomerID      int       `json:"customer_id"`
-	CustomerName    string    `json:"customer_name"`
-	CustomerAddress string    `json:"customer_address"`
-	CustomerCity    string    `json:"customer_city"`
-	PartCode        string    `json:"part_code"`
-	PartName        string    `json:"part_name"`
-	PartType        string    `json:"part_type"`
-	Unit            string    `json:"unit"`
-	PlanPrice       float64   `json:"plan_price"`
-	TotalPrice      float64   `json:"total_price"`
+	WarehouseNo      int             `json:"warehouse_no"`
+	ShipmentDocNo    int             `json:"shipment_doc_no"`
+	ShipmentDate     time.Time       `json:"shipment_date"`
+	Qty              decimal.Decimal `json:"qty"`
+	CustomerID       int             `json:"customer_id"`
+	CustomerName     string          `json:"customer_name"`
+	CustomerAddress  string          `json:"customer_address"`
+	CustomerCity     string          `json:"customer_city"`
+	PartCode         string          `json:"part_code"`
+	PartName         string          `json:"part_name"`
+	PartType         string          `json:"part_type"`
+	Unit             string          `json:"unit"`
+	PlanPrice        decimal.Decimal `json:"plan_price"`
+	EffectivePrice   decimal.Decimal `json:"effective_price"`
+	TotalPrice       decimal.Decimal `json:"total_price"`
+	Status           ShipmentStatus  `json:"status"`
+	ShippedQty       decimal.Decimal `json:"shipped_qty"`
+	ReturnedQty      decimal.Decimal `json:"returned_qty"`
+	BaseQty          decimal.Decimal `json:"base_qty"`
+	BaseUnit         string          `json:"base_unit"`
+	UnitPrice        decimal.Decimal `json:"unit_price"`
+	Currency         string          `json:"currency"`
+	CustomerCurrency string          `json:"customer_currency"`
+	ExchangeRate     decimal.Decimal `json:"exchange_rate"`
+	TotalPriceRUB    decimal.Decimal `json:"total_price_rub"`
+	ReportCurrency   string          `json:"report_currency"`
+	ReportTotal      decimal.Decimal `json:"report_total"`
 }
 
 // Task1Result represents the result for Task 1.
 type Task1Result struct {
-	WarehouseNo   int       `json:"warehouse_no"`
-	PartCode      string    `json:"part_code"`
-	ShipmentDate  time.Time `json:"shipment_date"`
-	Qty           float64   `json:"qty"`
-	CustomerName  string    `json:"customer_name"`
+	WarehouseNo   int             `json:"warehouse_no"`
+	PartCode      string          `json:"part_code"`
+	ShipmentDate  time.Time       `json:"shipment_date"`
+	Qty           decimal.Decimal `json:"qty"`
+	CustomerName  string          `json:"customer_name"`
 }
 
 // Task2Result represents the result for Task 2 with aggregation.
+// Quantities are converted to the part's unit. TotalPartQty is the total of
+// the selected partition; it is null and TotalUnit is empty when the partition
+// mixes units. ShareOfTotal is the share of the quantity within a part and of
+// the value in rubles within a customer or warehouse (see Task2Partition.ByValue).
 type Task2Result struct {
-	WarehouseNo      int     `json:"warehouse_no"`
-	PartCode         string  `json:"part_code"`
-	CustomerName     string  `json:"customer_name"`
-	Qty              float64 `json:"qty"`
-	TotalPartQty     float64 `json:"total_part_qty"`
-	ShareOfTotal     float64 `json:"share_of_total"`
+	WarehouseNo      int                 `json:"warehouse_no"`
+	PartCode         string              `json:"part_code"`
+	CustomerName     string              `json:"customer_name"`
+	Qty              decimal.Decimal     `json:"qty"`
+	Unit             string              `json:"unit"`
+	TotalPartQty     decimal.NullDecimal `json:"total_part_qty"`
+	TotalUnit        string              `json:"total_unit"`
+	ShareOfTotal     decimal.Decimal     `json:"share_of_total"`
 }
 
 // Task3Result represents the result for Task 3.
@@ -75,8 +130,21 @@ type Task3Result struct {
 }
 
 // ProcedureResult represents the result of the stored procedure.
+// TotalValue is in Currency, converted at each shipment date's rate.
+// Quantities are only given per part: parts are measured in different units.
 type ProcedureResult struct {
-	TotalQty   float64 `json:"total_qty"`
-	TotalValue float64 `json:"total_value"`
+	Parts      []PartSummary   `json:"parts"`
+	TotalValue decimal.Decimal `json:"total_value"`
+	Currency   string          `json:"currency"`
+}
+
+// PartSummary is the net quantity of one part sold to the customer, in the
+// part's unit, and its value in the currency of the ProcedureResult.
+type PartSummary struct {
+	PartCode string          `json:"part_code"`
+	PartName string          `json:"part_name"`
+	Unit     string          `json:"unit"`
+	Qty      decimal.Decimal `json:"qty"`
+	Value    decimal.Decimal `json:"value"`
 }
 
@@ -1,15 +1,29 @@
 package domain
 
-import "time"
+import (
+	"time"
 
-// GORM модели для ORM подхода в Task1
+	"github.com/shopspring/decimal"
+)
 
-// CustomerGorm представляет покупателя для GORM
+// GORM модели для ORM подхода: Task1 и GORM-реализация репозитория
+
+// CustomerGorm представляет покупателя для GORM.
+// Region только читается из справочника городов при соединении с ним, поля
+// корзины не записываются при создании.
 type CustomerGorm struct {
-	CustomerID int    `gorm:"primaryKey;column:customer_id"`
-	Name       string `gorm:"column:name"`
-	Address    string `gorm:"column:address"`
-	City       string `gorm:"column:city"`
+	CustomerID  int              `gorm:"primaryKey;column:customer_id"`
+	Name        string           `gorm:"column:name"`
+	Address     string           `gorm:"column:address"`
+	City        string           `gorm:"column:city"`
+	Region      string           `gorm:"column:region;->"`
+	PostalCode  *string          `gorm:"column:postal_code"`
+	Street      *string          `gorm:"column:street"`
+	Building    *string          `gorm:"column:building"`
+	Currency    string           `gorm:"column:currency"`
+	CreditLimit *decimal.Decimal `gorm:"column:credit_limit"`
+	DeletedAt   *time.Time       `gorm:"column:deleted_at;<-:update"`
+	DeletedBy   *string          `gorm:"column:deleted_by;<-:update"`
 }
 
 // TableName возвращает имя таблицы для GORM
@@ -17,13 +31,50 @@ func (CustomerGorm) TableName() string {
 	return "customers"
 }
 
+// NewCustomerGorm строит модель GORM по покупателю; пустые части адреса
+// хранятся как NULL.
+func NewCustomerGorm(c Customer) CustomerGorm {
+	return CustomerGorm{
+		CustomerID:  c.CustomerID,
+		Name:        c.Name,
+		Address:     c.Address,
+		City:        c.City,
+		PostalCode:  nullString(c.PostalCode),
+		Street:      nullString(c.Street),
+		Building:    nullString(c.Building),
+		Currency:    c.Currency,
+		CreditLimit: c.CreditLimit,
+	}
+}
+
+// Customer возвращает покупателя по модели GORM
+func (c CustomerGorm) Customer() Customer {
+	return Customer{
+		CustomerID:  c.CustomerID,
+		Name:        c.Name,
+		Address:     c.Address,
+		City:        c.City,
+		Region:      c.Region,
+		PostalCode:  stringValue(c.PostalCode),
+		Street:      stringValue(c.Street),
+		Building:    stringValue(c.Building),
+		Currency:    c.Currency,
+		CreditLimit: c.CreditLimit,
+		DeletedAt:   c.DeletedAt,
+		DeletedBy:   stringValue(c.DeletedBy),
+	}
+}
+
 // PartGorm представляет деталь для GORM
 type PartGorm struct {
-	PartCode  string  `gorm:"primaryKey;column:part_code"`
-	PartType  string  `gorm:"column:part_type"`
-	Name      string  `gorm:"column:name"`
-	Unit      string  `gorm:"column:unit"`
-	PlanPrice float64 `gorm:"column:plan_price"`
+	PartCode  string          `gorm:"primaryKey;column:part_code"`
+	PartType  string          `gorm:"column:part_type"`
+	Name      string          `gorm:"column:name"`
+	Unit      string          `gorm:"column:unit"`
+	PlanPrice decimal.Decimal `gorm:"column:plan_price"`
+	Currency  string          `gorm:"column:currency"`
+	DeletedAt *time.Time      `gorm:"column:deleted_at;<-:update"`
+	DeletedBy *string         `gorm:"column:deleted_by;<-:update"`
 }
 
 // TableName возвращает имя таблицы для GORM
@@ -31,17 +82,45 @@ func (PartGorm) TableName() string {
 	return "parts"
 }
 
+// NewPartGorm строит модель GORM по детали
+func NewPartGorm(p Part) PartGorm {
+	return PartGorm{
+		PartCode:  p.PartCode,
+		PartType:  p.PartType,
+		Name:      p.Name,
+		Unit:      p.Unit,
+		PlanPrice: p.PlanPrice,
+		Currency:  p.Currency,
+	}
+}
+
+// Part возвращает деталь по модели GORM
+func (p PartGorm) Part() Part {
+	return Part{
+		PartCode:  p.PartCode,
+		PartType:  p.PartType,
+		Name:      p.Name,
+		Unit:      p.Unit,
+		PlanPrice: p.PlanPrice,
+		Currency:  p.Currency,
+		DeletedAt: p.DeletedAt,
+		DeletedBy: stringValue(p.DeletedBy),
+	}
+}
+
 // ShipmentGorm представляет отгрузку для GORM с загрузкой связей
 type ShipmentGorm struct {
-	WarehouseNo   int       `gorm:"primaryKey;column:warehouse_no"`
-	ShipmentDocNo int       `gorm:"primaryKey;column:shipment_doc_no"`
-	CustomerID    int       `gorm:"column:customer_id"`
-	PartCode      string    `gorm:"column:part_code"`
-	Unit          string    `gorm:"column:unit"`
-	Qty           float64   `gorm:"column:qty"`
-	ShipmentDate  time.Time `gorm:"column:shipment_date"`
-
-	// Связи GORM - автоматически загружаются через Preload
+	WarehouseNo   int             `gorm:"primaryKey;column:warehouse_no"`
+	ShipmentDocNo int             `gorm:"primaryKey;column:shipment_doc_no"`
+	CustomerID    int             `gorm:"column:customer_id"`
+	PartCode      string          `gorm:"column:part_code"`
+	Unit          string          `gorm:"column:unit"`
+	Qty           decimal.Decimal `gorm:"column:qty"`
+	Price         decimal.Decimal `gorm:"column:price"`
+	ShipmentDate  time.Time       `gorm:"column:shipment_date"`
+	Status        ShipmentStatus  `gorm:"column:status"`
+
+	// Связи GORM - загружаются через Joins или Preload
 	Customer CustomerGorm `gorm:"foreignKey:CustomerID;references:CustomerID"`
 	Part     PartGorm     `gorm:"foreignKey:PartCode;references:PartCode"`
 }
@@ -51,3 +130,48 @@ func (ShipmentGorm) TableName() string {
 	return "shipments"
 }
 
+// NewShipmentGorm строит модель GORM по отгрузке (без связей)
+func NewShipmentGorm(s Shipment) ShipmentGorm {
+	return ShipmentGorm{
+		WarehouseNo:   s.WarehouseNo,
+		ShipmentDocNo: s.ShipmentDocNo,
+		CustomerID:    s.CustomerID,
+		PartCode:      s.PartCode,
+		Unit:          s.Unit,
+		Qty:           s.Qty,
+		Price:         s.Price,
+		ShipmentDate:  s.ShipmentDate,
+		Status:        s.Status,
+	}
+}
+
+// Shipment возвращает отгрузку по модели GORM
+func (s ShipmentGorm) Shipment() Shipment {
+	return Shipment{
+		WarehouseNo:   s.WarehouseNo,
+		ShipmentDocNo: s.ShipmentDocNo,
+		CustomerID:    s.CustomerID,
+		PartCode:      s.PartCode,
+		Unit:          s.Unit,
+		Qty:           s.Qty,
+		Price:         s.Price,
+		ShipmentDate:  s.ShipmentDate,
+		Status:        s.Status,
+	}
+}
+
+// nullString возвращает nil для пустой строки
+func nullString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// stringValue возвращает пустую строку вместо NULL
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
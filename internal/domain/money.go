@@ -0,0 +1,23 @@
+package domain
+
+import "github.com/shopspring/decimal"
+
+// Quantities, prices and amounts are exact decimals end to end: they are
+// scanned from NUMERIC columns and kept as decimal.Decimal in Go. The
+// commands set decimal.MarshalJSONWithoutQuotes at startup, so they are
+// encoded as JSON numbers (not strings) and existing clients keep working.
+//
+// Rounding rules, all half away from zero as in PostgreSQL ROUND, applied
+// in SQL where the value is computed there and with RoundMoney in Go:
+//   - money amounts (line amounts, totals, credit notes, VAT) are rounded to
+//     MoneyPlaces after every multiplication or conversion and before they
+//     are summed;
+//   - shares are percentages rounded to 2 places;
+//   - quantities are not rounded; conversion factors and exchange rates keep
+//     the precision they are stored with.
+const MoneyPlaces = 2
+
+// RoundMoney rounds an amount to kopecks.
+func RoundMoney(d decimal.Decimal) decimal.Decimal {
+	return d.Round(MoneyPlaces)
+}
@@ -0,0 +1,72 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// PriceListEntry is a contract price of a part for one customer. It is in the
+// part's unit and Currency and applies from ValidFrom to ValidTo inclusive;
+// a nil ValidTo means open-ended.
+type PriceListEntry struct {
+	PriceListID int64           `json:"price_list_id"`
+	CustomerID  int             `json:"customer_id"`
+	PartCode    string          `json:"part_code"`
+	Price       decimal.Decimal `json:"price"`
+	ValidFrom   time.Time       `json:"valid_from"`
+	ValidTo     *time.Time      `json:"valid_to,omitempty"`
+}
+
+// DiscountRule is a percentage discount for one customer or for every
+// customer in a city. A nil PartCode applies the rule to all parts.
+// Exactly one of CustomerID and City is set.
+type DiscountRule struct {
+	RuleID     int64           `json:"rule_id"`
+	CustomerID *int            `json:"customer_id,omitempty"`
+	City       *string         `json:"city,omitempty"`
+	PartCode   *string         `json:"part_code,omitempty"`
+	Percent    decimal.Decimal `json:"percent"`
+	ValidFrom  time.Time       `json:"valid_from"`
+	ValidTo    *time.Time      `json:"valid_to,omitempty"`
+}
+
+// VolumeDiscount is a quantity tier: shipments of at least MinQty (in the
+// part's unit) get Percent off. A nil PartCode applies the tier to all parts.
+type VolumeDiscount struct {
+	TierID   int64           `json:"tier_id"`
+	PartCode *string         `json:"part_code,omitempty"`
+	MinQty   decimal.Decimal `json:"min_qty"`
+	Percent  decimal.Decimal `json:"percent"`
+}
+
+// Price sources of a PriceQuote.
+const (
+	PriceSourcePlan      = "plan"
+	PriceSourcePriceList = "price_list"
+)
+
+// PriceQuote is the effective price of a part for a customer, quantity and
+// date, with the rules that produced it. BasePrice and EffectivePrice are per
+// unit of the part (BaseQty refers to it), UnitPrice is per shipment Unit and
+// Amount is for the whole quantity; all are in the part's Currency.
+type PriceQuote struct {
+	CustomerID      int             `json:"customer_id"`
+	PartCode        string          `json:"part_code"`
+	Qty             decimal.Decimal `json:"qty"`
+	Unit            string          `json:"unit"`
+	BaseQty         decimal.Decimal `json:"base_qty"`
+	Date            time.Time       `json:"date"`
+	Currency        string          `json:"currency"`
+	PlanPrice       decimal.Decimal `json:"plan_price"`
+	PriceSource     string          `json:"price_source"`
+	PriceListID     *int64          `json:"price_list_id,omitempty"`
+	BasePrice       decimal.Decimal `json:"base_price"`
+	DiscountRuleID  *int64          `json:"discount_rule_id,omitempty"`
+	DiscountPercent decimal.Decimal `json:"discount_percent"`
+	VolumeTierID    *int64          `json:"volume_tier_id,omitempty"`
+	VolumePercent   decimal.Decimal `json:"volume_percent"`
+	EffectivePrice  decimal.Decimal `json:"effective_price"`
+	UnitPrice       decimal.Decimal `json:"unit_price"`
+	Amount          decimal.Decimal `json:"amount"`
+}
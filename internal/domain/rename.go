@@ -0,0 +1,14 @@
+package domain
+
+import "time"
+
+// PartRename reports a change of a part code: Shipments moved to the new code
+// through the foreign key cascade and AuditRows updated in the audit log.
+type PartRename struct {
+	OldCode   string    `json:"old_code"`
+	NewCode   string    `json:"new_code"`
+	Shipments int64     `json:"shipments"`
+	AuditRows int64     `json:"audit_rows"`
+	RenamedBy string    `json:"renamed_by"`
+	RenamedAt time.Time `json:"renamed_at"`
+}
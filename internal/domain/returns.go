@@ -0,0 +1,33 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// ShipmentReturn is a return of parts by the customer against an original
+// shipment document. Each return issues a credit note for its value in the
+// customer's currency.
+type ShipmentReturn struct {
+	ReturnID      int64           `json:"return_id"`
+	CreditNoteNo  string          `json:"credit_note_no"`
+	WarehouseNo   int             `json:"warehouse_no"`
+	ShipmentDocNo int             `json:"shipment_doc_no"`
+	CustomerID    int             `json:"customer_id"`
+	PartCode      string          `json:"part_code"`
+	Unit          string          `json:"unit"`
+	Qty           decimal.Decimal `json:"qty"`
+	ReturnDate    time.Time       `json:"return_date"`
+	Reason        string          `json:"reason"`
+	CreditAmount  decimal.Decimal `json:"credit_amount"`
+	Currency      string          `json:"currency"`
+	CreatedBy     string          `json:"created_by"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+// CreditNoteNumber formats the credit note number issued for a return.
+func CreditNoteNumber(returnID int64) string {
+	return fmt.Sprintf("КН-%06d", returnID)
+}
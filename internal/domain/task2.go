@@ -0,0 +1,57 @@
+package domain
+
+import "time"
+
+// Task2Partition selects the group within which Task 2 computes shares.
+type Task2Partition string
+
+const (
+	PartitionPart      Task2Partition = "part"
+	PartitionCustomer  Task2Partition = "customer"
+	PartitionWarehouse Task2Partition = "warehouse"
+)
+
+// Task2Partitions lists the supported partitions, the default first.
+var Task2Partitions = []Task2Partition{PartitionPart, PartitionCustomer, PartitionWarehouse}
+
+var task2PartitionLabels = map[Task2Partition]string{
+	PartitionPart:      "в пределах детали",
+	PartitionCustomer:  "в пределах покупателя",
+	PartitionWarehouse: "в пределах склада",
+}
+
+// Valid reports whether p is a known partition.
+func (p Task2Partition) Valid() bool {
+	_, ok := task2PartitionLabels[p]
+	return ok
+}
+
+// Label returns the human-readable name of the partition.
+func (p Task2Partition) Label() string {
+	return task2PartitionLabels[p]
+}
+
+// ByValue reports whether shares within p are computed from the shipment value
+// in rubles: customer and warehouse partitions mix parts with different units,
+// so their quantities cannot be added up.
+func (p Task2Partition) ByValue() bool {
+	return p != PartitionPart
+}
+
+// Task2Filter selects the shipments of the Task 2 report: the half-open date
+// range [From, To) and optional warehouse, customer and part filters
+// (zero values mean "any").
+type Task2Filter struct {
+	From        time.Time      `json:"from"`
+	To          time.Time      `json:"to"`
+	WarehouseNo int            `json:"warehouse_no,omitempty"`
+	CustomerID  int            `json:"customer_id,omitempty"`
+	PartCode    string         `json:"part_code,omitempty"`
+	Partition   Task2Partition `json:"partition"`
+}
+
+// YearPeriod returns the half-open range covering the calendar year.
+func YearPeriod(year int) (time.Time, time.Time) {
+	from := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	return from, from.AddDate(1, 0, 0)
+}
@@ -0,0 +1,161 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Quantifier is a quantifier of the Task 3 query.
+type Quantifier string
+
+const (
+	QuantifierSome Quantifier = "some"
+	QuantifierAll  Quantifier = "all"
+	QuantifierNone Quantifier = "none"
+)
+
+// PartQuantifiers and ShipmentQuantifiers list the quantifiers allowed over
+// parts and over shipments of a part, the default first.
+var (
+	PartQuantifiers     = []Quantifier{QuantifierSome, QuantifierAll}
+	ShipmentQuantifiers = []Quantifier{QuantifierAll, QuantifierNone, QuantifierSome}
+)
+
+// PartTypes lists the allowed values of Part.PartType.
+var PartTypes = []string{"покупная", "собственного производства"}
+
+// PartPredicate is the condition P on parts. Empty fields do not restrict;
+// prices are plan prices in the part's currency.
+type PartPredicate struct {
+	PriceAbove *decimal.Decimal `json:"price_above,omitempty"`
+	PriceBelow *decimal.Decimal `json:"price_below,omitempty"`
+	PartTypes  []string         `json:"part_types,omitempty"`
+}
+
+// ShipmentPredicate is the condition Q on shipments. Empty fields do not
+// restrict; DateFrom and DateTo are inclusive.
+type ShipmentPredicate struct {
+	Warehouses []int      `json:"warehouses,omitempty"`
+	DateFrom   *time.Time `json:"date_from,omitempty"`
+	DateTo     *time.Time `json:"date_to,omitempty"`
+}
+
+// Task3Query selects the customers for whom, for Parts-quantified parts
+// matching Part that were shipped to the customer, Shipments-quantified
+// shipments of that part to the customer satisfy Shipment.
+//
+// Parts range only over parts shipped to the customer, and "all" needs at
+// least one such part, so no customer qualifies vacuously. Shipments of a
+// part are never empty, so "all" over them is not vacuous either.
+type Task3Query struct {
+	Parts     Quantifier        `json:"parts"`
+	Shipments Quantifier        `json:"shipments"`
+	Part      PartPredicate     `json:"part"`
+	Shipment  ShipmentPredicate `json:"shipment"`
+}
+
+// DefaultTask3Query is the original Task 3: for some part priced over 100,
+// all shipments of the part to the customer came from warehouse 5.
+func DefaultTask3Query() Task3Query {
+	price := decimal.NewFromInt(100)
+	return Task3Query{
+		Parts:     QuantifierSome,
+		Shipments: QuantifierAll,
+		Part:      PartPredicate{PriceAbove: &price},
+		Shipment:  ShipmentPredicate{Warehouses: []int{5}},
+	}
+}
+
+// Validate checks the query against the whitelist of quantifiers and
+// predicate values.
+func (q Task3Query) Validate() error {
+	if !slices.Contains(PartQuantifiers, q.Parts) {
+		return fmt.Errorf("unknown part quantifier: %q", q.Parts)
+	}
+	if !slices.Contains(ShipmentQuantifiers, q.Shipments) {
+		return fmt.Errorf("unknown shipment quantifier: %q", q.Shipments)
+	}
+	for _, p := range []*decimal.Decimal{q.Part.PriceAbove, q.Part.PriceBelow} {
+		if p != nil && p.IsNegative() {
+			return errors.New("price threshold must not be negative")
+		}
+	}
+	for _, t := range q.Part.PartTypes {
+		if !slices.Contains(PartTypes, t) {
+			return fmt.Errorf("unknown part type: %q", t)
+		}
+	}
+	for _, w := range q.Shipment.Warehouses {
+		if w <= 0 {
+			return fmt.Errorf("invalid warehouse: %d", w)
+		}
+	}
+	if q.Shipment.DateFrom != nil && q.Shipment.DateTo != nil && q.Shipment.DateTo.Before(*q.Shipment.DateFrom) {
+		return errors.New("date_to is before date_from")
+	}
+	return nil
+}
+
+// String describes the query in Russian, as shown on the Task 3 page.
+func (q Task3Query) String() string {
+	var b strings.Builder
+	if q.Parts == QuantifierAll {
+		b.WriteString("Для всех купленных деталей")
+	} else {
+		b.WriteString("Для некоторой купленной детали")
+	}
+	b.WriteString(" " + q.Part.String() + " ")
+	switch q.Shipments {
+	case QuantifierAll:
+		b.WriteString("все отгрузки этой детали покупателю")
+	case QuantifierNone:
+		b.WriteString("ни одна отгрузка этой детали покупателю не")
+	default:
+		b.WriteString("хотя бы одна отгрузка этой детали покупателю")
+	}
+	b.WriteString(" " + q.Shipment.String())
+	return b.String()
+}
+
+func (p PartPredicate) String() string {
+	var conds []string
+	if p.PriceAbove != nil {
+		conds = append(conds, "ценой > "+p.PriceAbove.String())
+	}
+	if p.PriceBelow != nil {
+		conds = append(conds, "ценой < "+p.PriceBelow.String())
+	}
+	if len(p.PartTypes) > 0 {
+		conds = append(conds, "типа «"+strings.Join(p.PartTypes, "», «")+"»")
+	}
+	if len(conds) == 0 {
+		return "(любой)"
+	}
+	return "с " + strings.Join(conds, " и ")
+}
+
+func (p ShipmentPredicate) String() string {
+	var conds []string
+	if len(p.Warehouses) > 0 {
+		ws := make([]string, len(p.Warehouses))
+		for i, w := range p.Warehouses {
+			ws[i] = fmt.Sprint(w)
+		}
+		conds = append(conds, "со склада "+strings.Join(ws, ", "))
+	}
+	if p.DateFrom != nil {
+		conds = append(conds, "не ранее "+p.DateFrom.Format("02.01.2006"))
+	}
+	if p.DateTo != nil {
+		conds = append(conds, "не позднее "+p.DateTo.Format("02.01.2006"))
+	}
+	if len(conds) == 0 {
+		return "(любая)"
+	}
+	return strings.Join(conds, " и ")
+}
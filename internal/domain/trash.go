@@ -0,0 +1,15 @@
+package domain
+
+// Trash lists customers and parts that were deleted and can be restored.
+type Trash struct {
+	Customers []Customer `json:"customers"`
+	Parts     []Part     `json:"parts"`
+}
+
+// PurgeResult reports what purging a record from the trash removes. Shipments
+// is the number of shipment documents deleted along with it; Purged is false
+// when the purge was only previewed.
+type PurgeResult struct {
+	Shipments int64 `json:"shipments"`
+	Purged    bool  `json:"purged"`
+}
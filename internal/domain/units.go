@@ -0,0 +1,20 @@
+package domain
+
+import "github.com/shopspring/decimal"
+
+// Unit is a unit of measure. Factor converts one unit into BaseUnit,
+// e.g. "г" has BaseUnit "кг" and Factor 0.001.
+type Unit struct {
+	UnitCode string          `json:"unit_code"`
+	Name     string          `json:"name"`
+	BaseUnit string          `json:"base_unit"`
+	Factor   decimal.Decimal `json:"factor"`
+}
+
+// PartUnit is a unit a part may be shipped in. Factor converts one UnitCode
+// into the part's own unit (parts.unit), which always has Factor 1.
+type PartUnit struct {
+	PartCode string          `json:"part_code"`
+	UnitCode string          `json:"unit_code"`
+	Factor   decimal.Decimal `json:"factor"`
+}
@@ -0,0 +1,116 @@
+package domain
+
+import "time"
+
+// ShipmentStatus is the lifecycle state of a shipment document.
+type ShipmentStatus string
+
+const (
+	StatusDraft     ShipmentStatus = "draft"
+	StatusConfirmed ShipmentStatus = "confirmed"
+	StatusPicked    ShipmentStatus = "picked"
+	StatusShipped   ShipmentStatus = "shipped"
+	StatusDelivered ShipmentStatus = "delivered"
+	StatusCancelled ShipmentStatus = "cancelled"
+)
+
+// ShipmentStatuses lists all statuses in lifecycle order.
+var ShipmentStatuses = []ShipmentStatus{
+	StatusDraft, StatusConfirmed, StatusPicked, StatusShipped, StatusDelivered, StatusCancelled,
+}
+
+// shipmentTransitions is the state machine: allowed next statuses per status.
+var shipmentTransitions = map[ShipmentStatus][]ShipmentStatus{
+	StatusDraft:     {StatusConfirmed, StatusCancelled},
+	StatusConfirmed: {StatusPicked, StatusCancelled},
+	StatusPicked:    {StatusShipped, StatusCancelled},
+	StatusShipped:   {StatusDelivered},
+}
+
+var shipmentStatusLabels = map[ShipmentStatus]string{
+	StatusDraft:     "Черновик",
+	StatusConfirmed: "Подтверждена",
+	StatusPicked:    "Собрана",
+	StatusShipped:   "Отгружена",
+	StatusDelivered: "Доставлена",
+	StatusCancelled: "Отменена",
+}
+
+// Valid reports whether s is a known status.
+func (s ShipmentStatus) Valid() bool {
+	_, ok := shipmentStatusLabels[s]
+	return ok
+}
+
+// Label returns the Russian name of the status for templates.
+func (s ShipmentStatus) Label() string {
+	return shipmentStatusLabels[s]
+}
+
+// CanTransitionTo reports whether the state machine allows s -> next.
+func (s ShipmentStatus) CanTransitionTo(next ShipmentStatus) bool {
+	for _, allowed := range shipmentTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// Editable reports whether a shipment in this status may still be changed
+// via UpdateShipment. Once goods have left the warehouse, or the document was
+// cancelled, it is only changed through transitions.
+func (s ShipmentStatus) Editable() bool {
+	return s == StatusDraft || s == StatusConfirmed || s == StatusPicked
+}
+
+// Deletable reports whether a shipment in this status may be deleted. Goods
+// of any other document have left or are leaving the warehouse, and its
+// returns and status history must be kept.
+func (s ShipmentStatus) Deletable() bool {
+	return s == StatusDraft || s == StatusCancelled
+}
+
+// ShipmentAction is a named transition exposed as
+// POST /api/shipments/:warehouse/:doc/<Name>.
+type ShipmentAction struct {
+	Name   string         `json:"name"`
+	Target ShipmentStatus `json:"target"`
+	Label  string         `json:"label"`
+}
+
+// ShipmentActions lists all transition endpoints.
+var ShipmentActions = []ShipmentAction{
+	{Name: "confirm", Target: StatusConfirmed, Label: "Подтвердить"},
+	{Name: "pick", Target: StatusPicked, Label: "Собрать"},
+	{Name: "ship", Target: StatusShipped, Label: "Отгрузить"},
+	{Name: "deliver", Target: StatusDelivered, Label: "Доставлено"},
+	{Name: "cancel", Target: StatusCancelled, Label: "Отменить"},
+}
+
+// AvailableActions returns the transitions allowed from the shipment's status.
+func (s Shipment) AvailableActions() []ShipmentAction {
+	var actions []ShipmentAction
+	for _, a := range ShipmentActions {
+		if s.Status.CanTransitionTo(a.Target) {
+			actions = append(actions, a)
+		}
+	}
+	return actions
+}
+
+// ShipmentStatusChange is one entry of the shipment status history.
+type ShipmentStatusChange struct {
+	WarehouseNo   int            `json:"warehouse_no"`
+	ShipmentDocNo int            `json:"shipment_doc_no"`
+	FromStatus    ShipmentStatus `json:"from_status"`
+	ToStatus      ShipmentStatus `json:"to_status"`
+	ChangedBy     string         `json:"changed_by"`
+	ChangedAt     time.Time      `json:"changed_at"`
+}
+
+// Returnable reports whether goods of the shipment have left the warehouse
+// and may therefore come back as a customer return.
+func (s Shipment) Returnable() bool {
+	return s.Status == StatusShipped || s.Status == StatusDelivered
+}
@@ -0,0 +1,152 @@
+// Package exchange reads exchange rates published by the Central Bank of
+// Russia. It understands the daily XML feed (XML_daily.asp) and a plain CSV
+// export with one rate per line.
+package exchange
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/student/my-kpfu-db-app/internal/domain"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// Parse detects the format by the first non-blank byte and reads all rates.
+func Parse(r io.Reader) ([]domain.ExchangeRate, error) {
+	br := bufio.NewReader(r)
+	for {
+		b, err := br.Peek(1)
+		if err != nil {
+			return nil, fmt.Errorf("empty rates file: %w", err)
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n', 0xEF, 0xBB, 0xBF:
+			br.ReadByte()
+			continue
+		case '<':
+			return ParseXML(br)
+		}
+		return ParseCSV(br)
+	}
+}
+
+type valCurs struct {
+	Date    string `xml:"Date,attr"`
+	Valutes []struct {
+		CharCode string `xml:"CharCode"`
+		Nominal  string `xml:"Nominal"`
+		Value    string `xml:"Value"`
+	} `xml:"Valute"`
+}
+
+// ParseXML reads the CBR daily feed:
+//
+//	<ValCurs Date="02.03.2024" name="Foreign Currency Market">
+//	  <Valute ID="R01235"><CharCode>USD</CharCode><Nominal>1</Nominal><Value>91,6012</Value></Valute>
+//	</ValCurs>
+//
+// The feed is served in windows-1251.
+func ParseXML(r io.Reader) ([]domain.ExchangeRate, error) {
+	dec := xml.NewDecoder(r)
+	dec.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		if strings.EqualFold(charset, "windows-1251") {
+			return charmap.Windows1251.NewDecoder().Reader(input), nil
+		}
+		return nil, fmt.Errorf("unsupported charset %s", charset)
+	}
+
+	var doc valCurs
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("parse CBR XML: %w", err)
+	}
+	date, err := parseDate(doc.Date)
+	if err != nil {
+		return nil, err
+	}
+
+	rates := make([]domain.ExchangeRate, 0, len(doc.Valutes))
+	for _, v := range doc.Valutes {
+		rate, err := rate(v.Nominal, v.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", v.CharCode, err)
+		}
+		rates = append(rates, domain.ExchangeRate{Currency: strings.TrimSpace(v.CharCode), Date: date, Rate: rate})
+	}
+	return rates, nil
+}
+
+// ParseCSV reads lines of "date;currency;nominal;value". The separator may
+// be ';' or ',', dates are DD.MM.YYYY or YYYY-MM-DD, numbers may use a
+// decimal comma. A header line is skipped.
+func ParseCSV(r io.Reader) ([]domain.ExchangeRate, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	cr := csv.NewReader(bytes.NewReader(data))
+	if bytes.ContainsRune(data, ';') {
+		cr.Comma = ';'
+	}
+	cr.FieldsPerRecord = 4
+	cr.TrimLeadingSpace = true
+
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("parse rates CSV: %w", err)
+	}
+
+	var rates []domain.ExchangeRate
+	for i, rec := range records {
+		date, err := parseDate(rec[0])
+		if err != nil {
+			if i == 0 {
+				continue // заголовок
+			}
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		rate, err := rate(rec[2], rec[3])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		rates = append(rates, domain.ExchangeRate{
+			Currency: strings.ToUpper(strings.TrimSpace(rec[1])),
+			Date:     date,
+			Rate:     rate,
+		})
+	}
+	return rates, nil
+}
+
+// rateScale is the precision of exchange_rates.rate.
+const rateScale = 8
+
+// rate converts the quoted value for nominal units into the price of one unit.
+func rate(nominal, value string) (decimal.Decimal, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(nominal))
+	if err != nil || n <= 0 {
+		return decimal.Zero, fmt.Errorf("invalid nominal %q", nominal)
+	}
+	v, err := decimal.NewFromString(strings.Replace(strings.TrimSpace(value), ",", ".", 1))
+	if err != nil || !v.IsPositive() {
+		return decimal.Zero, fmt.Errorf("invalid rate %q", value)
+	}
+	return v.DivRound(decimal.NewFromInt(int64(n)), rateScale), nil
+}
+
+func parseDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{"02.01.2006", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", s)
+}
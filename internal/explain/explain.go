@@ -0,0 +1,167 @@
+// Package explain parses the output of EXPLAIN (ANALYZE, BUFFERS, FORMAT
+// JSON) into a tree and highlights what usually makes a query slow:
+// sequential scans, row estimates far from the actual rows, and the nodes
+// that take most of the time.
+package explain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/student/my-kpfu-db-app/internal/domain"
+)
+
+const (
+	// MisestimateFactor is how many times the row estimate may be off
+	// before a node is flagged.
+	MisestimateFactor = 10
+	// ExpensiveShare is the share of execution time a node must take by
+	// itself to be flagged; at most MaxExpensive nodes are.
+	ExpensiveShare = 0.1
+	MaxExpensive   = 3
+)
+
+type rawNode struct {
+	NodeType     string    `json:"Node Type"`
+	Relationship string    `json:"Parent Relationship"`
+	Relation     string    `json:"Relation Name"`
+	Alias        string    `json:"Alias"`
+	Index        string    `json:"Index Name"`
+	IndexCond    string    `json:"Index Cond"`
+	HashCond     string    `json:"Hash Cond"`
+	JoinFilter   string    `json:"Join Filter"`
+	Filter       string    `json:"Filter"`
+	TotalCost    float64   `json:"Total Cost"`
+	PlanRows     float64   `json:"Plan Rows"`
+	ActualRows   float64   `json:"Actual Rows"`
+	Loops        float64   `json:"Actual Loops"`
+	ActualTotal  float64   `json:"Actual Total Time"`
+	SharedHit    int64     `json:"Shared Hit Blocks"`
+	SharedRead   int64     `json:"Shared Read Blocks"`
+	Plans        []rawNode `json:"Plans"`
+}
+
+type rawPlan struct {
+	Plan          rawNode `json:"Plan"`
+	PlanningTime  float64 `json:"Planning Time"`
+	ExecutionTime float64 `json:"Execution Time"`
+}
+
+// Parse builds the annotated plan from the JSON output.
+func Parse(data []byte) (*domain.Plan, error) {
+	var raw []rawPlan
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parse plan: %w", err)
+	}
+	if len(raw) == 0 {
+		return nil, errors.New("parse plan: empty output")
+	}
+
+	plan := &domain.Plan{
+		PlanningMS:  raw[0].PlanningTime,
+		ExecutionMS: raw[0].ExecutionTime,
+		Root:        convert(raw[0].Plan),
+		Warnings:    []string{},
+	}
+	annotate(plan)
+	return plan, nil
+}
+
+func convert(r rawNode) *domain.PlanNode {
+	n := &domain.PlanNode{
+		NodeType:     r.NodeType,
+		Relationship: r.Relationship,
+		Relation:     r.Relation,
+		Alias:        r.Alias,
+		Index:        r.Index,
+		Condition:    firstNonEmpty(r.IndexCond, r.HashCond, r.JoinFilter, r.Filter),
+		TotalCost:    r.TotalCost,
+		PlanRows:     r.PlanRows,
+		ActualRows:   r.ActualRows,
+		Loops:        r.Loops,
+		TotalMS:      r.ActualTotal * r.Loops,
+		SharedHit:    r.SharedHit,
+		SharedRead:   r.SharedRead,
+	}
+
+	// Время узла без дочерних; у параллельных узлов оценка приблизительная
+	n.SelfMS = n.TotalMS
+	for _, child := range r.Plans {
+		c := convert(child)
+		n.Children = append(n.Children, c)
+		n.SelfMS -= c.TotalMS
+	}
+	n.SelfMS = math.Max(0, n.SelfMS)
+	n.TotalMS, n.SelfMS = round(n.TotalMS), round(n.SelfMS)
+
+	// Узлы, которые не выполнялись (Loops = 0), не оцениваются
+	if r.Loops > 0 {
+		actual, planned := math.Max(r.ActualRows, 1), math.Max(r.PlanRows, 1)
+		n.EstimateError = round(math.Max(actual/planned, planned/actual))
+		n.Misestimate = n.EstimateError >= MisestimateFactor
+	}
+	n.SeqScan = strings.HasSuffix(r.NodeType, "Seq Scan")
+	return n
+}
+
+// annotate marks the most expensive nodes and writes the warnings.
+func annotate(plan *domain.Plan) {
+	var nodes []*domain.PlanNode
+	var walk func(n *domain.PlanNode)
+	walk = func(n *domain.PlanNode) {
+		nodes = append(nodes, n)
+		for _, c := range n.Children {
+			walk(c)
+		}
+	}
+	walk(plan.Root)
+
+	byCost := append([]*domain.PlanNode(nil), nodes...)
+	sort.SliceStable(byCost, func(i, j int) bool { return byCost[i].SelfMS > byCost[j].SelfMS })
+	for i, n := range byCost {
+		if i >= MaxExpensive || plan.ExecutionMS <= 0 || n.SelfMS < plan.ExecutionMS*ExpensiveShare {
+			break
+		}
+		n.Expensive = true
+	}
+
+	for _, n := range nodes {
+		name := describe(n)
+		if n.Expensive {
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("%s: %.2f мс из %.2f (%.0f%%)",
+				name, n.SelfMS, plan.ExecutionMS, n.SelfMS/plan.ExecutionMS*100))
+		}
+		if n.SeqScan {
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("%s: последовательное чтение таблицы, %.0f строк за проход",
+				name, n.ActualRows))
+		}
+		if n.Misestimate {
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("%s: ожидалось %.0f строк, получено %.0f (ошибка в %.0f раз)",
+				name, n.PlanRows, n.ActualRows, n.EstimateError))
+		}
+	}
+}
+
+func describe(n *domain.PlanNode) string {
+	if n.Relation == "" {
+		return n.NodeType
+	}
+	return n.NodeType + " " + n.Relation
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func round(v float64) float64 {
+	return math.Round(v*1000) / 1000
+}
@@ -0,0 +1,240 @@
+// Package forecast predicts monthly demand from its history with a moving
+// average, simple exponential smoothing and additive Holt-Winters with a
+// yearly season. Parameters are fitted by grid search on the one-step-ahead
+// squared error, and every method is measured by MAPE on a holdout window
+// at the end of the history.
+package forecast
+
+import (
+	"math"
+
+	"github.com/student/my-kpfu-db-app/internal/domain"
+)
+
+// Season is the length of the Holt-Winters season in months.
+const Season = 12
+
+// movingAverageWindows are the windows tried by the moving average.
+var movingAverageWindows = []int{2, 3, 4, 6, 12}
+
+// smoothingGrid are the values tried for every smoothing parameter.
+var smoothingGrid = []float64{0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9}
+
+// Model is a method fitted to a series.
+type Model struct {
+	Method  domain.ForecastMethod
+	Params  map[string]float64
+	predict func(horizon int) []float64
+}
+
+// Predict returns the next horizon values; demand is never negative.
+func (m *Model) Predict(horizon int) []float64 {
+	values := m.predict(horizon)
+	for i, v := range values {
+		values[i] = math.Max(v, 0)
+	}
+	return values
+}
+
+// fitters fit a method to a series, or return nil when the series is too
+// short for it.
+var fitters = map[domain.ForecastMethod]func(series []float64) *Model{
+	domain.MethodMovingAverage:        FitMovingAverage,
+	domain.MethodExponentialSmoothing: FitExponentialSmoothing,
+	domain.MethodHoltWinters:          FitHoltWinters,
+}
+
+// Result is the evaluation of one method. Holdout and MAPE are nil when the
+// series without the holdout is too short for the method.
+type Result struct {
+	Method   domain.ForecastMethod
+	Params   map[string]float64
+	Holdout  []float64
+	MAPE     *float64
+	Forecast []float64
+}
+
+// Run fits every method the series is long enough for to the series without
+// its last holdout values, measures it on them, and forecasts horizon values
+// after the series with the method fitted to the whole series.
+func Run(series []float64, holdout, horizon int) []Result {
+	var results []Result
+	for _, method := range domain.ForecastMethods {
+		full := fitters[method](series)
+		if full == nil {
+			continue
+		}
+		r := Result{Method: method, Params: full.Params, Forecast: full.Predict(horizon)}
+		if holdout > 0 && holdout < len(series) {
+			if train := fitters[method](series[:len(series)-holdout]); train != nil {
+				r.Holdout = train.Predict(holdout)
+				r.MAPE = MAPE(series[len(series)-holdout:], r.Holdout)
+			}
+		}
+		results = append(results, r)
+	}
+	return results
+}
+
+// MAPE returns the mean absolute percentage error of predicted over the
+// nonzero actual values, or nil when there are none.
+func MAPE(actual, predicted []float64) *float64 {
+	var sum float64
+	n := 0
+	for i, a := range actual {
+		if a == 0 {
+			continue
+		}
+		sum += math.Abs(a-predicted[i]) / math.Abs(a)
+		n++
+	}
+	if n == 0 {
+		return nil
+	}
+	mape := sum / float64(n) * 100
+	return &mape
+}
+
+// FitMovingAverage picks the window with the lowest one-step error; the
+// forecast is the mean of the last window values.
+func FitMovingAverage(series []float64) *Model {
+	best, bestErr := 0, math.Inf(1)
+	for _, w := range movingAverageWindows {
+		if w >= len(series) {
+			break
+		}
+		var sse float64
+		for t := w; t < len(series); t++ {
+			d := series[t] - mean(series[t-w:t])
+			sse += d * d
+		}
+		if mse := sse / float64(len(series)-w); mse < bestErr {
+			best, bestErr = w, mse
+		}
+	}
+	if best == 0 {
+		return nil
+	}
+
+	level := mean(series[len(series)-best:])
+	return &Model{
+		Method:  domain.MethodMovingAverage,
+		Params:  map[string]float64{"window": float64(best)},
+		predict: func(horizon int) []float64 { return repeat(level, horizon) },
+	}
+}
+
+// FitExponentialSmoothing picks the smoothing factor alpha with the lowest
+// one-step error; the forecast is the last level.
+func FitExponentialSmoothing(series []float64) *Model {
+	if len(series) < 2 {
+		return nil
+	}
+	var best, level float64
+	bestErr := math.Inf(1)
+	for _, alpha := range smoothingGrid {
+		l, sse := series[0], 0.0
+		for _, y := range series[1:] {
+			sse += (y - l) * (y - l)
+			l = alpha*y + (1-alpha)*l
+		}
+		if sse < bestErr {
+			best, bestErr, level = alpha, sse, l
+		}
+	}
+
+	return &Model{
+		Method:  domain.MethodExponentialSmoothing,
+		Params:  map[string]float64{"alpha": best},
+		predict: func(horizon int) []float64 { return repeat(level, horizon) },
+	}
+}
+
+// holtWinters is the state of the additive Holt-Winters method after a
+// series: the level, the trend and the seasonal terms of every month of the
+// series.
+type holtWinters struct {
+	level, trend float64
+	seasonal     []float64
+}
+
+// runHoltWinters smooths series with the given factors and returns the
+// final state and the one-step squared error. The state starts from the
+// first two seasons: the trend is the change of the season mean per month,
+// the level is the trend line through the first season mean taken at the
+// end of the season, and the seasonal terms are the deviations of the first
+// season from that line, so a pure trend has no seasonal terms.
+func runHoltWinters(series []float64, alpha, beta, gamma float64) (holtWinters, float64) {
+	first, second := mean(series[:Season]), mean(series[Season:2*Season])
+	trend := (second - first) / Season
+	// Среднее первого сезона относится к его середине
+	middle := float64(Season-1) / 2
+	s := holtWinters{level: first + middle*trend, trend: trend, seasonal: make([]float64, len(series))}
+	for t := 0; t < Season; t++ {
+		s.seasonal[t] = series[t] - (first + (float64(t)-middle)*trend)
+	}
+
+	var sse float64
+	for t := Season; t < len(series); t++ {
+		y, prevSeason := series[t], s.seasonal[t-Season]
+		d := y - (s.level + s.trend + prevSeason)
+		sse += d * d
+
+		level := alpha*(y-prevSeason) + (1-alpha)*(s.level+s.trend)
+		s.trend = beta*(level-s.level) + (1-beta)*s.trend
+		s.level = level
+		s.seasonal[t] = gamma*(y-level) + (1-gamma)*prevSeason
+	}
+	return s, sse
+}
+
+// FitHoltWinters picks the level, trend and seasonal factors with the
+// lowest one-step error; it needs two full seasons of history.
+func FitHoltWinters(series []float64) *Model {
+	if len(series) < 2*Season {
+		return nil
+	}
+	var best holtWinters
+	var params map[string]float64
+	bestErr := math.Inf(1)
+	for _, alpha := range smoothingGrid {
+		for _, beta := range smoothingGrid {
+			for _, gamma := range smoothingGrid {
+				s, sse := runHoltWinters(series, alpha, beta, gamma)
+				if sse < bestErr {
+					best, bestErr = s, sse
+					params = map[string]float64{"alpha": alpha, "beta": beta, "gamma": gamma}
+				}
+			}
+		}
+	}
+
+	n := len(series)
+	return &Model{
+		Method: domain.MethodHoltWinters,
+		Params: params,
+		predict: func(horizon int) []float64 {
+			values := make([]float64, horizon)
+			for h := 1; h <= horizon; h++ {
+				values[h-1] = best.level + float64(h)*best.trend + best.seasonal[n-Season+(h-1)%Season]
+			}
+			return values
+		},
+	}
+}
+
+func mean(values []float64) float64 {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+func repeat(v float64, n int) []float64 {
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = v
+	}
+	return values
+}
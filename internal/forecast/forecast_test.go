@@ -0,0 +1,226 @@
+package forecast
+
+import (
+	"math"
+	"testing"
+
+	"github.com/student/my-kpfu-db-app/internal/domain"
+)
+
+const eps = 1e-6
+
+// series returns n values of f(t), t = 0..n-1.
+func series(n int, f func(t float64) float64) []float64 {
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = f(float64(i))
+	}
+	return values
+}
+
+func constant(t float64) float64 { return 10 }
+func linear(t float64) float64   { return 10 + 2*t }
+func sine(t float64) float64     { return 100 + 20*math.Sin(2*math.Pi*t/Season) }
+
+func byMethod(results []Result) map[domain.ForecastMethod]Result {
+	m := make(map[domain.ForecastMethod]Result, len(results))
+	for _, r := range results {
+		m[r.Method] = r
+	}
+	return m
+}
+
+func assertValues(t *testing.T, name string, got, want []float64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s: got %d values, want %d", name, len(got), len(want))
+	}
+	for i := range want {
+		if math.Abs(got[i]-want[i]) > eps {
+			t.Fatalf("%s: got %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestRunOnKnownSeries(t *testing.T) {
+	tests := []struct {
+		name    string
+		series  []float64
+		holdout int
+		// expected 3-month forecast of the listed methods
+		want map[domain.ForecastMethod][]float64
+		// methods that must reproduce the holdout exactly
+		exact []domain.ForecastMethod
+	}{
+		{
+			name:    "constant",
+			series:  series(24, constant),
+			holdout: 6,
+			want: map[domain.ForecastMethod][]float64{
+				domain.MethodMovingAverage:        {10, 10, 10},
+				domain.MethodExponentialSmoothing: {10, 10, 10},
+				domain.MethodHoltWinters:          {10, 10, 10},
+			},
+			// Без 6 проверочных месяцев остается 18 - мало для Хольта-Уинтерса
+			exact: []domain.ForecastMethod{domain.MethodMovingAverage, domain.MethodExponentialSmoothing},
+		},
+		{
+			name:    "linear trend",
+			series:  series(36, linear),
+			holdout: 6,
+			want: map[domain.ForecastMethod][]float64{
+				domain.MethodHoltWinters: {linear(36), linear(37), linear(38)},
+			},
+			exact: []domain.ForecastMethod{domain.MethodHoltWinters},
+		},
+		{
+			name:    "seasonal sine",
+			series:  series(48, sine),
+			holdout: 12,
+			want: map[domain.ForecastMethod][]float64{
+				domain.MethodHoltWinters: {sine(48), sine(49), sine(50)},
+			},
+			exact: []domain.ForecastMethod{domain.MethodHoltWinters},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := byMethod(Run(tt.series, tt.holdout, 3))
+			for method, want := range tt.want {
+				r, ok := results[method]
+				if !ok {
+					t.Fatalf("%s: no result", method)
+				}
+				assertValues(t, string(method), r.Forecast, want)
+			}
+			for _, method := range tt.exact {
+				r := results[method]
+				if r.MAPE == nil || *r.MAPE > eps {
+					t.Errorf("%s: MAPE = %v, want 0", method, r.MAPE)
+				}
+				if len(r.Holdout) != tt.holdout {
+					t.Errorf("%s: %d holdout values, want %d", method, len(r.Holdout), tt.holdout)
+				}
+			}
+		})
+	}
+}
+
+func TestHoltWintersBeatsFlatMethodsOnSeason(t *testing.T) {
+	results := byMethod(Run(series(48, sine), 12, 12))
+	hw := results[domain.MethodHoltWinters].MAPE
+	for _, method := range []domain.ForecastMethod{domain.MethodMovingAverage, domain.MethodExponentialSmoothing} {
+		flat := results[method].MAPE
+		if hw == nil || flat == nil || *hw >= *flat {
+			t.Errorf("Holt-Winters MAPE %v is not below %s MAPE %v", hw, method, flat)
+		}
+	}
+}
+
+func TestRunShortHistory(t *testing.T) {
+	tests := []struct {
+		length int
+		want   []domain.ForecastMethod
+	}{
+		{0, nil},
+		{1, nil},
+		{2, []domain.ForecastMethod{domain.MethodExponentialSmoothing}},
+		{3, []domain.ForecastMethod{domain.MethodMovingAverage, domain.MethodExponentialSmoothing}},
+		{2*Season - 1, []domain.ForecastMethod{domain.MethodMovingAverage, domain.MethodExponentialSmoothing}},
+		{2 * Season, domain.ForecastMethods},
+	}
+
+	for _, tt := range tests {
+		results := Run(series(tt.length, constant), 0, 3)
+		var got []domain.ForecastMethod
+		for _, r := range results {
+			got = append(got, r.Method)
+			if len(r.Forecast) != 3 {
+				t.Errorf("length %d, %s: %d forecast values, want 3", tt.length, r.Method, len(r.Forecast))
+			}
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("length %d: methods %v, want %v", tt.length, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("length %d: methods %v, want %v", tt.length, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestRunHoldout(t *testing.T) {
+	s := series(12, constant)
+	tests := []struct {
+		name    string
+		holdout int
+		// methods measured on the holdout
+		measured []domain.ForecastMethod
+	}{
+		{"no holdout", 0, nil},
+		{"whole history", len(s), nil},
+		{"longer than history", len(s) + 1, nil},
+		{"one month left", len(s) - 1, nil},
+		{"two months left", len(s) - 2, []domain.ForecastMethod{domain.MethodExponentialSmoothing}},
+		{"three months left", len(s) - 3, []domain.ForecastMethod{domain.MethodMovingAverage, domain.MethodExponentialSmoothing}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := byMethod(Run(s, tt.holdout, 1))
+			if len(results) != 2 {
+				t.Fatalf("got %d methods, want moving average and smoothing", len(results))
+			}
+			for method, r := range results {
+				measured := false
+				for _, m := range tt.measured {
+					measured = measured || m == method
+				}
+				if measured != (r.MAPE != nil) || measured != (r.Holdout != nil) {
+					t.Errorf("%s: MAPE %v, holdout %v, want measured %v", method, r.MAPE, r.Holdout, measured)
+				}
+				// Прогноз строится по всей истории независимо от проверки
+				assertValues(t, string(method), r.Forecast, []float64{10})
+			}
+		})
+	}
+}
+
+func TestMAPE(t *testing.T) {
+	tests := []struct {
+		name              string
+		actual, predicted []float64
+		want              *float64
+	}{
+		{"exact", []float64{1, 2, 3}, []float64{1, 2, 3}, ptr(0)},
+		{"months without shipments are skipped", []float64{100, 0, 50}, []float64{110, 5, 25}, ptr(30)},
+		{"over and under forecast", []float64{10, 10}, []float64{12, 8}, ptr(20)},
+		{"no shipments", []float64{0, 0}, []float64{1, 2}, nil},
+		{"empty", nil, nil, nil},
+	}
+
+	for _, tt := range tests {
+		got := MAPE(tt.actual, tt.predicted)
+		switch {
+		case tt.want == nil && got != nil:
+			t.Errorf("%s: MAPE = %v, want nil", tt.name, *got)
+		case tt.want != nil && (got == nil || math.Abs(*got-*tt.want) > eps):
+			t.Errorf("%s: MAPE = %v, want %v", tt.name, got, *tt.want)
+		}
+	}
+}
+
+func TestPredictIsNotNegative(t *testing.T) {
+	// Спрос падает на 10 в месяц и сразу после истории уходит ниже нуля
+	falling := series(2*Season, func(t float64) float64 { return 230 - 10*t })
+	m := FitHoltWinters(falling)
+	assertValues(t, "falling demand", m.Predict(3), []float64{0, 0, 0})
+}
+
+func ptr(v float64) *float64 {
+	return &v
+}
@@ -0,0 +1,219 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"github.com/student/my-kpfu-db-app/internal/domain"
+)
+
+// ============================================================================
+// ABC/XYZ Classification
+// ============================================================================
+
+const (
+	defaultABCXYZSnapshots = 20
+	// maxABCXYZMonths bounds the period, so that monthly vectors stay small
+	maxABCXYZMonths = 120
+)
+
+// abcxyzOptions are the classification options read from the query string.
+type abcxyzOptions struct {
+	Subject    domain.ABCXYZSubject
+	From, To   time.Time
+	Thresholds domain.ABCXYZThresholds
+}
+
+// readABCXYZOptions reads the subject (part), an inclusive ?from=&to= range
+// of months in 2006-01 form (by default the last 12 complete months) and
+// the a, b, x, y thresholds in percent (80, 95, 10, 25).
+func readABCXYZOptions(c *gin.Context) (abcxyzOptions, error) {
+	o := abcxyzOptions{
+		Subject:    domain.SubjectParts,
+		Thresholds: domain.DefaultABCXYZThresholds(),
+	}
+	if s := c.Query("subject"); s != "" {
+		o.Subject = domain.ABCXYZSubject(s)
+		if !o.Subject.Valid() {
+			return o, errors.New("unknown subject: " + s)
+		}
+	}
+
+	for _, t := range []struct {
+		name  string
+		value *decimal.Decimal
+	}{{"a", &o.Thresholds.A}, {"b", &o.Thresholds.B}, {"x", &o.Thresholds.X}, {"y", &o.Thresholds.Y}} {
+		v, err := queryDecimal(c, t.name)
+		if err != nil {
+			return o, err
+		}
+		if v != nil {
+			*t.value = *v
+		}
+	}
+	if err := o.Thresholds.Validate(); err != nil {
+		return o, err
+	}
+
+	now := time.Now()
+	last := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -1, 0)
+	first := last.AddDate(0, -11, 0)
+	if v := c.Query("from"); v != "" {
+		t, err := time.Parse("2006-01", v)
+		if err != nil {
+			return o, errors.New("invalid from")
+		}
+		first = t
+	}
+	if v := c.Query("to"); v != "" {
+		t, err := time.Parse("2006-01", v)
+		if err != nil {
+			return o, errors.New("invalid to")
+		}
+		last = t
+	}
+	if last.Before(first) {
+		return o, errors.New("from must not be after to")
+	}
+	o.From, o.To = domain.MonthPeriod(first, last)
+	if o.To.After(o.From.AddDate(0, maxABCXYZMonths, 0)) {
+		return o, errors.New("period must not exceed 120 months")
+	}
+	return o, nil
+}
+
+func (h *Handler) classifyABCXYZ(c *gin.Context) (*domain.ABCXYZSnapshot, bool) {
+	o, err := readABCXYZOptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	snapshot, err := h.repo.ClassifyABCXYZ(c.Request.Context(), o.Subject, o.From, o.To, o.Thresholds)
+	if err != nil {
+		writeError(c, err)
+		return nil, false
+	}
+	return snapshot, true
+}
+
+// GetABCXYZ classifies the sales of the period without saving the result.
+func (h *Handler) GetABCXYZ(c *gin.Context) {
+	snapshot, ok := h.classifyABCXYZ(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, snapshot)
+}
+
+// CreateABCXYZSnapshot classifies the sales of the period and saves the
+// result, replacing an earlier snapshot of the same subject and period.
+func (h *Handler) CreateABCXYZSnapshot(c *gin.Context) {
+	snapshot, ok := h.classifyABCXYZ(c)
+	if !ok {
+		return
+	}
+	if err := h.repo.SaveABCXYZSnapshot(c.Request.Context(), snapshot); err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, snapshot)
+}
+
+func (h *Handler) GetABCXYZSnapshots(c *gin.Context) {
+	subject := domain.ABCXYZSubject(c.Query("subject"))
+	if subject != "" && !subject.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown subject: " + string(subject)})
+		return
+	}
+	limit := defaultABCXYZSnapshots
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive number"})
+			return
+		}
+		limit = n
+	}
+
+	snapshots, err := h.repo.GetABCXYZSnapshots(c.Request.Context(), subject, limit)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, snapshots)
+}
+
+func (h *Handler) GetABCXYZSnapshot(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid snapshot ID"})
+		return
+	}
+
+	snapshot, err := h.repo.GetABCXYZSnapshot(c.Request.Context(), id)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, snapshot)
+}
+
+// ABCXYZPage renders the classification matrix of the period, or of a saved
+// snapshot with ?snapshot=id, and the list of saved snapshots.
+func (h *Handler) ABCXYZPage(c *gin.Context) {
+	ctx := c.Request.Context()
+	o, err := readABCXYZOptions(c)
+	if err != nil {
+		c.String(http.StatusBadRequest, "Invalid classification options: %v", err)
+		return
+	}
+
+	var snapshot *domain.ABCXYZSnapshot
+	if v := c.Query("snapshot"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			c.String(http.StatusBadRequest, "Invalid snapshot ID")
+			return
+		}
+		if snapshot, err = h.repo.GetABCXYZSnapshot(ctx, id); err != nil {
+			c.String(errorStatus(err), "Error fetching snapshot: %v", err)
+			return
+		}
+		o = abcxyzOptions{Subject: snapshot.Subject, From: snapshot.From, To: snapshot.To, Thresholds: snapshot.Thresholds}
+	} else if snapshot, err = h.repo.ClassifyABCXYZ(ctx, o.Subject, o.From, o.To, o.Thresholds); err != nil {
+		c.String(http.StatusInternalServerError, "Error classifying sales: %v", err)
+		return
+	}
+
+	snapshots, err := h.repo.GetABCXYZSnapshots(ctx, "", defaultABCXYZSnapshots)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Error fetching snapshots: %v", err)
+		return
+	}
+
+	c.HTML(http.StatusOK, "abcxyz.html", gin.H{
+		"Title":     "ABC/XYZ-анализ",
+		"Snapshot":  snapshot,
+		"Snapshots": snapshots,
+		"Options":   o,
+		"Last":      o.To.AddDate(0, -1, 0),
+		"Subjects":  domain.ABCXYZSubjects,
+		"XYZ":       domain.XYZClasses,
+		"Rows":      matrixRows(snapshot.Matrix),
+	})
+}
+
+// matrixRows splits the matrix into rows by ABC class.
+func matrixRows(matrix []domain.ABCXYZCell) [][]domain.ABCXYZCell {
+	n := len(domain.XYZClasses)
+	rows := make([][]domain.ABCXYZCell, 0, len(matrix)/n)
+	for i := 0; i+n <= len(matrix); i += n {
+		rows = append(rows, matrix[i:i+n])
+	}
+	return rows
+}
@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/student/my-kpfu-db-app/internal/domain"
+)
+
+// ============================================================================
+// Cities and Customer Contacts
+// ============================================================================
+
+func (h *Handler) GetCities(c *gin.Context) {
+	cities, err := h.repo.GetCities(c.Request.Context())
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, cities)
+}
+
+func (h *Handler) CreateCity(c *gin.Context) {
+	var city domain.City
+	if err := c.ShouldBindJSON(&city); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.repo.CreateCity(c.Request.Context(), &city); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, city)
+}
+
+func (h *Handler) GetContacts(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	contacts, err := h.repo.GetContacts(c.Request.Context(), id)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, contacts)
+}
+
+func (h *Handler) CreateContact(c *gin.Context) {
+	var contact domain.Contact
+	if err := c.ShouldBindJSON(&contact); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	contact.CustomerID, _ = strconv.Atoi(c.Param("id"))
+
+	if err := h.repo.CreateContact(c.Request.Context(), &contact); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, contact)
+}
+
+func (h *Handler) DeleteContact(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	contactID, _ := strconv.ParseInt(c.Param("contact_id"), 10, 64)
+	if err := h.repo.DeleteContact(c.Request.Context(), id, contactID); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Contact deleted"})
+}
@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// Benchmark Results
+// ============================================================================
+
+const defaultBenchRuns = 10
+
+func (h *Handler) GetBenchRuns(c *gin.Context) {
+	limit := defaultBenchRuns
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive number"})
+			return
+		}
+		limit = n
+	}
+
+	runs, err := h.repo.GetBenchRuns(c.Request.Context(), limit)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, runs)
+}
+
+// BenchPage renders the latest benchmark runs of the cmd/bench command.
+func (h *Handler) BenchPage(c *gin.Context) {
+	runs, err := h.repo.GetBenchRuns(c.Request.Context(), defaultBenchRuns)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Error fetching benchmark runs: %v", err)
+		return
+	}
+
+	c.HTML(http.StatusOK, "bench.html", gin.H{
+		"Title": "Сравнение стратегий запросов",
+		"Runs":  runs,
+	})
+}
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// Change Log and Undo
+// ============================================================================
+
+// maxUndo limits how many changes one request may list or undo.
+const maxUndo = 100
+
+func (h *Handler) GetChanges(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > maxUndo {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+		return
+	}
+
+	changes, err := h.repo.GetChanges(c.Request.Context(), c.Query("user"), limit)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, changes)
+}
+
+func (h *Handler) UndoChange(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid change id"})
+		return
+	}
+
+	change, err := h.repo.UndoChange(c.Request.Context(), id)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, change)
+}
+
+// UndoLast undoes the last ?last=N (default 1) changes of the operator in
+// the X-User header.
+func (h *Handler) UndoLast(c *gin.Context) {
+	n, err := strconv.Atoi(c.DefaultQuery("last", "1"))
+	if err != nil || n < 1 || n > maxUndo {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "last must be between 1 and 100"})
+		return
+	}
+
+	changes, err := h.repo.UndoLast(c.Request.Context(), n)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, changes)
+}
@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// Comparison of Twin Implementations
+// ============================================================================
+
+// CompareTask1 runs the SQL and ORM variants of Task 1 and diffs the results.
+func (h *Handler) CompareTask1(c *gin.Context) {
+	city := c.Query("city")
+	if city == "" {
+		city = "Казань"
+	}
+
+	comparison, err := h.repo.CompareTask1(c.Request.Context(), city)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, comparison)
+}
+
+// CompareTask3 runs the SQL and record-based variants of Task 3 with the
+// same query parameters and diffs the results.
+func (h *Handler) CompareTask3(c *gin.Context) {
+	q, err := task3Query(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	comparison, err := h.repo.CompareTask3(c.Request.Context(), q)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, comparison)
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"github.com/student/my-kpfu-db-app/internal/domain"
+)
+
+// ============================================================================
+// Payments, Balances and Debtors
+// ============================================================================
+
+// paymentRequest describes a received payment; the date is YYYY-MM-DD and
+// defaults to today, the currency defaults to the customer's.
+type paymentRequest struct {
+	CustomerID  int             `json:"customer_id" binding:"required"`
+	PaymentDate string          `json:"payment_date"`
+	Amount      decimal.Decimal `json:"amount"`
+	Currency    string          `json:"currency"`
+	Reference   string          `json:"reference"`
+}
+
+func (h *Handler) RecordPayment(c *gin.Context) {
+	var req paymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	date := time.Now()
+	if req.PaymentDate != "" {
+		var err error
+		if date, err = time.Parse("2006-01-02", req.PaymentDate); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment_date"})
+			return
+		}
+	}
+
+	payment := domain.Payment{
+		CustomerID:  req.CustomerID,
+		PaymentDate: date,
+		Amount:      req.Amount,
+		Currency:    strings.ToUpper(req.Currency),
+		Reference:   req.Reference,
+	}
+	if err := h.repo.RecordPayment(c.Request.Context(), &payment); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, payment)
+}
+
+func (h *Handler) GetPayments(c *gin.Context) {
+	customerID, _ := strconv.Atoi(c.Query("customer_id"))
+	payments, err := h.repo.GetPayments(c.Request.Context(), customerID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, payments)
+}
+
+func (h *Handler) GetCustomerBalance(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	balance, err := h.repo.GetCustomerBalance(c.Request.Context(), id)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, balance)
+}
+
+func (h *Handler) GetDebtors(c *gin.Context) {
+	debtors, err := h.repo.GetDebtors(c.Request.Context())
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, debtors)
+}
+
+func (h *Handler) DebtorsPage(c *gin.Context) {
+	debtors, err := h.repo.GetDebtors(c.Request.Context())
+	if err != nil {
+		c.String(errorStatus(err), "Error fetching debtors: %v", err)
+		return
+	}
+
+	c.HTML(http.StatusOK, "debtors.html", gin.H{
+		"Title":   "Дебиторская задолженность",
+		"Debtors": debtors,
+	})
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/student/my-kpfu-db-app/internal/exchange"
+)
+
+// ============================================================================
+// Currencies and Exchange Rates
+// ============================================================================
+
+func (h *Handler) GetCurrencies(c *gin.Context) {
+	currencies, err := h.repo.GetCurrencies(c.Request.Context())
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, currencies)
+}
+
+func (h *Handler) GetExchangeRates(c *gin.Context) {
+	rates, err := h.repo.GetExchangeRates(c.Request.Context(), strings.ToUpper(c.Query("currency")))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rates)
+}
+
+// ImportExchangeRates loads rates from a CBR XML or CSV file sent either as
+// the multipart field "file" or as the raw request body.
+func (h *Handler) ImportExchangeRates(c *gin.Context) {
+	var body io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		file, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		f, err := file.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		defer f.Close()
+		body = f
+	}
+
+	rates, err := exchange.Parse(body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	imported, err := h.repo.ImportExchangeRates(c.Request.Context(), rates)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"imported": imported, "skipped": len(rates) - imported})
+}
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/student/my-kpfu-db-app/internal/domain"
+)
+
+// ============================================================================
+// Sales Dashboard
+// ============================================================================
+
+const (
+	defaultDashboardTop = 10
+	maxDashboardTop     = 100
+	// maxDashboardPeriods bounds the length of a time series, so that a long
+	// range is not requested by days
+	maxDashboardPeriods = 400
+)
+
+// granularityDays is the approximate length of a period, used to bound the
+// number of periods.
+var granularityDays = map[domain.Granularity]int{
+	domain.GranularityDay:     1,
+	domain.GranularityWeek:    7,
+	domain.GranularityMonth:   30,
+	domain.GranularityQuarter: 91,
+}
+
+// dashboardFilter reads the dashboard options from the query string: an
+// inclusive ?from=&to= range (by default the last 12 months including the
+// current one), granularity (month), by (warehouse), top (10) and currency
+// (the base one).
+func dashboardFilter(c *gin.Context) (domain.DashboardFilter, error) {
+	f := domain.DashboardFilter{
+		Granularity: domain.GranularityMonth,
+		By:          domain.DimensionWarehouse,
+		TopN:        defaultDashboardTop,
+		Currency:    reportCurrency(c),
+	}
+	if g := c.Query("granularity"); g != "" {
+		f.Granularity = domain.Granularity(g)
+		if !f.Granularity.Valid() {
+			return f, errors.New("unknown granularity: " + g)
+		}
+	}
+	if by := c.Query("by"); by != "" {
+		f.By = domain.SalesDimension(by)
+		if !f.By.Valid() || f.By == domain.DimensionPart {
+			return f, errors.New("unknown dimension: " + by)
+		}
+	}
+	if top := c.Query("top"); top != "" {
+		n, err := strconv.Atoi(top)
+		if err != nil || n <= 0 || n > maxDashboardTop {
+			return f, errors.New("top must be between 1 and 100")
+		}
+		f.TopN = n
+	}
+
+	var ok bool
+	var err error
+	if f.From, f.To, ok, err = queryRange(c); err != nil {
+		return f, err
+	}
+	if !ok {
+		now := time.Now()
+		f.To = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, 1, 0)
+		f.From = f.To.AddDate(-1, 0, 0)
+	}
+
+	days := int(f.To.Sub(f.From).Hours() / 24)
+	if days/granularityDays[f.Granularity] > maxDashboardPeriods {
+		return f, errors.New("too many periods: choose a longer granularity or a shorter range")
+	}
+	return f, nil
+}
+
+func (h *Handler) GetDashboard(c *gin.Context) {
+	filter, err := dashboardFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	dashboard, err := h.repo.GetDashboard(c.Request.Context(), filter)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dashboard)
+}
+
+// DashboardPage renders the sales overview with charts.
+func (h *Handler) DashboardPage(c *gin.Context) {
+	filter, err := dashboardFilter(c)
+	if err != nil {
+		c.String(http.StatusBadRequest, "Invalid dashboard options: %v", err)
+		return
+	}
+
+	dashboard, err := h.repo.GetDashboard(c.Request.Context(), filter)
+	if err != nil {
+		c.String(errorStatus(err), "Error fetching sales data: %v", err)
+		return
+	}
+	currencies, err := h.repo.GetCurrencies(c.Request.Context())
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Error fetching currencies: %v", err)
+		return
+	}
+
+	c.HTML(http.StatusOK, "dashboard.html", gin.H{
+		"Title":         "Аналитика продаж",
+		"Dashboard":     dashboard,
+		"Filter":        filter,
+		"Last":          filter.To.AddDate(0, 0, -1),
+		"Granularities": domain.Granularities,
+		"Dimensions":    domain.SalesDimensions,
+		"Currencies":    currencies,
+	})
+}
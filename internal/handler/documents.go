@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// Printable Documents
+// ============================================================================
+
+func (h *Handler) Waybill(c *gin.Context) {
+	warehouse, _ := strconv.Atoi(c.Param("warehouse"))
+	doc, _ := strconv.Atoi(c.Param("doc"))
+
+	lines, err := h.repo.GetShipmentDocument(c.Request.Context(), warehouse, doc)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	// Render into a buffer first so a failure still produces a JSON error
+	var buf bytes.Buffer
+	if err := h.docs.Waybill(&buf, lines); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`inline; filename="waybill-%d-%d.pdf"`, warehouse, doc))
+	c.Data(http.StatusOK, "application/pdf", buf.Bytes())
+}
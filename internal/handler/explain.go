@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/student/my-kpfu-db-app/internal/domain"
+)
+
+// ============================================================================
+// Report Query Plans
+// ============================================================================
+
+// ExplainReport runs EXPLAIN ANALYZE for a report query with the same query
+// parameters as the report itself: task-1 (?city=), task-2, task-3 and view
+// (?status=&currency=).
+func (h *Handler) ExplainReport(c *gin.Context) {
+	ctx := c.Request.Context()
+	var plan *domain.Plan
+	var err error
+
+	switch c.Param("report") {
+	case "task-1":
+		city := c.Query("city")
+		if city == "" {
+			city = "Казань"
+		}
+		plan, err = h.repo.ExplainTask1(ctx, city)
+	case "task-2":
+		filter, ferr := task2Filter(c)
+		if ferr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": ferr.Error()})
+			return
+		}
+		plan, err = h.repo.ExplainTask2(ctx, filter)
+	case "task-3":
+		q, qerr := task3Query(c)
+		if qerr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": qerr.Error()})
+			return
+		}
+		plan, err = h.repo.ExplainTask3(ctx, q)
+	case "view":
+		status, ok := statusFilter(c)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown shipment status: " + string(status)})
+			return
+		}
+		plan, err = h.repo.ExplainFullShipmentInfo(ctx, status, reportCurrency(c))
+	default:
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown report: " + c.Param("report")})
+		return
+	}
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, plan)
+}
@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/student/my-kpfu-db-app/internal/domain"
+)
+
+// ============================================================================
+// Demand Forecast
+// ============================================================================
+
+const (
+	defaultForecastMonths = 6
+	maxForecastMonths     = 24
+)
+
+// forecastQuery reads ?part=&warehouse= (no warehouse - all) and the horizon
+// and holdout in months, 6 by default.
+func forecastQuery(c *gin.Context) (domain.ForecastQuery, error) {
+	q := domain.ForecastQuery{
+		PartCode: c.Query("part"),
+		Horizon:  defaultForecastMonths,
+		Holdout:  defaultForecastMonths,
+	}
+	if v := c.Query("warehouse"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return q, errors.New("warehouse must be a positive number")
+		}
+		q.WarehouseNo = &n
+	}
+	for _, p := range []struct {
+		name  string
+		value *int
+	}{{"horizon", &q.Horizon}, {"holdout", &q.Holdout}} {
+		v := c.Query(p.name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxForecastMonths {
+			return q, errors.New(p.name + " must be between 1 and 24")
+		}
+		*p.value = n
+	}
+	return q, nil
+}
+
+func (h *Handler) GetForecast(c *gin.Context) {
+	q, err := forecastQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if q.PartCode == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "part is required: parts are measured in different units"})
+		return
+	}
+
+	forecast, err := h.repo.GetForecast(c.Request.Context(), q)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, forecast)
+}
+
+// ForecastPage renders the shipment history with the forecasts of every
+// method on a chart; without ?part= it shows the first part.
+func (h *Handler) ForecastPage(c *gin.Context) {
+	ctx := c.Request.Context()
+	q, err := forecastQuery(c)
+	if err != nil {
+		c.String(http.StatusBadRequest, "Invalid forecast options: %v", err)
+		return
+	}
+
+	parts, err := h.repo.GetParts(ctx)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Error fetching parts: %v", err)
+		return
+	}
+	if q.PartCode == "" && len(parts) > 0 {
+		q.PartCode = parts[0].PartCode
+	}
+
+	forecast, err := h.repo.GetForecast(ctx, q)
+	if err != nil {
+		c.String(errorStatus(err), "Error forecasting shipments: %v", err)
+		return
+	}
+
+	labels := make(map[domain.ForecastMethod]string, len(domain.ForecastMethods))
+	for _, m := range domain.ForecastMethods {
+		labels[m] = m.Label()
+	}
+
+	c.HTML(http.StatusOK, "forecast.html", gin.H{
+		"Title":        "Прогноз спроса",
+		"Forecast":     forecast,
+		"Parts":        parts,
+		"MethodLabels": labels,
+	})
+}
@@ -1,22 +1,31 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/student/my-kpfu-db-app/internal/config"
+	"github.com/student/my-kpfu-db-app/internal/document"
 	"github.com/student/my-kpfu-db-app/internal/domain"
 	"github.com/student/my-kpfu-db-app/internal/repository"
 )
 
-// Handler holds the repository.
+// Handler holds the repository, the CRUD store of the configured backend,
+// the PDF document renderer and configuration.
 type Handler struct {
-	repo *repository.Repository
+	repo  *repository.Repository
+	store repository.Store
+	docs  *document.Renderer
+	cfg   *config.Config
 }
 
 // New creates a new Handler.
-func New(repo *repository.Repository) *Handler {
-	return &Handler{repo: repo}
+func New(repo *repository.Repository, store repository.Store, docs *document.Renderer, cfg *config.Config) *Handler {
+	return &Handler{repo: repo, store: store, docs: docs, cfg: cfg}
 }
 
 // RegisterRoutes registers all routes for the application.
@@ -25,36 +34,129 @@ func (h *Handler) RegisterRoutes(r *gin.Engine) {
 	r.GET("/", h.Home)
 	r.GET("/view", h.View)
 	r.GET("/dynamic", h.Dynamic)
+	r.GET("/debtors", h.DebtorsPage)
+	r.GET("/trash", h.TrashPage)
 
 	// Task pages
 	r.GET("/task-1", h.Task1Page)
 	r.GET("/task-2", h.Task2Page)
 	r.GET("/task-3", h.Task3Page)
+	r.GET("/bench", h.BenchPage)
+	r.GET("/dashboard", h.DashboardPage)
+	r.GET("/abc-xyz", h.ABCXYZPage)
+	r.GET("/forecast", h.ForecastPage)
 
 	// API endpoints for CRUD operations
 	api := r.Group("/api")
+	api.Use(h.withUser)
 	{
 		// Parts
 		api.POST("/parts", h.CreatePart)
 		api.PUT("/parts/:code", h.UpdatePart)
 		api.DELETE("/parts/:code", h.DeletePart)
+		api.POST("/parts/:code/restore", h.RestorePart)
+		api.POST("/parts/:code/rename", h.RenamePart)
+
+		// Currencies and exchange rates
+		api.GET("/currencies", h.GetCurrencies)
+		api.GET("/exchange-rates", h.GetExchangeRates)
+		api.POST("/exchange-rates", h.ImportExchangeRates)
+
+		// Units of measure
+		api.GET("/units", h.GetUnits)
+		api.GET("/parts/:code/units", h.GetPartUnits)
+		api.POST("/parts/:code/units", h.AddPartUnit)
+		api.DELETE("/parts/:code/units/:unit", h.DeletePartUnit)
 
 		// Customers
 		api.POST("/customers", h.CreateCustomer)
 		api.PUT("/customers/:id", h.UpdateCustomer)
 		api.DELETE("/customers/:id", h.DeleteCustomer)
+		api.POST("/customers/:id/restore", h.RestoreCustomer)
+		api.GET("/customers/duplicates", h.FindDuplicateCustomers)
+		api.POST("/customers/:id/merge", h.MergeCustomers)
+		api.GET("/customers/:id/balance", h.GetCustomerBalance)
+		api.GET("/customers/:id/contacts", h.GetContacts)
+		api.POST("/customers/:id/contacts", h.CreateContact)
+		api.DELETE("/customers/:id/contacts/:contact_id", h.DeleteContact)
+
+		// Trash and purge (purge is for administrators only)
+		api.GET("/trash", h.GetTrash)
+		admin := api.Group("/admin", h.requireAdmin)
+		admin.DELETE("/customers/:id", h.PurgeCustomer)
+		admin.DELETE("/parts/:code", h.PurgePart)
+
+		// Change log and undo
+		api.GET("/changes", h.GetChanges)
+		api.POST("/changes/undo", h.UndoLast)
+		api.POST("/changes/:id/undo", h.UndoChange)
+
+		// Cities
+		api.GET("/cities", h.GetCities)
+		api.POST("/cities", h.CreateCity)
+
+		// Payments and receivables
+		api.POST("/payments", h.RecordPayment)
+		api.GET("/payments", h.GetPayments)
+		api.GET("/debtors", h.GetDebtors)
+
+		// Pricing
+		api.GET("/price-lists", h.GetPriceLists)
+		api.POST("/price-lists", h.CreatePriceListEntry)
+		api.DELETE("/price-lists/:id", h.DeletePriceListEntry)
+		api.GET("/discounts", h.GetDiscountRules)
+		api.POST("/discounts", h.CreateDiscountRule)
+		api.DELETE("/discounts/:id", h.DeleteDiscountRule)
+		api.GET("/volume-discounts", h.GetVolumeDiscounts)
+		api.POST("/volume-discounts", h.CreateVolumeDiscount)
+		api.DELETE("/volume-discounts/:id", h.DeleteVolumeDiscount)
+		api.GET("/prices/quote", h.QuotePrice)
 
 		// Shipments
 		api.POST("/shipments", h.CreateShipment)
 		api.PUT("/shipments/:warehouse/:doc", h.UpdateShipment)
 		api.DELETE("/shipments/:warehouse/:doc", h.DeleteShipment)
 
+		// Shipment lifecycle
+		for _, action := range domain.ShipmentActions {
+			api.POST("/shipments/:warehouse/:doc/"+action.Name, h.TransitionShipment(action))
+		}
+		api.GET("/shipments/:warehouse/:doc/history", h.GetShipmentHistory)
+
+		// Customer returns
+		api.POST("/shipments/:warehouse/:doc/returns", h.CreateReturn)
+		api.GET("/shipments/:warehouse/:doc/returns", h.GetShipmentReturns)
+		api.GET("/returns", h.GetReturns)
+
+		// Printable documents
+		api.GET("/shipments/:warehouse/:doc/waybill.pdf", h.Waybill)
+
+		// Invoices
+		api.POST("/invoices", h.CreateInvoice)
+		api.GET("/invoices", h.GetInvoices)
+		api.GET("/invoices/:id", h.GetInvoice)
+		api.GET("/invoices/:id/invoice.pdf", h.InvoicePDF)
+		api.POST("/invoices/:id/void", h.VoidInvoice)
+
 		// Tasks
 		api.GET("/task-1/sql", h.Task1SQL)
 		api.GET("/task-1/orm", h.Task1ORM)
+		api.GET("/task-1/compare", h.CompareTask1)
 		api.GET("/task-2", h.Task2)
 		api.GET("/task-3/sql", h.Task3SQL)
 		api.GET("/task-3/record", h.Task3Record)
+		api.GET("/task-3/query", h.Task3Query)
+		api.GET("/task-3/compare", h.CompareTask3)
+		api.GET("/bench", h.GetBenchRuns)
+		api.GET("/explain/:report", h.ExplainReport)
+
+		// Sales analytics
+		api.GET("/dashboard", h.GetDashboard)
+		api.GET("/abc-xyz", h.GetABCXYZ)
+		api.POST("/abc-xyz/snapshots", h.CreateABCXYZSnapshot)
+		api.GET("/abc-xyz/snapshots", h.GetABCXYZSnapshots)
+		api.GET("/abc-xyz/snapshots/:id", h.GetABCXYZSnapshot)
+		api.GET("/forecast", h.GetForecast)
 
 		// Dynamic table data
 		api.GET("/table/:name", h.GetTableData)
@@ -64,47 +166,219 @@ func (h *Handler) RegisterRoutes(r *gin.Engine) {
 	}
 }
 
+// withUser stores the operator name from the X-User header in the request
+// context, so repository methods can record who made a change.
+func (h *Handler) withUser(c *gin.Context) {
+	if user := c.GetHeader("X-User"); user != "" {
+		c.Request = c.Request.WithContext(repository.WithUser(c.Request.Context(), user))
+	}
+	c.Next()
+}
+
+// writeError maps repository errors to HTTP status codes.
+func writeError(c *gin.Context, err error) {
+	c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+}
+
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, repository.ErrUnknownCurrency), errors.Is(err, repository.ErrUnknownCity):
+		return http.StatusBadRequest
+	case errors.Is(err, repository.ErrInvalidTransition), errors.Is(err, repository.ErrShipmentLocked),
+		errors.Is(err, repository.ErrNotReturnable), errors.Is(err, repository.ErrCreditLimit),
+		errors.Is(err, repository.ErrArchived), errors.Is(err, repository.ErrNotArchived),
+		errors.Is(err, repository.ErrPurgeBlocked), errors.Is(err, repository.ErrUndoConflict),
+		errors.Is(err, repository.ErrPartCodeTaken):
+		return http.StatusConflict
+	case errors.Is(err, repository.ErrReturnQty), errors.Is(err, repository.ErrReturnDate),
+		errors.Is(err, repository.ErrNothingToInvoice), errors.Is(err, repository.ErrUnitNotAllowed),
+		errors.Is(err, repository.ErrNoExchangeRate),
+		errors.Is(err, repository.ErrInvalidPricing), errors.Is(err, repository.ErrInvalidPayment),
+		errors.Is(err, repository.ErrInvalidCreditLimit), errors.Is(err, repository.ErrInvalidAddress),
+		errors.Is(err, repository.ErrInvalidContact), errors.Is(err, repository.ErrInvalidMerge),
+		errors.Is(err, repository.ErrInvalidPartCode), errors.Is(err, repository.ErrInvalidPeriod):
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusInternalServerError
+}
+
+// reportCurrency reads the optional ?currency= query parameter.
+func reportCurrency(c *gin.Context) string {
+	if currency := strings.ToUpper(c.Query("currency")); currency != "" {
+		return currency
+	}
+	return domain.BaseCurrency
+}
+
+// statusFilter reads the optional ?status= query parameter.
+func statusFilter(c *gin.Context) (domain.ShipmentStatus, bool) {
+	status := domain.ShipmentStatus(c.Query("status"))
+	return status, status == "" || status.Valid()
+}
+
+// task2Filter reads the Task 2 report options from the query string. The
+// period is either ?year= (the current year by default) or an inclusive
+// ?from=&to= range of dates, which takes precedence.
+func task2Filter(c *gin.Context) (domain.Task2Filter, error) {
+	f := domain.Task2Filter{
+		Partition: domain.PartitionPart,
+		PartCode:  strings.TrimSpace(c.Query("part_code")),
+	}
+	if p := c.Query("partition"); p != "" {
+		f.Partition = domain.Task2Partition(p)
+		if !f.Partition.Valid() {
+			return f, errors.New("unknown partition: " + p)
+		}
+	}
+
+	var err error
+	if w := c.Query("warehouse_no"); w != "" {
+		if f.WarehouseNo, err = strconv.Atoi(w); err != nil || f.WarehouseNo <= 0 {
+			return f, errors.New("invalid warehouse_no")
+		}
+	}
+	if id := c.Query("customer_id"); id != "" {
+		if f.CustomerID, err = strconv.Atoi(id); err != nil || f.CustomerID <= 0 {
+			return f, errors.New("invalid customer_id")
+		}
+	}
+
+	var ok bool
+	if f.From, f.To, ok, err = queryRange(c); ok || err != nil {
+		return f, err
+	}
+
+	year := time.Now().Year()
+	if y := c.Query("year"); y != "" {
+		if year, err = strconv.Atoi(y); err != nil || year < 1 || year > 9999 {
+			return f, errors.New("invalid year")
+		}
+	}
+	f.From, f.To = domain.YearPeriod(year)
+	return f, nil
+}
+
+// queryRange reads the inclusive from and to dates and returns them as the
+// half-open range [from, to+1). ok is false when neither is set.
+func queryRange(c *gin.Context) (time.Time, time.Time, bool, error) {
+	var from, to time.Time
+	fromStr, toStr := c.Query("from"), c.Query("to")
+	if fromStr == "" && toStr == "" {
+		return from, to, false, nil
+	}
+	if fromStr == "" || toStr == "" {
+		return from, to, false, errors.New("from and to must be set together")
+	}
+
+	var err error
+	if from, err = time.Parse("2006-01-02", fromStr); err != nil {
+		return from, to, false, errors.New("invalid from date")
+	}
+	if to, err = time.Parse("2006-01-02", toStr); err != nil {
+		return from, to, false, errors.New("invalid to date")
+	}
+	if to.Before(from) {
+		return from, to, false, errors.New("to is before from")
+	}
+	return from, to.AddDate(0, 0, 1), true, nil
+}
+
 // ============================================================================
 // Main Pages
 // ============================================================================
 
 func (h *Handler) Home(c *gin.Context) {
-	parts, err := h.repo.GetParts(c.Request.Context())
+	status, ok := statusFilter(c)
+	if !ok {
+		c.String(http.StatusBadRequest, "Unknown shipment status: %s", status)
+		return
+	}
+
+	parts, err := h.store.GetParts(c.Request.Context())
 	if err != nil {
 		c.String(http.StatusInternalServerError, "Error fetching parts: %v", err)
 		return
 	}
 
-	customers, err := h.repo.GetCustomers(c.Request.Context())
+	customers, err := h.store.GetCustomers(c.Request.Context())
 	if err != nil {
 		c.String(http.StatusInternalServerError, "Error fetching customers: %v", err)
 		return
 	}
 
-	shipments, err := h.repo.GetShipments(c.Request.Context())
+	shipments, err := h.store.GetShipments(c.Request.Context(), status)
 	if err != nil {
 		c.String(http.StatusInternalServerError, "Error fetching shipments: %v", err)
 		return
 	}
 
+	returns, err := h.repo.GetReturns(c.Request.Context())
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Error fetching returns: %v", err)
+		return
+	}
+
+	units, err := h.repo.GetUnits(c.Request.Context())
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Error fetching units: %v", err)
+		return
+	}
+
+	currencies, err := h.repo.GetCurrencies(c.Request.Context())
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Error fetching currencies: %v", err)
+		return
+	}
+
+	cities, err := h.repo.GetCities(c.Request.Context())
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Error fetching cities: %v", err)
+		return
+	}
+
 	c.HTML(http.StatusOK, "home.html", gin.H{
-		"Title":     "Главная",
-		"Parts":     parts,
-		"Customers": customers,
-		"Shipments": shipments,
+		"Title":      "Главная",
+		"Parts":      parts,
+		"Customers":  customers,
+		"Shipments":  shipments,
+		"Returns":    returns,
+		"Units":      units,
+		"Currencies": currencies,
+		"Cities":     cities,
+		"Statuses":   domain.ShipmentStatuses,
+		"Status":     status,
 	})
 }
 
 func (h *Handler) View(c *gin.Context) {
-	fullInfo, err := h.repo.GetFullShipmentInfo(c.Request.Context())
+	status, ok := statusFilter(c)
+	if !ok {
+		c.String(http.StatusBadRequest, "Unknown shipment status: %s", status)
+		return
+	}
+
+	currency := reportCurrency(c)
+	fullInfo, err := h.repo.GetFullShipmentInfo(c.Request.Context(), status, currency)
 	if err != nil {
-		c.String(http.StatusInternalServerError, "Error fetching view data: %v", err)
+		c.String(errorStatus(err), "Error fetching view data: %v", err)
+		return
+	}
+
+	currencies, err := h.repo.GetCurrencies(c.Request.Context())
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Error fetching currencies: %v", err)
 		return
 	}
 
 	c.HTML(http.StatusOK, "view.html", gin.H{
-		"Title":    "VIEW - Полная информация об отгрузках",
-		"FullInfo": fullInfo,
+		"Title":      "VIEW - Полная информация об отгрузках",
+		"FullInfo":   fullInfo,
+		"Statuses":   domain.ShipmentStatuses,
+		"Status":     status,
+		"Currencies": currencies,
+		"Currency":   currency,
 	})
 }
 
@@ -125,21 +399,42 @@ func (h *Handler) Task1Page(c *gin.Context) {
 }
 
 func (h *Handler) Task2Page(c *gin.Context) {
-	results, err := h.repo.GetTask2(c.Request.Context())
+	filter, err := task2Filter(c)
+	if err != nil {
+		c.String(http.StatusBadRequest, "Invalid report options: %v", err)
+		return
+	}
+
+	results, err := h.repo.GetTask2(c.Request.Context(), filter)
 	if err != nil {
 		c.String(http.StatusInternalServerError, "Error fetching task 2 data: %v", err)
 		return
 	}
 
+	customers, err := h.store.GetCustomers(c.Request.Context())
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Error fetching customers: %v", err)
+		return
+	}
+
 	c.HTML(http.StatusOK, "task2.html", gin.H{
-		"Title":   "Задача 2: Отгрузки текущего года",
-		"Results": results,
+		"Title":      "Задача 2: Отгрузки за период",
+		"Results":    results,
+		"Filter":     filter,
+		"Last":       filter.To.AddDate(0, 0, -1),
+		"Range":      c.Query("from") != "",
+		"Customers":  customers,
+		"Partitions": domain.Task2Partitions,
 	})
 }
 
 func (h *Handler) Task3Page(c *gin.Context) {
 	c.HTML(http.StatusOK, "task3.html", gin.H{
-		"Title": "Задача 3: Кванторный запрос",
+		"Title":               "Задача 3: Кванторный запрос",
+		"Query":               domain.DefaultTask3Query(),
+		"PartQuantifiers":     domain.PartQuantifiers,
+		"ShipmentQuantifiers": domain.ShipmentQuantifiers,
+		"PartTypes":           domain.PartTypes,
 	})
 }
 
@@ -154,8 +449,8 @@ func (h *Handler) CreatePart(c *gin.Context) {
 		return
 	}
 
-	if err := h.repo.CreatePart(c.Request.Context(), &part); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.store.CreatePart(c.Request.Context(), &part); err != nil {
+		writeError(c, err)
 		return
 	}
 
@@ -171,22 +466,62 @@ func (h *Handler) UpdatePart(c *gin.Context) {
 
 	part.PartCode = c.Param("code")
 
-	if err := h.repo.UpdatePart(c.Request.Context(), &part); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	ctx := c.Request.Context()
+	if h.dryRunStore(c, func(store repository.Store) error { return store.UpdatePart(ctx, &part) }) {
+		return
+	}
+
+	if err := h.store.UpdatePart(c.Request.Context(), &part); err != nil {
+		writeError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, part)
 }
 
+type renamePartRequest struct {
+	NewCode string `json:"new_code" binding:"required"`
+}
+
+// RenamePart changes a part code. It supports ?dry_run=true.
+func (h *Handler) RenamePart(c *gin.Context) {
+	var req renamePartRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	code := c.Param("code")
+	ctx := c.Request.Context()
+	if h.dryRun(c, func(tx *repository.Repository) error {
+		_, err := tx.RenamePart(ctx, code, req.NewCode)
+		return err
+	}) {
+		return
+	}
+
+	rename, err := h.repo.RenamePart(ctx, code, req.NewCode)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rename)
+}
+
 func (h *Handler) DeletePart(c *gin.Context) {
 	code := c.Param("code")
-	if err := h.repo.DeletePart(c.Request.Context(), code); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	ctx := c.Request.Context()
+	if h.dryRunStore(c, func(store repository.Store) error { return store.DeletePart(ctx, code) }) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Part deleted"})
+	if err := h.store.DeletePart(c.Request.Context(), code); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Part moved to trash"})
 }
 
 func (h *Handler) CreateCustomer(c *gin.Context) {
@@ -196,8 +531,8 @@ func (h *Handler) CreateCustomer(c *gin.Context) {
 		return
 	}
 
-	if err := h.repo.CreateCustomer(c.Request.Context(), &customer); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.store.CreateCustomer(c.Request.Context(), &customer); err != nil {
+		writeError(c, err)
 		return
 	}
 
@@ -214,8 +549,13 @@ func (h *Handler) UpdateCustomer(c *gin.Context) {
 	id, _ := strconv.Atoi(c.Param("id"))
 	customer.CustomerID = id
 
-	if err := h.repo.UpdateCustomer(c.Request.Context(), &customer); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	ctx := c.Request.Context()
+	if h.dryRunStore(c, func(store repository.Store) error { return store.UpdateCustomer(ctx, &customer) }) {
+		return
+	}
+
+	if err := h.store.UpdateCustomer(c.Request.Context(), &customer); err != nil {
+		writeError(c, err)
 		return
 	}
 
@@ -224,12 +564,17 @@ func (h *Handler) UpdateCustomer(c *gin.Context) {
 
 func (h *Handler) DeleteCustomer(c *gin.Context) {
 	id, _ := strconv.Atoi(c.Param("id"))
-	if err := h.repo.DeleteCustomer(c.Request.Context(), id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	ctx := c.Request.Context()
+	if h.dryRunStore(c, func(store repository.Store) error { return store.DeleteCustomer(ctx, id) }) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Customer deleted"})
+	if err := h.store.DeleteCustomer(c.Request.Context(), id); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Customer moved to trash"})
 }
 
 func (h *Handler) CreateShipment(c *gin.Context) {
@@ -239,14 +584,32 @@ func (h *Handler) CreateShipment(c *gin.Context) {
 		return
 	}
 
-	if err := h.repo.CreateShipment(c.Request.Context(), &shipment); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	// ?override_credit_limit=true records the shipment even over the customer's credit limit
+	override := c.Query("override_credit_limit") == "true"
+	if err := h.store.CreateShipment(c.Request.Context(), &shipment, override); err != nil {
+		writeShipmentError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusCreated, shipment)
 }
 
+// writeShipmentError reports a credit limit breach with the amounts, so the
+// operator can repeat the request with ?override_credit_limit=true.
+func writeShipmentError(c *gin.Context, err error) {
+	var limitErr *repository.CreditLimitError
+	if errors.As(err, &limitErr) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":        err.Error(),
+			"credit_limit": limitErr.CreditLimit,
+			"exposure":     limitErr.Exposure,
+			"currency":     limitErr.Currency,
+		})
+		return
+	}
+	writeError(c, err)
+}
+
 func (h *Handler) UpdateShipment(c *gin.Context) {
 	var shipment domain.Shipment
 	if err := c.ShouldBindJSON(&shipment); err != nil {
@@ -259,8 +622,14 @@ func (h *Handler) UpdateShipment(c *gin.Context) {
 	shipment.WarehouseNo = warehouse
 	shipment.ShipmentDocNo = doc
 
-	if err := h.repo.UpdateShipment(c.Request.Context(), &shipment); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	override := c.Query("override_credit_limit") == "true"
+	ctx := c.Request.Context()
+	if h.dryRunStore(c, func(store repository.Store) error { return store.UpdateShipment(ctx, &shipment, override) }) {
+		return
+	}
+
+	if err := h.store.UpdateShipment(c.Request.Context(), &shipment, override); err != nil {
+		writeShipmentError(c, err)
 		return
 	}
 
@@ -271,14 +640,49 @@ func (h *Handler) DeleteShipment(c *gin.Context) {
 	warehouse, _ := strconv.Atoi(c.Param("warehouse"))
 	doc, _ := strconv.Atoi(c.Param("doc"))
 
-	if err := h.repo.DeleteShipment(c.Request.Context(), warehouse, doc); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	ctx := c.Request.Context()
+	if h.dryRunStore(c, func(store repository.Store) error { return store.DeleteShipment(ctx, warehouse, doc) }) {
+		return
+	}
+
+	if err := h.store.DeleteShipment(c.Request.Context(), warehouse, doc); err != nil {
+		writeError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Shipment deleted"})
 }
 
+// TransitionShipment returns a handler moving the shipment along the
+// lifecycle to the action's target status.
+func (h *Handler) TransitionShipment(action domain.ShipmentAction) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		warehouse, _ := strconv.Atoi(c.Param("warehouse"))
+		doc, _ := strconv.Atoi(c.Param("doc"))
+
+		shipment, err := h.repo.TransitionShipment(c.Request.Context(), warehouse, doc, action.Target)
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, shipment)
+	}
+}
+
+func (h *Handler) GetShipmentHistory(c *gin.Context) {
+	warehouse, _ := strconv.Atoi(c.Param("warehouse"))
+	doc, _ := strconv.Atoi(c.Param("doc"))
+
+	history, err := h.repo.GetShipmentStatusHistory(c.Request.Context(), warehouse, doc)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
 // ============================================================================
 // Task API Handlers
 // ============================================================================
@@ -314,7 +718,13 @@ func (h *Handler) Task1ORM(c *gin.Context) {
 }
 
 func (h *Handler) Task2(c *gin.Context) {
-	results, err := h.repo.GetTask2(c.Request.Context())
+	filter, err := task2Filter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	results, err := h.repo.GetTask2(c.Request.Context(), filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -324,7 +734,13 @@ func (h *Handler) Task2(c *gin.Context) {
 }
 
 func (h *Handler) Task3SQL(c *gin.Context) {
-	results, err := h.repo.GetTask3SQL(c.Request.Context())
+	q, err := task3Query(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	results, err := h.repo.GetTask3SQL(c.Request.Context(), q)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -334,7 +750,13 @@ func (h *Handler) Task3SQL(c *gin.Context) {
 }
 
 func (h *Handler) Task3Record(c *gin.Context) {
-	results, err := h.repo.GetTask3RecordBased(c.Request.Context())
+	q, err := task3Query(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	results, err := h.repo.GetTask3RecordBased(c.Request.Context(), q)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -362,9 +784,9 @@ func (h *Handler) GetProcedureResult(c *gin.Context) {
 		return
 	}
 
-	result, err := h.repo.GetCustomerShipmentSummary(c.Request.Context(), customerID)
+	result, err := h.repo.GetCustomerShipmentSummary(c.Request.Context(), customerID, reportCurrency(c))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeError(c, err)
 		return
 	}
 
@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/student/my-kpfu-db-app/internal/repository"
+)
+
+// dryRun serves ?dry_run=true: op runs in a transaction that is rolled back
+// and the response is the per-table impact report. It reports whether the
+// request was handled, so the caller performs the operation only when it
+// returns false.
+func (h *Handler) dryRun(c *gin.Context, op func(tx *repository.Repository) error) bool {
+	if c.Query("dry_run") != "true" {
+		return false
+	}
+
+	impact, err := h.repo.DryRun(c.Request.Context(), op)
+	if err != nil {
+		writeError(c, err)
+		return true
+	}
+
+	c.JSON(http.StatusOK, impact)
+	return true
+}
+
+// dryRunStore is dryRun for the CRUD operations: op gets the store of the
+// configured backend bound to the rolled-back transaction, so the preview
+// runs the same code as the write.
+func (h *Handler) dryRunStore(c *gin.Context, op func(store repository.Store) error) bool {
+	return h.dryRun(c, func(tx *repository.Repository) error {
+		store, err := tx.Store(h.cfg.RepositoryBackend)
+		if err != nil {
+			return err
+		}
+		return op(store)
+	})
+}
@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"github.com/student/my-kpfu-db-app/internal/domain"
+)
+
+// ============================================================================
+// Invoices
+// ============================================================================
+
+// invoiceRequest describes the billing period; dates are YYYY-MM-DD. VATRate
+// is a percentage between 0 and 100 with at most two decimals.
+type invoiceRequest struct {
+	CustomerID int              `json:"customer_id" binding:"required"`
+	PeriodFrom string           `json:"period_from" binding:"required"`
+	PeriodTo   string           `json:"period_to" binding:"required"`
+	VATRate    *decimal.Decimal `json:"vat_rate"`
+}
+
+func (h *Handler) CreateInvoice(c *gin.Context) {
+	var req invoiceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	from, err := time.Parse("2006-01-02", req.PeriodFrom)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid period_from"})
+		return
+	}
+	to, err := time.Parse("2006-01-02", req.PeriodTo)
+	if err != nil || to.Before(from) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid period_to"})
+		return
+	}
+
+	vatRate := h.cfg.VATRate
+	if req.VATRate != nil {
+		vatRate = *req.VATRate
+		if !domain.ValidVATRate(vatRate) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "vat_rate must be between 0 and 100 with at most two decimals"})
+			return
+		}
+	}
+
+	invoice, err := h.repo.CreateInvoice(c.Request.Context(), req.CustomerID, from, to, vatRate)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, invoice)
+}
+
+func (h *Handler) GetInvoices(c *gin.Context) {
+	customerID, _ := strconv.Atoi(c.Query("customer_id"))
+
+	invoices, err := h.repo.GetInvoices(c.Request.Context(), customerID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, invoices)
+}
+
+func (h *Handler) GetInvoice(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invoice ID"})
+		return
+	}
+
+	invoice, err := h.repo.GetInvoice(c.Request.Context(), id)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	if c.Query("download") != "" {
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="invoice-%s.json"`, invoice.Number))
+	}
+	c.JSON(http.StatusOK, invoice)
+}
+
+func (h *Handler) InvoicePDF(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invoice ID"})
+		return
+	}
+
+	invoice, err := h.repo.GetInvoice(c.Request.Context(), id)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := h.docs.Invoice(&buf, invoice); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`inline; filename="invoice-%s.pdf"`, invoice.Number))
+	c.Data(http.StatusOK, "application/pdf", buf.Bytes())
+}
+
+func (h *Handler) VoidInvoice(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invoice ID"})
+		return
+	}
+
+	invoice, err := h.repo.VoidInvoice(c.Request.Context(), id)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, invoice)
+}
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/student/my-kpfu-db-app/internal/dedup"
+	"github.com/student/my-kpfu-db-app/internal/repository"
+)
+
+// ============================================================================
+// Duplicate Customers
+// ============================================================================
+
+func (h *Handler) FindDuplicateCustomers(c *gin.Context) {
+	threshold := dedup.DefaultThreshold
+	if v := c.Query("threshold"); v != "" {
+		t, err := strconv.ParseFloat(v, 64)
+		if err != nil || t <= 0 || t > 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "threshold must be between 0 and 1"})
+			return
+		}
+		threshold = t
+	}
+
+	duplicates, err := h.repo.FindDuplicateCustomers(c.Request.Context(), threshold)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, duplicates)
+}
+
+type mergeRequest struct {
+	DuplicateID int `json:"duplicate_id" binding:"required"`
+}
+
+// MergeCustomers merges the customer given in the body into the one in the
+// path. It supports ?dry_run=true.
+func (h *Handler) MergeCustomers(c *gin.Context) {
+	var req mergeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, _ := strconv.Atoi(c.Param("id"))
+	ctx := c.Request.Context()
+	if h.dryRun(c, func(tx *repository.Repository) error {
+		_, err := tx.MergeCustomers(ctx, id, req.DuplicateID)
+		return err
+	}) {
+		return
+	}
+
+	result, err := h.repo.MergeCustomers(ctx, id, req.DuplicateID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
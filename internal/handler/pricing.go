@@ -0,0 +1,214 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"github.com/student/my-kpfu-db-app/internal/domain"
+)
+
+// ============================================================================
+// Price Lists, Discounts and Quotes
+// ============================================================================
+
+// priceListRequest and discountRuleRequest carry dates as YYYY-MM-DD;
+// an empty valid_to means the rule has no end date.
+type priceListRequest struct {
+	CustomerID int             `json:"customer_id" binding:"required"`
+	PartCode   string          `json:"part_code" binding:"required"`
+	Price      decimal.Decimal `json:"price"`
+	ValidFrom  string          `json:"valid_from" binding:"required"`
+	ValidTo    string          `json:"valid_to"`
+}
+
+type discountRuleRequest struct {
+	CustomerID *int            `json:"customer_id"`
+	City       *string         `json:"city"`
+	PartCode   *string         `json:"part_code"`
+	Percent    decimal.Decimal `json:"percent"`
+	ValidFrom  string          `json:"valid_from" binding:"required"`
+	ValidTo    string          `json:"valid_to"`
+}
+
+// parsePeriod parses the validity dates of a pricing rule.
+func parsePeriod(from, to string) (time.Time, *time.Time, bool) {
+	validFrom, err := time.Parse("2006-01-02", from)
+	if err != nil {
+		return time.Time{}, nil, false
+	}
+	if to == "" {
+		return validFrom, nil, true
+	}
+	validTo, err := time.Parse("2006-01-02", to)
+	if err != nil {
+		return time.Time{}, nil, false
+	}
+	return validFrom, &validTo, true
+}
+
+func (h *Handler) GetPriceLists(c *gin.Context) {
+	customerID, _ := strconv.Atoi(c.Query("customer_id"))
+	entries, err := h.repo.GetPriceLists(c.Request.Context(), customerID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+func (h *Handler) CreatePriceListEntry(c *gin.Context) {
+	var req priceListRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	from, to, ok := parsePeriod(req.ValidFrom, req.ValidTo)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid validity dates"})
+		return
+	}
+
+	entry := domain.PriceListEntry{
+		CustomerID: req.CustomerID,
+		PartCode:   req.PartCode,
+		Price:      req.Price,
+		ValidFrom:  from,
+		ValidTo:    to,
+	}
+	if err := h.repo.CreatePriceListEntry(c.Request.Context(), &entry); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, entry)
+}
+
+func (h *Handler) DeletePriceListEntry(c *gin.Context) {
+	id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err := h.repo.DeletePriceListEntry(c.Request.Context(), id); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Price removed"})
+}
+
+func (h *Handler) GetDiscountRules(c *gin.Context) {
+	rules, err := h.repo.GetDiscountRules(c.Request.Context())
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+func (h *Handler) CreateDiscountRule(c *gin.Context) {
+	var req discountRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	from, to, ok := parsePeriod(req.ValidFrom, req.ValidTo)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid validity dates"})
+		return
+	}
+
+	rule := domain.DiscountRule{
+		CustomerID: req.CustomerID,
+		City:       req.City,
+		PartCode:   req.PartCode,
+		Percent:    req.Percent,
+		ValidFrom:  from,
+		ValidTo:    to,
+	}
+	if err := h.repo.CreateDiscountRule(c.Request.Context(), &rule); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+func (h *Handler) DeleteDiscountRule(c *gin.Context) {
+	id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err := h.repo.DeleteDiscountRule(c.Request.Context(), id); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Discount removed"})
+}
+
+func (h *Handler) GetVolumeDiscounts(c *gin.Context) {
+	tiers, err := h.repo.GetVolumeDiscounts(c.Request.Context())
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tiers)
+}
+
+func (h *Handler) CreateVolumeDiscount(c *gin.Context) {
+	var tier domain.VolumeDiscount
+	if err := c.ShouldBindJSON(&tier); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.repo.CreateVolumeDiscount(c.Request.Context(), &tier); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, tier)
+}
+
+func (h *Handler) DeleteVolumeDiscount(c *gin.Context) {
+	id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err := h.repo.DeleteVolumeDiscount(c.Request.Context(), id); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Volume discount removed"})
+}
+
+// QuotePrice resolves the price a shipment would get:
+// ?customer_id=&part_code=&qty=[&unit=][&date=YYYY-MM-DD]. The unit defaults
+// to the part's unit and the date to today.
+func (h *Handler) QuotePrice(c *gin.Context) {
+	customerID, err := strconv.Atoi(c.Query("customer_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer_id"})
+		return
+	}
+	qty, err := decimal.NewFromString(c.Query("qty"))
+	if err != nil || !qty.IsPositive() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid qty"})
+		return
+	}
+	date := time.Now()
+	if d := c.Query("date"); d != "" {
+		if date, err = time.Parse("2006-01-02", d); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date"})
+			return
+		}
+	}
+
+	quote, err := h.repo.QuotePrice(c.Request.Context(), customerID, c.Query("part_code"), c.Query("unit"), qty, date)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, quote)
+}
@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/student/my-kpfu-db-app/internal/domain"
+)
+
+// ============================================================================
+// Customer Returns
+// ============================================================================
+
+func (h *Handler) CreateReturn(c *gin.Context) {
+	var ret domain.ShipmentReturn
+	if err := c.ShouldBindJSON(&ret); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ret.WarehouseNo, _ = strconv.Atoi(c.Param("warehouse"))
+	ret.ShipmentDocNo, _ = strconv.Atoi(c.Param("doc"))
+
+	if err := h.repo.CreateReturn(c.Request.Context(), &ret); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, ret)
+}
+
+func (h *Handler) GetShipmentReturns(c *gin.Context) {
+	warehouse, _ := strconv.Atoi(c.Param("warehouse"))
+	doc, _ := strconv.Atoi(c.Param("doc"))
+
+	returns, err := h.repo.GetShipmentReturns(c.Request.Context(), warehouse, doc)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, returns)
+}
+
+func (h *Handler) GetReturns(c *gin.Context) {
+	returns, err := h.repo.GetReturns(c.Request.Context())
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, returns)
+}
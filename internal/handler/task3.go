@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"github.com/student/my-kpfu-db-app/internal/domain"
+	"github.com/student/my-kpfu-db-app/internal/quantifier"
+)
+
+// ============================================================================
+// Task 3 Quantifier Query
+// ============================================================================
+
+// task3Query reads the Task 3 query from the query string. Without
+// parameters it is the original task; otherwise parts defaults to "some",
+// shipments to "all" and omitted predicates do not restrict. Lists are given
+// as repeated or comma-separated values.
+func task3Query(c *gin.Context) (domain.Task3Query, error) {
+	if c.Request.URL.RawQuery == "" {
+		return domain.DefaultTask3Query(), nil
+	}
+
+	q := domain.Task3Query{
+		Parts:     domain.Quantifier(c.DefaultQuery("parts", string(domain.QuantifierSome))),
+		Shipments: domain.Quantifier(c.DefaultQuery("shipments", string(domain.QuantifierAll))),
+		Part:      domain.PartPredicate{PartTypes: queryList(c, "part_type")},
+	}
+
+	var err error
+	if q.Part.PriceAbove, err = queryDecimal(c, "price_above"); err != nil {
+		return q, err
+	}
+	if q.Part.PriceBelow, err = queryDecimal(c, "price_below"); err != nil {
+		return q, err
+	}
+	for _, w := range queryList(c, "warehouse") {
+		n, err := strconv.Atoi(w)
+		if err != nil {
+			return q, errors.New("invalid warehouse: " + w)
+		}
+		q.Shipment.Warehouses = append(q.Shipment.Warehouses, n)
+	}
+	if q.Shipment.DateFrom, err = queryDate(c, "date_from"); err != nil {
+		return q, err
+	}
+	if q.Shipment.DateTo, err = queryDate(c, "date_to"); err != nil {
+		return q, err
+	}
+	return q, q.Validate()
+}
+
+func queryList(c *gin.Context, name string) []string {
+	var list []string
+	for _, v := range c.QueryArray(name) {
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+	}
+	return list
+}
+
+func queryDecimal(c *gin.Context, name string) (*decimal.Decimal, error) {
+	v := c.Query(name)
+	if v == "" {
+		return nil, nil
+	}
+	d, err := decimal.NewFromString(v)
+	if err != nil {
+		return nil, errors.New("invalid " + name)
+	}
+	return &d, nil
+}
+
+func queryDate(c *gin.Context, name string) (*time.Time, error) {
+	v := c.Query(name)
+	if v == "" {
+		return nil, nil
+	}
+	t, err := time.Parse("2006-01-02", v)
+	if err != nil {
+		return nil, errors.New("invalid " + name)
+	}
+	return &t, nil
+}
+
+// Task3Query shows how the query is understood: the parsed parameters, their
+// description and the generated SQL with its arguments.
+func (h *Handler) Task3Query(c *gin.Context) {
+	q, err := task3Query(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sql, args := quantifier.SQL(q)
+	c.JSON(http.StatusOK, gin.H{
+		"query":       q,
+		"description": q.String(),
+		"sql":         sql,
+		"args":        args,
+	})
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/student/my-kpfu-db-app/internal/repository"
+)
+
+// ============================================================================
+// Trash, Restore and Purge
+// ============================================================================
+
+// requireAdmin lets only the operators listed in the configuration through.
+func (h *Handler) requireAdmin(c *gin.Context) {
+	if !slices.Contains(h.cfg.AdminUsers, repository.UserFromContext(c.Request.Context())) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "administrator access required"})
+		return
+	}
+	c.Next()
+}
+
+func (h *Handler) GetTrash(c *gin.Context) {
+	trash, err := h.repo.GetTrash(c.Request.Context())
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, trash)
+}
+
+func (h *Handler) RestoreCustomer(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	if err := h.repo.RestoreCustomer(c.Request.Context(), id); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Customer restored"})
+}
+
+func (h *Handler) RestorePart(c *gin.Context) {
+	if err := h.repo.RestorePart(c.Request.Context(), c.Param("code")); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Part restored"})
+}
+
+// PurgeCustomer reports how many shipments purging the customer removes;
+// with ?confirm=true it also purges.
+func (h *Handler) PurgeCustomer(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	ctx := c.Request.Context()
+	if h.dryRun(c, func(tx *repository.Repository) error {
+		_, err := tx.PurgeCustomer(ctx, id, true)
+		return err
+	}) {
+		return
+	}
+
+	result, err := h.repo.PurgeCustomer(c.Request.Context(), id, c.Query("confirm") == "true")
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// PurgePart works like PurgeCustomer for a part.
+func (h *Handler) PurgePart(c *gin.Context) {
+	code := c.Param("code")
+	ctx := c.Request.Context()
+	if h.dryRun(c, func(tx *repository.Repository) error {
+		_, err := tx.PurgePart(ctx, code, true)
+		return err
+	}) {
+		return
+	}
+
+	result, err := h.repo.PurgePart(c.Request.Context(), code, c.Query("confirm") == "true")
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *Handler) TrashPage(c *gin.Context) {
+	trash, err := h.repo.GetTrash(c.Request.Context())
+	if err != nil {
+		c.String(errorStatus(err), "Error fetching trash: %v", err)
+		return
+	}
+
+	c.HTML(http.StatusOK, "trash.html", gin.H{
+		"Title": "Корзина",
+		"Trash": trash,
+	})
+}
@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/student/my-kpfu-db-app/internal/domain"
+)
+
+// ============================================================================
+// Units of Measure
+// ============================================================================
+
+func (h *Handler) GetUnits(c *gin.Context) {
+	units, err := h.repo.GetUnits(c.Request.Context())
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, units)
+}
+
+func (h *Handler) GetPartUnits(c *gin.Context) {
+	units, err := h.repo.GetPartUnits(c.Request.Context(), c.Param("code"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, units)
+}
+
+func (h *Handler) AddPartUnit(c *gin.Context) {
+	var pu domain.PartUnit
+	if err := c.ShouldBindJSON(&pu); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	pu.PartCode = c.Param("code")
+
+	if err := h.repo.AddPartUnit(c.Request.Context(), &pu); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, pu)
+}
+
+func (h *Handler) DeletePartUnit(c *gin.Context) {
+	if err := h.repo.DeletePartUnit(c.Request.Context(), c.Param("code"), c.Param("unit")); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Unit removed"})
+}
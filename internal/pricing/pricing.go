@@ -0,0 +1,153 @@
+// Package pricing resolves the effective price of a part for a customer.
+//
+// The price is built in three steps:
+//  1. The base price is the customer's price-list entry valid on the date
+//     (the one that started latest wins), or the part's plan price.
+//  2. A customer discount applies to plan prices only; price-list prices are
+//     already negotiated. Rules for the customer take precedence over rules
+//     for the customer's city; within that scope a rule for the part beats a
+//     rule for all parts, and the largest percentage wins.
+//  3. A volume discount is the highest tier the quantity reaches. Tiers for
+//     the part take precedence over tiers for all parts.
+//
+// Discounts are multiplied, not added: 10% and 5% give 14.5% off. The
+// effective price is rounded to kopecks once, after both discounts.
+package pricing
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/student/my-kpfu-db-app/internal/domain"
+)
+
+// Request is everything the engine needs to price one shipment line.
+// Qty is in the part's unit. The rule slices may contain rules of other
+// customers, parts or dates; Resolve picks the applicable ones.
+type Request struct {
+	Customer  domain.Customer
+	Part      domain.Part
+	Qty       decimal.Decimal
+	Date      time.Time
+	PriceList []domain.PriceListEntry
+	Discounts []domain.DiscountRule
+	Volume    []domain.VolumeDiscount
+}
+
+var hundred = decimal.NewFromInt(100)
+
+// Resolve computes the effective price. Only the price fields and the rule
+// references of the quote are filled; quantities and units are the caller's.
+func Resolve(req Request) domain.PriceQuote {
+	// Даты правил хранятся без времени, поэтому сравниваем по календарному дню
+	y, m, d := req.Date.Date()
+	req.Date = time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+
+	q := domain.PriceQuote{
+		CustomerID:  req.Customer.CustomerID,
+		PartCode:    req.Part.PartCode,
+		BaseQty:     req.Qty,
+		Date:        req.Date,
+		Currency:    req.Part.Currency,
+		PlanPrice:   req.Part.PlanPrice,
+		PriceSource: domain.PriceSourcePlan,
+		BasePrice:   req.Part.PlanPrice,
+	}
+
+	if e := priceListEntry(req); e != nil {
+		q.PriceSource = domain.PriceSourcePriceList
+		q.PriceListID = &e.PriceListID
+		q.BasePrice = e.Price
+	} else if r := discountRule(req); r != nil {
+		q.DiscountRuleID = &r.RuleID
+		q.DiscountPercent = r.Percent
+	}
+	if t := volumeTier(req); t != nil {
+		q.VolumeTierID = &t.TierID
+		q.VolumePercent = t.Percent
+	}
+
+	q.EffectivePrice = domain.RoundMoney(q.BasePrice.
+		Mul(hundred.Sub(q.DiscountPercent)).
+		Mul(hundred.Sub(q.VolumePercent)).
+		Div(hundred.Mul(hundred)))
+	return q
+}
+
+func priceListEntry(req Request) *domain.PriceListEntry {
+	var best *domain.PriceListEntry
+	for i := range req.PriceList {
+		e := &req.PriceList[i]
+		if e.CustomerID != req.Customer.CustomerID || e.PartCode != req.Part.PartCode ||
+			!validOn(e.ValidFrom, e.ValidTo, req.Date) {
+			continue
+		}
+		if best == nil || e.ValidFrom.After(best.ValidFrom) ||
+			e.ValidFrom.Equal(best.ValidFrom) && e.PriceListID > best.PriceListID {
+			best = e
+		}
+	}
+	return best
+}
+
+// Области действия скидки от общей к частной
+const (
+	scopeNone = iota
+	scopeCity
+	scopeCustomer
+)
+
+func discountRule(req Request) *domain.DiscountRule {
+	var best *domain.DiscountRule
+	bestScope := scopeNone
+	bestForPart := false
+	for i := range req.Discounts {
+		r := &req.Discounts[i]
+		scope := scopeNone
+		switch {
+		case r.CustomerID != nil && *r.CustomerID == req.Customer.CustomerID:
+			scope = scopeCustomer
+		case r.CustomerID == nil && r.City != nil && *r.City == req.Customer.City:
+			scope = scopeCity
+		}
+		forPart, ok := matchPart(r.PartCode, req.Part.PartCode)
+		if scope == scopeNone || !ok || !validOn(r.ValidFrom, r.ValidTo, req.Date) {
+			continue
+		}
+		if best == nil || scope > bestScope ||
+			scope == bestScope && (forPart && !bestForPart ||
+				forPart == bestForPart && r.Percent.GreaterThan(best.Percent)) {
+			best, bestScope, bestForPart = r, scope, forPart
+		}
+	}
+	return best
+}
+
+func volumeTier(req Request) *domain.VolumeDiscount {
+	var best *domain.VolumeDiscount
+	bestForPart := false
+	for i := range req.Volume {
+		t := &req.Volume[i]
+		forPart, ok := matchPart(t.PartCode, req.Part.PartCode)
+		if !ok || req.Qty.LessThan(t.MinQty) {
+			continue
+		}
+		if best == nil || forPart && !bestForPart ||
+			forPart == bestForPart && t.MinQty.GreaterThan(best.MinQty) {
+			best, bestForPart = t, forPart
+		}
+	}
+	return best
+}
+
+// matchPart сообщает, относится ли правило к детали и задано ли оно именно для нее.
+func matchPart(ruleCode *string, partCode string) (forPart, ok bool) {
+	if ruleCode == nil {
+		return false, true
+	}
+	return true, *ruleCode == partCode
+}
+
+func validOn(from time.Time, to *time.Time, date time.Time) bool {
+	return !date.Before(from) && (to == nil || !date.After(*to))
+}
@@ -0,0 +1,263 @@
+package pricing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/student/my-kpfu-db-app/internal/domain"
+)
+
+func day(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func dayPtr(s string) *time.Time {
+	t := day(s)
+	return &t
+}
+
+func dec(s string) decimal.Decimal {
+	return decimal.RequireFromString(s)
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func idOf(id *int64) int64 {
+	if id == nil {
+		return 0
+	}
+	return *id
+}
+
+func TestResolve(t *testing.T) {
+	customer := domain.Customer{CustomerID: 1, City: "Казань"}
+	part := domain.Part{PartCode: "D001", PlanPrice: dec("100.00"), Currency: "RUB"}
+
+	tests := []struct {
+		name      string
+		qty       string
+		date      time.Time
+		priceList []domain.PriceListEntry
+		discounts []domain.DiscountRule
+		volume    []domain.VolumeDiscount
+
+		price    string
+		source   string
+		listID   int64
+		discount int64
+		tier     int64
+	}{
+		{
+			name:   "plan price without rules",
+			price:  "100.00",
+			source: domain.PriceSourcePlan,
+		},
+		{
+			name: "price list replaces plan price and suppresses customer discount",
+			priceList: []domain.PriceListEntry{
+				{PriceListID: 1, CustomerID: 1, PartCode: "D001", Price: dec("90.00"), ValidFrom: day("2025-01-01")},
+			},
+			discounts: []domain.DiscountRule{
+				{RuleID: 1, CustomerID: ptr(1), Percent: dec("10"), ValidFrom: day("2025-01-01")},
+			},
+			price:  "90.00",
+			source: domain.PriceSourcePriceList,
+			listID: 1,
+		},
+		{
+			name: "price list of another customer, part or period is ignored",
+			priceList: []domain.PriceListEntry{
+				{PriceListID: 1, CustomerID: 2, PartCode: "D001", Price: dec("50.00"), ValidFrom: day("2025-01-01")},
+				{PriceListID: 2, CustomerID: 1, PartCode: "D002", Price: dec("50.00"), ValidFrom: day("2025-01-01")},
+				{PriceListID: 3, CustomerID: 1, PartCode: "D001", Price: dec("50.00"), ValidFrom: day("2025-01-01"), ValidTo: dayPtr("2025-05-31")},
+				{PriceListID: 4, CustomerID: 1, PartCode: "D001", Price: dec("50.00"), ValidFrom: day("2025-07-01")},
+			},
+			price:  "100.00",
+			source: domain.PriceSourcePlan,
+		},
+		{
+			name: "price list that started latest wins",
+			priceList: []domain.PriceListEntry{
+				{PriceListID: 1, CustomerID: 1, PartCode: "D001", Price: dec("95.00"), ValidFrom: day("2025-03-01")},
+				{PriceListID: 2, CustomerID: 1, PartCode: "D001", Price: dec("80.00"), ValidFrom: day("2025-01-01")},
+			},
+			price:  "95.00",
+			source: domain.PriceSourcePriceList,
+			listID: 1,
+		},
+		{
+			name: "price list is valid through its last day whatever the time",
+			date: time.Date(2025, 6, 30, 18, 30, 0, 0, time.UTC),
+			priceList: []domain.PriceListEntry{
+				{PriceListID: 1, CustomerID: 1, PartCode: "D001", Price: dec("95.00"), ValidFrom: day("2025-01-01"), ValidTo: dayPtr("2025-06-30")},
+			},
+			price:  "95.00",
+			source: domain.PriceSourcePriceList,
+			listID: 1,
+		},
+		{
+			name: "customer rule beats a larger city rule",
+			discounts: []domain.DiscountRule{
+				{RuleID: 1, City: ptr("Казань"), Percent: dec("20"), ValidFrom: day("2025-01-01")},
+				{RuleID: 2, CustomerID: ptr(1), Percent: dec("5"), ValidFrom: day("2025-01-01")},
+			},
+			price:    "95.00",
+			source:   domain.PriceSourcePlan,
+			discount: 2,
+		},
+		{
+			name: "rule for the part beats a larger rule for all parts",
+			discounts: []domain.DiscountRule{
+				{RuleID: 1, CustomerID: ptr(1), Percent: dec("15"), ValidFrom: day("2025-01-01")},
+				{RuleID: 2, CustomerID: ptr(1), PartCode: ptr("D001"), Percent: dec("3"), ValidFrom: day("2025-01-01")},
+			},
+			price:    "97.00",
+			source:   domain.PriceSourcePlan,
+			discount: 2,
+		},
+		{
+			name: "largest percentage wins within a scope",
+			discounts: []domain.DiscountRule{
+				{RuleID: 1, City: ptr("Казань"), Percent: dec("7"), ValidFrom: day("2025-01-01")},
+				{RuleID: 2, City: ptr("Казань"), Percent: dec("12"), ValidFrom: day("2025-01-01")},
+			},
+			price:    "88.00",
+			source:   domain.PriceSourcePlan,
+			discount: 2,
+		},
+		{
+			name: "rules of another city, customer, part or period are ignored",
+			discounts: []domain.DiscountRule{
+				{RuleID: 1, City: ptr("Москва"), Percent: dec("10"), ValidFrom: day("2025-01-01")},
+				{RuleID: 2, CustomerID: ptr(2), Percent: dec("10"), ValidFrom: day("2025-01-01")},
+				{RuleID: 3, CustomerID: ptr(1), PartCode: ptr("D002"), Percent: dec("10"), ValidFrom: day("2025-01-01")},
+				{RuleID: 4, CustomerID: ptr(1), Percent: dec("10"), ValidFrom: day("2025-01-01"), ValidTo: dayPtr("2025-05-31")},
+			},
+			price:  "100.00",
+			source: domain.PriceSourcePlan,
+		},
+		{
+			name: "highest tier reached applies",
+			qty:  "150",
+			volume: []domain.VolumeDiscount{
+				{TierID: 1, MinQty: dec("10"), Percent: dec("2")},
+				{TierID: 2, MinQty: dec("100"), Percent: dec("5")},
+				{TierID: 3, MinQty: dec("500"), Percent: dec("10")},
+			},
+			price:  "95.00",
+			source: domain.PriceSourcePlan,
+			tier:   2,
+		},
+		{
+			name: "tier for the part beats a larger tier for all parts",
+			qty:  "600",
+			volume: []domain.VolumeDiscount{
+				{TierID: 1, MinQty: dec("500"), Percent: dec("10")},
+				{TierID: 2, PartCode: ptr("D001"), MinQty: dec("100"), Percent: dec("4")},
+				{TierID: 3, PartCode: ptr("D002"), MinQty: dec("100"), Percent: dec("50")},
+			},
+			price:  "96.00",
+			source: domain.PriceSourcePlan,
+			tier:   2,
+		},
+		{
+			name: "quantity below every tier gets no volume discount",
+			qty:  "9.999",
+			volume: []domain.VolumeDiscount{
+				{TierID: 1, MinQty: dec("10"), Percent: dec("2")},
+			},
+			price:  "100.00",
+			source: domain.PriceSourcePlan,
+		},
+		{
+			name: "customer and volume discounts multiply",
+			qty:  "100",
+			discounts: []domain.DiscountRule{
+				{RuleID: 1, CustomerID: ptr(1), Percent: dec("10"), ValidFrom: day("2025-01-01")},
+			},
+			volume: []domain.VolumeDiscount{
+				{TierID: 1, MinQty: dec("100"), Percent: dec("5")},
+			},
+			price:    "85.50",
+			source:   domain.PriceSourcePlan,
+			discount: 1,
+			tier:     1,
+		},
+		{
+			name: "volume discount applies to a price-list price",
+			qty:  "100",
+			priceList: []domain.PriceListEntry{
+				{PriceListID: 1, CustomerID: 1, PartCode: "D001", Price: dec("200.00"), ValidFrom: day("2025-01-01")},
+			},
+			volume: []domain.VolumeDiscount{
+				{TierID: 1, MinQty: dec("100"), Percent: dec("10")},
+			},
+			price:  "180.00",
+			source: domain.PriceSourcePriceList,
+			listID: 1,
+			tier:   1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := Request{
+				Customer:  customer,
+				Part:      part,
+				Qty:       decimal.NewFromInt(1),
+				Date:      day("2025-06-15"),
+				PriceList: tt.priceList,
+				Discounts: tt.discounts,
+				Volume:    tt.volume,
+			}
+			if tt.qty != "" {
+				req.Qty = dec(tt.qty)
+			}
+			if !tt.date.IsZero() {
+				req.Date = tt.date
+			}
+
+			q := Resolve(req)
+			if !q.EffectivePrice.Equal(dec(tt.price)) {
+				t.Errorf("effective price = %s, want %s", q.EffectivePrice, tt.price)
+			}
+			if q.PriceSource != tt.source {
+				t.Errorf("price source = %s, want %s", q.PriceSource, tt.source)
+			}
+			if got := idOf(q.PriceListID); got != tt.listID {
+				t.Errorf("price list = %d, want %d", got, tt.listID)
+			}
+			if got := idOf(q.DiscountRuleID); got != tt.discount {
+				t.Errorf("discount rule = %d, want %d", got, tt.discount)
+			}
+			if got := idOf(q.VolumeTierID); got != tt.tier {
+				t.Errorf("volume tier = %d, want %d", got, tt.tier)
+			}
+		})
+	}
+}
+
+// Цена округляется до копеек один раз, после обеих скидок: 1.15 * 0.9 * 0.95 =
+// 0.98325 -> 0.98, а округление после каждой скидки дало бы 1.04 * 0.95 -> 0.99
+func TestResolveRoundsOnce(t *testing.T) {
+	q := Resolve(Request{
+		Customer: domain.Customer{CustomerID: 1},
+		Part:     domain.Part{PartCode: "D001", PlanPrice: dec("1.15")},
+		Qty:      dec("100"),
+		Date:     day("2025-06-15"),
+		Discounts: []domain.DiscountRule{
+			{RuleID: 1, CustomerID: ptr(1), Percent: dec("10"), ValidFrom: day("2025-01-01")},
+		},
+		Volume: []domain.VolumeDiscount{{TierID: 1, MinQty: dec("1"), Percent: dec("5")}},
+	})
+	if !q.EffectivePrice.Equal(dec("0.98")) {
+		t.Errorf("effective price = %s, want 0.98", q.EffectivePrice)
+	}
+}
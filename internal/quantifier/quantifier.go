@@ -0,0 +1,265 @@
+// Package quantifier evaluates the generalized Task 3 query: customers for
+// whom, for some or all of the parts matching a predicate P that were shipped
+// to them, all, none or some of their shipments of the part satisfy a
+// predicate Q (see domain.Task3Query).
+//
+// The query is answered two ways that must agree: SQL builds a quantified
+// EXISTS / NOT EXISTS statement, and Evaluator folds shipment records one at
+// a time into per-(customer, part) state.
+// Predicates come from a fixed whitelist and their values are always passed
+// as parameters, so no user input reaches the SQL text.
+package quantifier
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/student/my-kpfu-db-app/internal/domain"
+)
+
+// SQL builds the statement and its arguments. It returns customer_id, name
+// and city ordered by customer_id. The query must be valid.
+func SQL(q domain.Task3Query) (string, []any) {
+	var args []any
+	param := func(v any, cast string) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d::%s", len(args), cast)
+	}
+
+	// P(p)
+	var partConds []string
+	if q.Part.PriceAbove != nil {
+		partConds = append(partConds, "p.plan_price > "+param(q.Part.PriceAbove.String(), "numeric"))
+	}
+	if q.Part.PriceBelow != nil {
+		partConds = append(partConds, "p.plan_price < "+param(q.Part.PriceBelow.String(), "numeric"))
+	}
+	if len(q.Part.PartTypes) > 0 {
+		partConds = append(partConds, "p.part_type = ANY("+param(q.Part.PartTypes, "text[]")+")")
+	}
+
+	// Q(s2); все столбцы NOT NULL, поэтому NOT (Q) не теряет строк
+	var shipmentConds []string
+	if len(q.Shipment.Warehouses) > 0 {
+		shipmentConds = append(shipmentConds, "s2.warehouse_no = ANY("+param(q.Shipment.Warehouses, "int[]")+")")
+	}
+	if q.Shipment.DateFrom != nil {
+		shipmentConds = append(shipmentConds, "s2.shipment_date >= "+param(*q.Shipment.DateFrom, "date"))
+	}
+	if q.Shipment.DateTo != nil {
+		shipmentConds = append(shipmentConds, "s2.shipment_date <= "+param(*q.Shipment.DateTo, "date"))
+	}
+	satisfies := conjunction(shipmentConds)
+
+	// Детали, удовлетворяющие P и отгружавшиеся покупателю
+	parts := `SELECT 1
+				FROM parts p
+				WHERE ` + conjunction(append(partConds, `EXISTS (
+					SELECT 1
+					FROM shipments s1
+					WHERE s1.customer_id = c.customer_id
+					AND s1.part_code = p.part_code
+				)`))
+
+	// Квантор по отгрузкам этой детали этому покупателю
+	shipments := func(cond string) string {
+		return `(
+					SELECT 1
+					FROM shipments s2
+					WHERE s2.customer_id = c.customer_id
+					AND s2.part_code = p.part_code
+					AND ` + cond + `
+				)`
+	}
+	var inner string
+	switch q.Shipments {
+	case domain.QuantifierAll:
+		inner = "NOT EXISTS " + shipments("NOT ("+satisfies+")")
+	case domain.QuantifierNone:
+		inner = "NOT EXISTS " + shipments(satisfies)
+	default:
+		inner = "EXISTS " + shipments(satisfies)
+	}
+
+	var where string
+	if q.Parts == domain.QuantifierAll {
+		// Есть хотя бы одна такая деталь и нет детали, для которой условие нарушено
+		where = `EXISTS (
+				` + parts + `
+			)
+			AND NOT EXISTS (
+				` + parts + `
+				AND NOT (` + inner + `)
+			)`
+	} else {
+		where = `EXISTS (
+				` + parts + `
+				AND ` + inner + `
+			)`
+	}
+
+	query := `
+		SELECT c.customer_id, c.name, c.city
+		FROM customers c
+		WHERE ` + where + `
+		ORDER BY c.customer_id
+	`
+	return query, args
+}
+
+func conjunction(conds []string) string {
+	if len(conds) == 0 {
+		return "TRUE"
+	}
+	return strings.Join(conds, "\n\t\t\t\tAND ")
+}
+
+// Evaluate answers the query over in-memory collections, keeping the order
+// of customers. Shipments of unknown parts are ignored, as the join in SQL
+// would ignore them.
+func Evaluate(q domain.Task3Query, customers []domain.Customer, parts []domain.Part, shipments []domain.Shipment) []domain.Task3Result {
+	e := NewEvaluator(q, parts)
+	for _, s := range shipments {
+		e.Add(s)
+	}
+	return e.Results(customers)
+}
+
+// Evaluator answers the query in one pass over the shipments, which can be
+// streamed: only the state of the shipment quantifier is kept for each
+// (customer, part) pair, so memory is proportional to the pairs, not to the
+// shipments, and every shipment is looked at in constant time.
+type Evaluator struct {
+	q        domain.Task3Query
+	matching map[string]bool
+	state    map[pairKey]bool
+}
+
+type pairKey struct {
+	customerID int
+	partCode   string
+}
+
+// NewEvaluator prepares the evaluation of q; parts are checked against P.
+func NewEvaluator(q domain.Task3Query, parts []domain.Part) *Evaluator {
+	e := &Evaluator{q: q, matching: make(map[string]bool), state: make(map[pairKey]bool)}
+	for _, p := range parts {
+		if matchesPart(q.Part, p) {
+			e.matching[p.PartCode] = true
+		}
+	}
+	return e
+}
+
+// PartCodes returns the codes of the parts satisfying P, sorted; shipments
+// of other parts do not affect the result and need not be added.
+func (e *Evaluator) PartCodes() []string {
+	codes := make([]string, 0, len(e.matching))
+	for code := range e.matching {
+		codes = append(codes, code)
+	}
+	slices.Sort(codes)
+	return codes
+}
+
+// Add folds a shipment into the state of its (customer, part) pair.
+func (e *Evaluator) Add(s domain.Shipment) {
+	if !e.matching[s.PartCode] {
+		return
+	}
+	k := pairKey{s.CustomerID, s.PartCode}
+	holds, seen := e.state[k]
+	if !seen {
+		// Пока отгрузок нет, «все» и «ни одна» выполняются, «некоторые» - нет
+		holds = e.q.Shipments != domain.QuantifierSome
+	}
+	// Решенное состояние пары уже не меняется
+	switch {
+	case e.q.Shipments == domain.QuantifierAll && holds:
+		holds = matchesShipment(e.q.Shipment, s)
+	case e.q.Shipments == domain.QuantifierNone && holds:
+		holds = !matchesShipment(e.q.Shipment, s)
+	case e.q.Shipments == domain.QuantifierSome && !holds:
+		holds = matchesShipment(e.q.Shipment, s)
+	}
+	e.state[k] = holds
+}
+
+// CustomerIDs returns the customers satisfying the query, sorted. A customer
+// has a state only for parts satisfying P that were shipped to them, so
+// "all" never holds vacuously.
+func (e *Evaluator) CustomerIDs() []int {
+	byCustomer := make(map[int]bool)
+	for k, holds := range e.state {
+		ok, seen := byCustomer[k.customerID]
+		switch {
+		case !seen:
+			byCustomer[k.customerID] = holds
+		case e.q.Parts == domain.QuantifierAll:
+			byCustomer[k.customerID] = ok && holds
+		default:
+			byCustomer[k.customerID] = ok || holds
+		}
+	}
+
+	var ids []int
+	for id, ok := range byCustomer {
+		if ok {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// Results returns the customers satisfying the query in the order given.
+func (e *Evaluator) Results(customers []domain.Customer) []domain.Task3Result {
+	ids := e.CustomerIDs()
+	var results []domain.Task3Result
+	for _, c := range customers {
+		if _, found := slices.BinarySearch(ids, c.CustomerID); found {
+			results = append(results, domain.Task3Result{
+				CustomerID:   c.CustomerID,
+				CustomerName: c.Name,
+				CustomerCity: c.City,
+			})
+		}
+	}
+	return results
+}
+
+func matchesPart(pred domain.PartPredicate, p domain.Part) bool {
+	if pred.PriceAbove != nil && !p.PlanPrice.GreaterThan(*pred.PriceAbove) {
+		return false
+	}
+	if pred.PriceBelow != nil && !p.PlanPrice.LessThan(*pred.PriceBelow) {
+		return false
+	}
+	if len(pred.PartTypes) > 0 && !slices.Contains(pred.PartTypes, p.PartType) {
+		return false
+	}
+	return true
+}
+
+func matchesShipment(pred domain.ShipmentPredicate, s domain.Shipment) bool {
+	if len(pred.Warehouses) > 0 && !slices.Contains(pred.Warehouses, s.WarehouseNo) {
+		return false
+	}
+	// Даты хранятся без времени, поэтому сравниваем по календарному дню
+	date := day(s.ShipmentDate)
+	if pred.DateFrom != nil && date < day(*pred.DateFrom) {
+		return false
+	}
+	if pred.DateTo != nil && date > day(*pred.DateTo) {
+		return false
+	}
+	return true
+}
+
+// day returns the calendar day of t as a comparable number.
+func day(t time.Time) int {
+	y, m, d := t.Date()
+	return y*10000 + int(m)*100 + d
+}
@@ -0,0 +1,158 @@
+package quantifier
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/student/my-kpfu-db-app/internal/compare"
+	"github.com/student/my-kpfu-db-app/internal/domain"
+)
+
+// nestedLoop answers the query straight from its definition: for every
+// customer and every part satisfying P, all shipments are scanned. It takes
+// O(customers * parts * shipments) and is the reference for Evaluator.
+func nestedLoop(q domain.Task3Query, customers []domain.Customer, parts []domain.Part, shipments []domain.Shipment) []domain.Task3Result {
+	var results []domain.Task3Result
+	for _, c := range customers {
+		shipped, held := 0, 0
+		for _, p := range parts {
+			if !matchesPart(q.Part, p) {
+				continue
+			}
+			n, matched := 0, 0
+			for _, s := range shipments {
+				if s.CustomerID != c.CustomerID || s.PartCode != p.PartCode {
+					continue
+				}
+				n++
+				if matchesShipment(q.Shipment, s) {
+					matched++
+				}
+			}
+			if n == 0 {
+				continue
+			}
+			shipped++
+
+			var holds bool
+			switch q.Shipments {
+			case domain.QuantifierAll:
+				holds = matched == n
+			case domain.QuantifierNone:
+				holds = matched == 0
+			case domain.QuantifierSome:
+				holds = matched > 0
+			}
+			if holds {
+				held++
+			}
+		}
+
+		ok := held > 0
+		if q.Parts == domain.QuantifierAll {
+			ok = shipped > 0 && held == shipped
+		}
+		if ok {
+			results = append(results, domain.Task3Result{CustomerID: c.CustomerID, CustomerName: c.Name, CustomerCity: c.City})
+		}
+	}
+	return results
+}
+
+// dataset generates few parts and many shipments per part, so that most
+// (customer, part) pairs have several shipments, plus a shipment of an
+// unknown part that must be ignored.
+func dataset(seed int64) compare.Dataset {
+	ds := compare.Generate(seed, domain.Volumes{Customers: 12, Parts: 5, Shipments: 150})
+	ds.Shipments = append(ds.Shipments, domain.Shipment{CustomerID: 0, PartCode: "UNKNOWN", WarehouseNo: 1})
+	return ds
+}
+
+func TestEvaluatorMatchesNestedLoop(t *testing.T) {
+	for seed := int64(1); seed <= 50; seed++ {
+		ds := dataset(seed)
+		rng := rand.New(rand.NewSource(seed))
+		queries := []domain.Task3Query{domain.DefaultTask3Query(), {}}
+		for i := 0; i < 5; i++ {
+			queries = append(queries, compare.RandomTask3Query(rng))
+		}
+
+		for _, base := range queries {
+			for _, parts := range domain.PartQuantifiers {
+				for _, shipments := range domain.ShipmentQuantifiers {
+					q := base
+					q.Parts, q.Shipments = parts, shipments
+
+					want := nestedLoop(q, ds.Customers, ds.Parts, ds.Shipments)
+					got := Evaluate(q, ds.Customers, ds.Parts, ds.Shipments)
+					if !reflect.DeepEqual(got, want) {
+						t.Fatalf("seed %d, %s: got %v, want %v", seed, q, got, want)
+					}
+
+					// Порядок отгрузок в потоке не влияет на результат
+					shuffled := append([]domain.Shipment(nil), ds.Shipments...)
+					rng.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+					if got := Evaluate(q, ds.Customers, ds.Parts, shuffled); !reflect.DeepEqual(got, want) {
+						t.Fatalf("seed %d, %s, shuffled: got %v, want %v", seed, q, got, want)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestEvaluatorAllIsNotVacuous(t *testing.T) {
+	ds := dataset(1)
+	// Ни одна деталь не удовлетворяет P: «все» не выполняется ни для кого
+	q := domain.Task3Query{Parts: domain.QuantifierAll, Shipments: domain.QuantifierAll}
+	q.Part.PartTypes = []string{"нет такого типа"}
+
+	if got := Evaluate(q, ds.Customers, ds.Parts, ds.Shipments); len(got) != 0 {
+		t.Fatalf("got %v, want no customers", got)
+	}
+}
+
+func TestPartCodes(t *testing.T) {
+	ds := dataset(1)
+	q := domain.DefaultTask3Query()
+	e := NewEvaluator(q, ds.Parts)
+
+	var want []string
+	for _, p := range ds.Parts {
+		if matchesPart(q.Part, p) {
+			want = append(want, p.PartCode)
+		}
+	}
+	if got := e.PartCodes(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("PartCodes() = %v, want %v", got, want)
+	}
+}
+
+// Evaluator растет линейно с числом отгрузок, вложенные циклы - как
+// произведение покупателей, деталей и отгрузок.
+func BenchmarkEvaluator(b *testing.B) {
+	for _, size := range []int{10, 100, 1000, 10000} {
+		ds := compare.Random(1, size)
+		q := domain.DefaultTask3Query()
+		b.Run(fmt.Sprintf("size=%d", size), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				Evaluate(q, ds.Customers, ds.Parts, ds.Shipments)
+			}
+		})
+	}
+}
+
+func BenchmarkNestedLoop(b *testing.B) {
+	for _, size := range []int{10, 100, 300} {
+		ds := compare.Random(1, size)
+		q := domain.DefaultTask3Query()
+		b.Run(fmt.Sprintf("size=%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				nestedLoop(q, ds.Customers, ds.Parts, ds.Shipments)
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+	"github.com/student/my-kpfu-db-app/internal/abcxyz"
+	"github.com/student/my-kpfu-db-app/internal/domain"
+)
+
+// ============================================================================
+// ABC/XYZ-классификация деталей и покупателей
+// ============================================================================
+
+// abcxyzDimensions сопоставляет предмет классификации с измерением продаж
+var abcxyzDimensions = map[domain.ABCXYZSubject]domain.SalesDimension{
+	domain.SubjectParts:     domain.DimensionPart,
+	domain.SubjectCustomers: domain.DimensionCustomer,
+}
+
+// ClassifyABCXYZ классифицирует детали или покупателей по продажам за месяцы
+// [from, to); границы расширяются до целых месяцев. Учитываются только те,
+// у кого были продажи в периоде. Спрос деталей - количество, покупателей -
+// сумма: покупатель берет детали в разных единицах.
+func (r *Repository) ClassifyABCXYZ(ctx context.Context, subject domain.ABCXYZSubject, from, to time.Time,
+	t domain.ABCXYZThresholds) (*domain.ABCXYZSnapshot, error) {
+	if !to.After(from) {
+		return nil, fmt.Errorf("%w: %s - %s", ErrInvalidPeriod, from.Format("2006-01-02"), to.Format("2006-01-02"))
+	}
+	// Последний день полуинтервала - в последнем месяце периода
+	from, to = domain.MonthPeriod(from, to.AddDate(0, 0, -1))
+
+	s := &domain.ABCXYZSnapshot{
+		Subject:    subject,
+		From:       from,
+		To:         to,
+		Months:     (to.Year()-from.Year())*12 + int(to.Month()-from.Month()),
+		Thresholds: t,
+		CreatedAt:  time.Now(),
+		CreatedBy:  UserFromContext(ctx),
+	}
+
+	col := salesDimensionColumns[abcxyzDimensions[subject]]
+	demand := "v.base_qty"
+	if subject.ByValue() {
+		demand = "v.total_price_rub"
+	}
+	query := fmt.Sprintf(`
+		SELECT 
+			%[1]s AS key,
+			MIN(%[2]s) AS name,
+			date_trunc('month', v.shipment_date::timestamp)::date AS month,
+			SUM(%[4]s) AS demand,
+			SUM(v.total_price_rub) AS value
+		FROM v_full_shipment_info v
+		WHERE v.shipment_date >= $1::date AND v.shipment_date < $2::date AND %[3]s
+		GROUP BY 1, 3
+		ORDER BY 1, 3
+	`, col.key, col.name, salesFilter, demand)
+	rows, err := r.db.Query(ctx, query, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Помесячный спрос по ключу; месяцы без продаж остаются нулями
+	var items []abcxyz.Item
+	for rows.Next() {
+		var key, name string
+		var month time.Time
+		var demand, value decimal.Decimal
+		if err := rows.Scan(&key, &name, &month, &demand, &value); err != nil {
+			return nil, err
+		}
+		if len(items) == 0 || items[len(items)-1].Key != key {
+			items = append(items, abcxyz.Item{Key: key, Name: name, Monthly: make([]decimal.Decimal, s.Months)})
+		}
+		it := &items[len(items)-1]
+		it.Value = it.Value.Add(value)
+		it.Monthly[(month.Year()-from.Year())*12+int(month.Month()-from.Month())] = demand
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	s.Items = abcxyz.Classify(items, t)
+	for _, it := range s.Items {
+		s.Total = s.Total.Add(it.Value)
+	}
+	s.Matrix = abcxyz.Matrix(s.Items, s.Total)
+	return s, nil
+}
+
+// SaveABCXYZSnapshot сохраняет снимок классификации. Для предмета и периода
+// хранится один снимок: повторное сохранение заменяет прежний.
+func (r *Repository) SaveABCXYZSnapshot(ctx context.Context, s *domain.ABCXYZSnapshot) error {
+	return r.inTx(ctx, func(tx *Repository) error {
+		err := tx.db.QueryRow(ctx, `
+			INSERT INTO abc_xyz_snapshots (subject, period_from, period_to, months, a_threshold, b_threshold,
+			                               x_threshold, y_threshold, total_value, created_at, created_by)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+			ON CONFLICT (subject, period_from, period_to) DO UPDATE SET
+				months = EXCLUDED.months,
+				a_threshold = EXCLUDED.a_threshold,
+				b_threshold = EXCLUDED.b_threshold,
+				x_threshold = EXCLUDED.x_threshold,
+				y_threshold = EXCLUDED.y_threshold,
+				total_value = EXCLUDED.total_value,
+				created_at = EXCLUDED.created_at,
+				created_by = EXCLUDED.created_by
+			RETURNING snapshot_id`,
+			s.Subject, s.From, s.To, s.Months, s.Thresholds.A, s.Thresholds.B, s.Thresholds.X, s.Thresholds.Y,
+			s.Total, s.CreatedAt, s.CreatedBy).Scan(&s.SnapshotID)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.db.Exec(ctx, "DELETE FROM abc_xyz_items WHERE snapshot_id = $1", s.SnapshotID); err != nil {
+			return err
+		}
+
+		n := len(s.Items)
+		keys, names, abc, xyz := make([]string, n), make([]string, n), make([]string, n), make([]string, n)
+		values, shares, cumulative := make([]string, n), make([]string, n), make([]string, n)
+		qtys, means, cvs := make([]string, n), make([]string, n), make([]*string, n)
+		for i, it := range s.Items {
+			keys[i], names[i], abc[i], xyz[i] = it.Key, it.Name, string(it.ABC), string(it.XYZ)
+			values[i], shares[i], cumulative[i] = it.Value.String(), it.Share.String(), it.CumulativeShare.String()
+			qtys[i], means[i] = it.Qty.String(), it.MeanQty.String()
+			if it.CV != nil {
+				cv := it.CV.String()
+				cvs[i] = &cv
+			}
+		}
+		_, err = tx.db.Exec(ctx, `
+			INSERT INTO abc_xyz_items (snapshot_id, item_key, name, value, share, cumulative_share, qty, mean_qty, cv,
+			                           abc_class, xyz_class)
+			SELECT $1, * FROM unnest($2::text[], $3::text[], $4::numeric[], $5::numeric[], $6::numeric[],
+			                         $7::numeric[], $8::numeric[], $9::numeric[], $10::text[], $11::text[])`,
+			s.SnapshotID, keys, names, values, shares, cumulative, qtys, means, cvs, abc, xyz)
+		return err
+	})
+}
+
+// abcxyzSnapshotColumns - столбцы заголовка снимка в порядке scanABCXYZSnapshot
+const abcxyzSnapshotColumns = `snapshot_id, subject, period_from, period_to, months, a_threshold, b_threshold,
+	       x_threshold, y_threshold, total_value, created_at, created_by`
+
+func scanABCXYZSnapshot(row pgx.Row, s *domain.ABCXYZSnapshot) error {
+	return row.Scan(&s.SnapshotID, &s.Subject, &s.From, &s.To, &s.Months, &s.Thresholds.A, &s.Thresholds.B,
+		&s.Thresholds.X, &s.Thresholds.Y, &s.Total, &s.CreatedAt, &s.CreatedBy)
+}
+
+// GetABCXYZSnapshots возвращает последние limit снимков (без позиций), новые
+// первыми; пустой subject - снимки по всем предметам.
+func (r *Repository) GetABCXYZSnapshots(ctx context.Context, subject domain.ABCXYZSubject, limit int) ([]domain.ABCXYZSnapshot, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+abcxyzSnapshotColumns+`
+		FROM abc_xyz_snapshots
+		WHERE $1 = '' OR subject = $1
+		ORDER BY period_to DESC, period_from DESC, subject
+		LIMIT $2`, string(subject), limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.ABCXYZSnapshot, error) {
+		var s domain.ABCXYZSnapshot
+		err := scanABCXYZSnapshot(row, &s)
+		return s, err
+	})
+}
+
+// GetABCXYZSnapshot возвращает сохраненный снимок с позициями и матрицей.
+func (r *Repository) GetABCXYZSnapshot(ctx context.Context, snapshotID int64) (*domain.ABCXYZSnapshot, error) {
+	var s domain.ABCXYZSnapshot
+	row := r.db.QueryRow(ctx, "SELECT "+abcxyzSnapshotColumns+" FROM abc_xyz_snapshots WHERE snapshot_id = $1", snapshotID)
+	err := scanABCXYZSnapshot(row, &s)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("ABC/XYZ snapshot %d: %w", snapshotID, ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, `
+		SELECT item_key, name, value, share, cumulative_share, qty, mean_qty, cv, abc_class, xyz_class
+		FROM abc_xyz_items
+		WHERE snapshot_id = $1
+		ORDER BY value DESC, item_key`, snapshotID)
+	if err != nil {
+		return nil, err
+	}
+	s.Items, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.ABCXYZItem, error) {
+		var it domain.ABCXYZItem
+		err := row.Scan(&it.Key, &it.Name, &it.Value, &it.Share, &it.CumulativeShare, &it.Qty, &it.MeanQty,
+			&it.CV, &it.ABC, &it.XYZ)
+		return it, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.Matrix = abcxyz.Matrix(s.Items, s.Total)
+	return &s, nil
+}
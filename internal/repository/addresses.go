@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/student/my-kpfu-db-app/internal/domain"
+)
+
+// ============================================================================
+// Города, адреса и контакты покупателей
+// ============================================================================
+
+var (
+	postalCodePattern = regexp.MustCompile(`^[0-9]{6}$`)
+	emailPattern      = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+)
+
+func (r *Repository) GetCities(ctx context.Context) ([]domain.City, error) {
+	rows, err := r.db.Query(ctx, "SELECT name, region FROM cities ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.City, error) {
+		var c domain.City
+		err := row.Scan(&c.Name, &c.Region)
+		return c, err
+	})
+}
+
+// CreateCity добавляет город в справочник. Город, совпадающий с уже известным
+// после нормализации, не дублируется: возвращается существующая запись.
+func (r *Repository) CreateCity(ctx context.Context, c *domain.City) error {
+	c.Name = strings.Join(strings.Fields(c.Name), " ")
+	if c.Name == "" {
+		return fmt.Errorf("%w: city name is required", ErrInvalidAddress)
+	}
+	return r.db.QueryRow(ctx, `
+		INSERT INTO cities (name, region) VALUES ($1, $2)
+		ON CONFLICT (name_key) DO UPDATE SET region = COALESCE(NULLIF(EXCLUDED.region, ''), cities.region)
+		RETURNING name, region`, c.Name, c.Region).Scan(&c.Name, &c.Region)
+}
+
+// resolveCity находит город справочника по введенному названию.
+func (r *Repository) resolveCity(ctx context.Context, input string) (domain.City, error) {
+	var c domain.City
+	err := r.db.QueryRow(ctx, "SELECT name, region FROM cities WHERE name_key = fn_city_key($1)",
+		input).Scan(&c.Name, &c.Region)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c, fmt.Errorf("%w: %q", ErrUnknownCity, input)
+	}
+	return c, err
+}
+
+// prepareCustomerAddress приводит адрес покупателя к структурированному виду:
+// город - к названию из справочника, адрес одной строкой - к улице и дому.
+func (r *Repository) prepareCustomerAddress(ctx context.Context, c *domain.Customer) error {
+	city, err := r.resolveCity(ctx, c.City)
+	if err != nil {
+		return err
+	}
+	c.City, c.Region = city.Name, city.Region
+
+	c.PostalCode = strings.TrimSpace(c.PostalCode)
+	if c.PostalCode != "" && !postalCodePattern.MatchString(c.PostalCode) {
+		return fmt.Errorf("%w: postal code must be 6 digits", ErrInvalidAddress)
+	}
+	if c.Street == "" && c.Building == "" {
+		c.Street, c.Building = domain.SplitStreetAddress(c.Address)
+	}
+	c.Street, c.Building = strings.TrimSpace(c.Street), strings.TrimSpace(c.Building)
+	c.Address = domain.StreetAddress(c.Street, c.Building)
+	return nil
+}
+
+// GetContacts возвращает контакты покупателя, основной - первым.
+func (r *Repository) GetContacts(ctx context.Context, customerID int) ([]domain.Contact, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT contact_id, customer_id, name, role, COALESCE(phone, ''), COALESCE(email, ''), is_primary
+		FROM customer_contacts
+		WHERE customer_id = $1
+		ORDER BY is_primary DESC, contact_id`, customerID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.Contact, error) {
+		var c domain.Contact
+		err := row.Scan(&c.ContactID, &c.CustomerID, &c.Name, &c.Role, &c.Phone, &c.Email, &c.IsPrimary)
+		return c, err
+	})
+}
+
+// CreateContact добавляет контакт. Новый основной контакт заменяет прежний.
+func (r *Repository) CreateContact(ctx context.Context, c *domain.Contact) error {
+	c.Name = strings.TrimSpace(c.Name)
+	c.Phone = domain.NormalizePhone(c.Phone)
+	c.Email = strings.ToLower(strings.TrimSpace(c.Email))
+	switch {
+	case c.Name == "":
+		return fmt.Errorf("%w: name is required", ErrInvalidContact)
+	case c.Phone == "" && c.Email == "":
+		return fmt.Errorf("%w: phone or email is required", ErrInvalidContact)
+	case c.Phone != "" && (len(c.Phone) < 11 || len(c.Phone) > 16):
+		return fmt.Errorf("%w: phone must have 10 to 15 digits", ErrInvalidContact)
+	case c.Email != "" && !emailPattern.MatchString(c.Email):
+		return fmt.Errorf("%w: invalid email", ErrInvalidContact)
+	}
+
+	return r.inTx(ctx, func(tx *Repository) error {
+		var exists bool
+		if err := tx.db.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM customers WHERE customer_id = $1)",
+			c.CustomerID).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("customer %d: %w", c.CustomerID, ErrNotFound)
+		}
+		if c.IsPrimary {
+			if _, err := tx.db.Exec(ctx, "UPDATE customer_contacts SET is_primary = false WHERE customer_id = $1 AND is_primary",
+				c.CustomerID); err != nil {
+				return err
+			}
+		}
+
+		query := `INSERT INTO customer_contacts (customer_id, name, role, phone, email, is_primary)
+		          VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), $6) RETURNING contact_id`
+		return tx.db.QueryRow(ctx, query, c.CustomerID, c.Name, c.Role, c.Phone, c.Email, c.IsPrimary).Scan(&c.ContactID)
+	})
+}
+
+func (r *Repository) DeleteContact(ctx context.Context, customerID int, contactID int64) error {
+	tag, err := r.db.Exec(ctx, "DELETE FROM customer_contacts WHERE customer_id = $1 AND contact_id = $2",
+		customerID, contactID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("contact %d: %w", contactID, ErrNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/student/my-kpfu-db-app/internal/bench"
+	"github.com/student/my-kpfu-db-app/internal/compare"
+	"github.com/student/my-kpfu-db-app/internal/domain"
+	"github.com/student/my-kpfu-db-app/internal/quantifier"
+)
+
+// ============================================================================
+// Нагрузочное сравнение стратегий выполнения запросов
+// ============================================================================
+
+// benchStrategies - сравниваемые стратегии; каждая возвращает число строк
+// результата. Строки, прочитанные через pgx, считает countingDB, а прочитанные
+// GORM стратегия добавляет к rowsRead сама. Задача 1 выполняется по первому
+// городу набора, Задача 3 - с исходным условием.
+var benchStrategies = []struct {
+	name string
+	run  func(ctx context.Context, r *Repository, city string, rowsRead *int64) (int, error)
+}{
+	{"task-1 sql", func(ctx context.Context, r *Repository, city string, _ *int64) (int, error) {
+		rows, err := r.GetTask1SQL(ctx, city)
+		return len(rows), err
+	}},
+	{"task-1 orm", func(ctx context.Context, r *Repository, city string, rowsRead *int64) (int, error) {
+		rows, n, err := r.task1ORM(ctx, city)
+		*rowsRead += n
+		return len(rows), err
+	}},
+	{"task-3 sql", func(ctx context.Context, r *Repository, _ string, _ *int64) (int, error) {
+		rows, err := r.GetTask3SQL(ctx, domain.DefaultTask3Query())
+		return len(rows), err
+	}},
+	{"task-3 record", func(ctx context.Context, r *Repository, _ string, _ *int64) (int, error) {
+		rows, err := r.GetTask3RecordBased(ctx, domain.DefaultTask3Query())
+		return len(rows), err
+	}},
+	{"task-3 full load", func(ctx context.Context, r *Repository, _ string, _ *int64) (int, error) {
+		rows, err := r.task3FullLoad(ctx, domain.DefaultTask3Query())
+		return len(rows), err
+	}},
+}
+
+// task3FullLoad - прежний record-ориентированный вариант Задачи 3: загружает
+// всех покупателей, все детали и все отгрузки и обходит их в памяти. Оставлен
+// как база сравнения для потокового GetTask3RecordBased.
+func (r *Repository) task3FullLoad(ctx context.Context, q domain.Task3Query) ([]domain.Task3Result, error) {
+	customers, err := r.queryCustomers(ctx, "TRUE")
+	if err != nil {
+		return nil, err
+	}
+	parts, err := r.queryParts(ctx, "TRUE")
+	if err != nil {
+		return nil, err
+	}
+	shipments, err := r.GetShipments(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	return quantifier.Evaluate(q, customers, parts, shipments), nil
+}
+
+// countingDB считает строки, прочитанные через pgx.
+type countingDB struct {
+	dbtx
+	rows *int64
+}
+
+func (c *countingDB) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	rows, err := c.dbtx.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	return &countingRows{Rows: rows, n: c.rows}, nil
+}
+
+func (c *countingDB) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	*c.rows++
+	return c.dbtx.QueryRow(ctx, sql, args...)
+}
+
+type countingRows struct {
+	pgx.Rows
+	n *int64
+}
+
+func (r *countingRows) Next() bool {
+	if r.Rows.Next() {
+		*r.n++
+		return true
+	}
+	return false
+}
+
+// Benchmark загружает в Sandbox набор данных заданного объема и выполняет
+// каждую стратегию iterations раз после одного прогревочного запуска.
+func (r *Repository) Benchmark(ctx context.Context, seed int64, v domain.Volumes, iterations int) (*domain.BenchRun, error) {
+	run := &domain.BenchRun{
+		RunAt:      time.Now(),
+		RunBy:      UserFromContext(ctx),
+		Seed:       seed,
+		Volumes:    v,
+		Iterations: iterations,
+	}
+	ds := compare.Generate(seed, v)
+
+	err := r.Sandbox(ctx, func(tx *Repository) error {
+		if err := tx.loadDataset(ctx, ds); err != nil {
+			return err
+		}
+		if _, err := tx.db.Exec(ctx, "ANALYZE customers, parts, shipments"); err != nil {
+			return err
+		}
+
+		var rowsRead int64
+		counted := &Repository{db: &countingDB{dbtx: tx.db, rows: &rowsRead}, gormDB: tx.gormDB, pinned: tx.pinned}
+
+		for _, s := range benchStrategies {
+			fn := func() (int, error) { return s.run(ctx, counted, ds.Cities[0], &rowsRead) }
+			if _, err := fn(); err != nil {
+				return fmt.Errorf("%s: %w", s.name, err)
+			}
+			samples := make([]bench.Sample, 0, iterations)
+			for i := 0; i < iterations; i++ {
+				sample, err := bench.Measure(fn, &rowsRead)
+				if err != nil {
+					return fmt.Errorf("%s: %w", s.name, err)
+				}
+				samples = append(samples, sample)
+			}
+			run.Results = append(run.Results, bench.Summarize(s.name, samples))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// SaveBenchRun сохраняет результаты прогона.
+func (r *Repository) SaveBenchRun(ctx context.Context, run *domain.BenchRun) error {
+	return r.inTx(ctx, func(tx *Repository) error {
+		err := tx.db.QueryRow(ctx, `
+			INSERT INTO bench_runs (run_at, run_by, seed, customers, parts, shipments, iterations)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			RETURNING run_id`,
+			run.RunAt, run.RunBy, run.Seed, run.Volumes.Customers, run.Volumes.Parts,
+			run.Volumes.Shipments, run.Iterations).Scan(&run.RunID)
+		if err != nil {
+			return err
+		}
+		for _, res := range run.Results {
+			_, err := tx.db.Exec(ctx, `
+				INSERT INTO bench_results (run_id, strategy, min_ms, p50_ms, p90_ms, p99_ms, max_ms, mean_ms,
+				                           result_rows, rows_read, alloc_bytes, allocs)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+				run.RunID, res.Strategy, res.MinMS, res.P50MS, res.P90MS, res.P99MS, res.MaxMS, res.MeanMS,
+				res.ResultRows, res.RowsRead, int64(res.AllocBytes), int64(res.Allocs))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetBenchRuns возвращает последние limit прогонов, новые первыми.
+func (r *Repository) GetBenchRuns(ctx context.Context, limit int) ([]domain.BenchRun, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT run_id, run_at, run_by, seed, customers, parts, shipments, iterations
+		FROM bench_runs
+		ORDER BY run_id DESC
+		LIMIT $1`, limit)
+	if err != nil {
+		return nil, err
+	}
+	runs, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.BenchRun, error) {
+		var b domain.BenchRun
+		err := row.Scan(&b.RunID, &b.RunAt, &b.RunBy, &b.Seed, &b.Volumes.Customers, &b.Volumes.Parts,
+			&b.Volumes.Shipments, &b.Iterations)
+		return b, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int64]*domain.BenchRun, len(runs))
+	ids := make([]int64, len(runs))
+	for i := range runs {
+		runs[i].Results = []domain.BenchResult{}
+		byID[runs[i].RunID] = &runs[i]
+		ids[i] = runs[i].RunID
+	}
+
+	rows, err = r.db.Query(ctx, `
+		SELECT run_id, strategy, min_ms, p50_ms, p90_ms, p99_ms, max_ms, mean_ms,
+		       result_rows, rows_read, alloc_bytes, allocs
+		FROM bench_results
+		WHERE run_id = ANY($1)
+		ORDER BY run_id, result_id`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var runID int64
+		var res domain.BenchResult
+		var allocBytes, allocs int64
+		if err := rows.Scan(&runID, &res.Strategy, &res.MinMS, &res.P50MS, &res.P90MS, &res.P99MS, &res.MaxMS,
+			&res.MeanMS, &res.ResultRows, &res.RowsRead, &allocBytes, &allocs); err != nil {
+			return nil, err
+		}
+		res.AllocBytes, res.Allocs = uint64(allocBytes), uint64(allocs)
+		byID[runID].Results = append(byID[runID].Results, res)
+	}
+	return runs, rows.Err()
+}
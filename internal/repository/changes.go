@@ -0,0 +1,353 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/student/my-kpfu-db-app/internal/domain"
+)
+
+// ============================================================================
+// Журнал изменений и отмена операций
+// ============================================================================
+
+// changeTable - первичный ключ таблицы с журналом изменений и ее уровень
+// в иерархии внешних ключей: 0 - детали и покупатели, 1 - записи, которые
+// на них ссылаются, 2 - подчиненные записи отгрузки.
+type changeTable struct {
+	key   []string
+	level int
+}
+
+// changeTables - таблицы с журналом изменений.
+var changeTables = map[string]changeTable{
+	"parts":             {key: []string{"part_code"}},
+	"customers":         {key: []string{"customer_id"}},
+	"shipments":         {key: []string{"warehouse_no", "shipment_doc_no"}, level: 1},
+	"part_units":        {key: []string{"part_code", "unit_code"}, level: 1},
+	"price_lists":       {key: []string{"price_list_id"}, level: 1},
+	"discount_rules":    {key: []string{"rule_id"}, level: 1},
+	"volume_discounts":  {key: []string{"tier_id"}, level: 1},
+	"customer_contacts": {key: []string{"contact_id"}, level: 1},
+	// Оплаты и подчиненные записи отгрузки журналируются только при удалении,
+	// объединения - при вставке и удалении
+	"payments":                {key: []string{"payment_id"}, level: 1},
+	"customer_merges":         {key: []string{"merge_id"}, level: 1},
+	"shipment_status_history": {key: []string{"history_id"}, level: 2},
+	"shipment_returns":        {key: []string{"return_id"}, level: 2},
+	"credit_overrides":        {key: []string{"override_id"}, level: 2},
+}
+
+// GetChanges возвращает последние limit изменений; user = "" - всех операторов.
+func (r *Repository) GetChanges(ctx context.Context, user string, limit int) ([]domain.Change, error) {
+	return r.queryChanges(ctx, `
+		SELECT change_id, ROW_NUMBER() OVER (ORDER BY MAX(log_id) DESC) FROM change_log
+		WHERE $1 = '' OR changed_by = $1
+		GROUP BY change_id
+		ORDER BY 2
+		LIMIT $2`, user, limit)
+}
+
+// UndoChange отменяет изменение: строки возвращаются к состоянию до него.
+// Если строку с тех пор изменили, возвращается ErrUndoConflict и ничего не отменяется.
+func (r *Repository) UndoChange(ctx context.Context, changeID int64) (*domain.Change, error) {
+	var changes []domain.Change
+	err := r.inTx(ctx, func(tx *Repository) error {
+		if err := tx.undo(ctx, changeID); err != nil {
+			return err
+		}
+		var err error
+		changes, err = tx.queryChanges(ctx, "SELECT $1::bigint, 1", changeID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &changes[0], nil
+}
+
+// UndoLast отменяет последние n изменений текущего оператора, начиная с самого
+// нового. Отмены и уже отмененные изменения пропускаются. Конфликт в любом из
+// изменений отменяет всю операцию.
+func (r *Repository) UndoLast(ctx context.Context, n int) ([]domain.Change, error) {
+	var changes []domain.Change
+	err := r.inTx(ctx, func(tx *Repository) error {
+		rows, err := tx.db.Query(ctx, `
+			SELECT change_id FROM change_log
+			WHERE changed_by = $1
+			GROUP BY change_id
+			HAVING NOT bool_or(is_undo) AND bool_and(undone_at IS NULL)
+			ORDER BY MAX(log_id) DESC
+			LIMIT $2`, UserFromContext(ctx), n)
+		if err != nil {
+			return err
+		}
+		ids, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+		if err != nil {
+			return err
+		}
+
+		for _, id := range ids {
+			if err := tx.undo(ctx, id); err != nil {
+				return err
+			}
+		}
+		changes, err = tx.queryChanges(ctx, "SELECT * FROM unnest($1::bigint[]) WITH ORDINALITY", ids)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// undo возвращает строки изменения к образам "до" и помечает изменение
+// отмененным. Сама отмена попадает в журнал с is_undo.
+func (r *Repository) undo(ctx context.Context, changeID int64) error {
+	rows, err := r.db.Query(ctx, `
+		SELECT log_id, table_name, operation, before_image, after_image, undone_at IS NOT NULL
+		FROM change_log
+		WHERE change_id = $1
+		ORDER BY log_id DESC
+		FOR UPDATE`, changeID)
+	if err != nil {
+		return err
+	}
+	var undone bool
+	entries, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.ChangeEntry, error) {
+		var e domain.ChangeEntry
+		err := row.Scan(&e.LogID, &e.Table, &e.Operation, &e.Before, &e.After, &undone)
+		return e, err
+	})
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("change %d: %w", changeID, ErrNotFound)
+	}
+	if undone {
+		return fmt.Errorf("%w: change %d was already undone", ErrUndoConflict, changeID)
+	}
+
+	if err := r.revertEntries(ctx, entries); err != nil {
+		return err
+	}
+	_, err = r.db.Exec(ctx, `UPDATE change_log SET undone_by = $2, undone_at = CURRENT_TIMESTAMP
+		WHERE change_id = $1`, changeID, UserFromContext(ctx))
+	return err
+}
+
+// revertEntries возвращает строки к образам "до". Изменение, которое нельзя
+// отменить целиком, не отменяется вовсе. Сначала удаляются вставленные строки
+// (подчиненные раньше родительских), затем возвращаются измененные и
+// удаленные (родительские раньше подчиненных); внутри - в обратном порядке.
+func (r *Repository) revertEntries(ctx context.Context, entries []domain.ChangeEntry) error {
+	for _, e := range entries {
+		if err := checkReversible(e); err != nil {
+			return err
+		}
+	}
+	if _, err := r.db.Exec(ctx, "SELECT set_config('app.undo', 'on', true)"); err != nil {
+		return err
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return revertOrder(entries[i]) < revertOrder(entries[j])
+	})
+	for _, e := range entries {
+		if err := r.revertEntry(ctx, e); err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && (pgErr.Code == "23503" || pgErr.Code == "23505") {
+				return fmt.Errorf("%w: %s: %s", ErrUndoConflict, e.Table, pgErr.Message)
+			}
+			return err
+		}
+	}
+	_, err := r.db.Exec(ctx, "SELECT set_config('app.undo', 'off', true)")
+	return err
+}
+
+// revertOrder - порядок отмены записи: вставки, затем изменения и удаления;
+// вставки - от подчиненных таблиц к родительским, остальное - наоборот.
+func revertOrder(e domain.ChangeEntry) int {
+	level := changeTables[e.Table].level
+	switch e.Operation {
+	case "INSERT":
+		return 2 - level
+	case "UPDATE":
+		return 3 + level
+	default:
+		return 6 + level
+	}
+}
+
+// checkReversible отклоняет записи журнала, изменение которых не сводится к
+// строкам журнала: выставление или аннулирование счета (счета и их строки не
+// журналируются), переход по статусам (отгрузка проходит их только вперед,
+// история переходов не журналируется) и объединение покупателей (ссылки на
+// дубль переходят к основному покупателю).
+func checkReversible(e domain.ChangeEntry) error {
+	switch {
+	case e.Table == "customer_merges" && e.Operation == "INSERT":
+		return fmt.Errorf("%w: customers were merged", ErrUndoConflict)
+	case e.Table == "shipments" && e.Operation == "UPDATE":
+		var b, a map[string]json.RawMessage
+		if err := json.Unmarshal(e.Before, &b); err != nil {
+			return err
+		}
+		if err := json.Unmarshal(e.After, &a); err != nil {
+			return err
+		}
+		if !bytes.Equal(b["status"], a["status"]) {
+			return fmt.Errorf("%w: shipment status changed from %s to %s", ErrUndoConflict, b["status"], a["status"])
+		}
+		if !bytes.Equal(b["invoice_id"], a["invoice_id"]) {
+			return fmt.Errorf("%w: shipment was invoiced or its invoice was voided", ErrUndoConflict)
+		}
+	}
+	return nil
+}
+
+// revertEntry возвращает одну строку к образу "до", предварительно проверив,
+// что текущее состояние строки совпадает с образом "после".
+func (r *Repository) revertEntry(ctx context.Context, e domain.ChangeEntry) error {
+	t, ok := changeTables[e.Table]
+	if !ok {
+		return fmt.Errorf("table %s has no change log", e.Table)
+	}
+	key := t.key
+	table := pgx.Identifier{e.Table}.Sanitize()
+	keyCols := columnList(key)
+	// Строка ищется по ключу из образа: после вставки и изменения - "после", после удаления - "до"
+	keyImage := e.After
+	if e.Operation == "DELETE" {
+		keyImage = e.Before
+	}
+	where := fmt.Sprintf("(%s) = (SELECT %s FROM jsonb_populate_record(NULL::%s, $1))", keyCols, keyCols, table)
+
+	var current []byte
+	var matches bool
+	err := r.db.QueryRow(ctx, fmt.Sprintf("SELECT to_jsonb(t), COALESCE(to_jsonb(t) = $2::jsonb, false) FROM %s t WHERE %s FOR UPDATE",
+		table, where), keyImage, e.After).Scan(&current, &matches)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+	exists := err == nil
+
+	switch e.Operation {
+	case "INSERT":
+		if !exists || !matches {
+			return fmt.Errorf("%w: %s row %s was changed since", ErrUndoConflict, e.Table, keyImage)
+		}
+		_, err = r.db.Exec(ctx, fmt.Sprintf("DELETE FROM %s WHERE %s", table, where), keyImage)
+	case "UPDATE":
+		if !exists || !matches {
+			// Строку уже вернул каскад ON UPDATE CASCADE при отмене изменения ключа родителя
+			var reverted bool
+			err := r.db.QueryRow(ctx, fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s t WHERE %s AND to_jsonb(t) = $1::jsonb)",
+				table, where), e.Before).Scan(&reverted)
+			if err != nil {
+				return err
+			}
+			if reverted {
+				return nil
+			}
+			return fmt.Errorf("%w: %s row %s was changed since", ErrUndoConflict, e.Table, keyImage)
+		}
+		cols, err := updatedColumns(e.Before, e.After, key)
+		if err != nil {
+			return err
+		}
+		list := columnList(cols)
+		_, err = r.db.Exec(ctx, fmt.Sprintf("UPDATE %s SET (%s) = (SELECT %s FROM jsonb_populate_record(NULL::%s, $2)) WHERE %s",
+			table, list, list, table, where), keyImage, e.Before)
+		return err
+	case "DELETE":
+		if exists {
+			return fmt.Errorf("%w: %s row %s exists again", ErrUndoConflict, e.Table, keyImage)
+		}
+		_, err = r.db.Exec(ctx, fmt.Sprintf("INSERT INTO %s OVERRIDING SYSTEM VALUE SELECT * FROM jsonb_populate_record(NULL::%s, $1)",
+			table, table), e.Before)
+	default:
+		return fmt.Errorf("unknown operation %s", e.Operation)
+	}
+	return err
+}
+
+// updatedColumns возвращает столбцы, которые нужно вернуть к образу "до".
+// Ключевые столбцы обновляются только если ключ менялся: идентификаторы
+// GENERATED ALWAYS нельзя присваивать.
+func updatedColumns(before, after json.RawMessage, key []string) ([]string, error) {
+	var b, a map[string]json.RawMessage
+	if err := json.Unmarshal(before, &b); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(after, &a); err != nil {
+		return nil, err
+	}
+
+	keyChanged := false
+	for _, k := range key {
+		if !bytes.Equal(b[k], a[k]) {
+			keyChanged = true
+		}
+	}
+
+	var cols []string
+	for col := range b {
+		if !keyChanged && slices.Contains(key, col) {
+			continue
+		}
+		cols = append(cols, col)
+	}
+	sort.Strings(cols)
+	return cols, nil
+}
+
+// queryChanges загружает изменения, идентификаторы и порядковые номера
+// которых возвращает idQuery.
+func (r *Repository) queryChanges(ctx context.Context, idQuery string, args ...any) ([]domain.Change, error) {
+	rows, err := r.db.Query(ctx, `
+		WITH ids(id, ord) AS (`+idQuery+`)
+		SELECT l.change_id, l.changed_by, l.changed_at, l.is_undo, l.undone_by, l.undone_at,
+		       l.log_id, l.table_name, l.operation, l.before_image, l.after_image
+		FROM change_log l
+		JOIN ids ON ids.id = l.change_id
+		ORDER BY ids.ord, l.log_id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []domain.Change
+	for rows.Next() {
+		var c domain.Change
+		var e domain.ChangeEntry
+		if err := rows.Scan(&c.ChangeID, &c.ChangedBy, &c.ChangedAt, &c.IsUndo, &c.UndoneBy, &c.UndoneAt,
+			&e.LogID, &e.Table, &e.Operation, &e.Before, &e.After); err != nil {
+			return nil, err
+		}
+		if n := len(changes); n == 0 || changes[n-1].ChangeID != c.ChangeID {
+			changes = append(changes, c)
+		}
+		last := &changes[len(changes)-1]
+		last.Entries = append(last.Entries, e)
+	}
+	return changes, rows.Err()
+}
+
+func columnList(cols []string) string {
+	quoted := make([]string, len(cols))
+	for i, col := range cols {
+		quoted[i] = pgx.Identifier{col}.Sanitize()
+	}
+	return strings.Join(quoted, ", ")
+}
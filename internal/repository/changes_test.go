@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+	"github.com/student/my-kpfu-db-app/internal/domain"
+)
+
+// loggedSince возвращает записи журнала после logID в порядке отмены. Sandbox -
+// одна транзакция, и все изменения в ней получают один change_id, поэтому
+// изменение выделяется по log_id.
+func loggedSince(t *testing.T, ctx context.Context, tx *Repository, logID int64) []domain.ChangeEntry {
+	t.Helper()
+	rows, err := tx.db.Query(ctx, `SELECT log_id, table_name, operation, before_image, after_image
+		FROM change_log WHERE log_id > $1 ORDER BY log_id DESC`, logID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.ChangeEntry, error) {
+		var e domain.ChangeEntry
+		err := row.Scan(&e.LogID, &e.Table, &e.Operation, &e.Before, &e.After)
+		return e, err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
+
+// childRows считает строки, которые ссылаются на покупателя и деталь отгрузки.
+func childRows(t *testing.T, ctx context.Context, tx *Repository, s domain.Shipment) [5]int {
+	t.Helper()
+	var n [5]int
+	err := tx.db.QueryRow(ctx, `
+		SELECT (SELECT COUNT(*) FROM shipments WHERE customer_id = $1),
+		       (SELECT COUNT(*) FROM payments WHERE customer_id = $1),
+		       (SELECT COUNT(*) FROM customer_contacts WHERE customer_id = $1),
+		       (SELECT COUNT(*) FROM price_lists WHERE customer_id = $1 OR part_code = $2),
+		       (SELECT COUNT(*) FROM part_units WHERE part_code = $2)`,
+		s.CustomerID, s.PartCode).Scan(&n[0], &n[1], &n[2], &n[3], &n[4])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestUndoChange(t *testing.T) {
+	repo := testRepository(t)
+	ctx := WithUser(context.Background(), "go-test")
+	vat := decimal.NewFromInt(20)
+
+	tests := []struct {
+		name   string
+		status domain.ShipmentStatus
+		// prepare выполняется до отменяемого изменения
+		prepare func(tx *Repository, s domain.Shipment) error
+		change  func(tx *Repository, s domain.Shipment) error
+		want    error
+	}{
+		{
+			name:   "edit shipment",
+			status: domain.StatusDraft,
+			change: func(tx *Repository, s domain.Shipment) error {
+				_, err := tx.db.Exec(ctx, "UPDATE shipments SET qty = 5 WHERE warehouse_no = $1 AND shipment_doc_no = $2",
+					s.WarehouseNo, s.ShipmentDocNo)
+				return err
+			},
+		},
+		{
+			name:   "transition",
+			status: domain.StatusConfirmed,
+			change: func(tx *Repository, s domain.Shipment) error {
+				_, err := tx.TransitionShipment(ctx, s.WarehouseNo, s.ShipmentDocNo, domain.StatusPicked)
+				return err
+			},
+			want: ErrUndoConflict,
+		},
+		{
+			name:   "invoice",
+			status: domain.StatusShipped,
+			change: func(tx *Repository, s domain.Shipment) error {
+				_, err := tx.CreateInvoice(ctx, s.CustomerID, s.ShipmentDate, s.ShipmentDate, vat)
+				return err
+			},
+			want: ErrUndoConflict,
+		},
+		{
+			name:   "void invoice",
+			status: domain.StatusShipped,
+			prepare: func(tx *Repository, s domain.Shipment) error {
+				_, err := tx.CreateInvoice(ctx, s.CustomerID, s.ShipmentDate, s.ShipmentDate, vat)
+				return err
+			},
+			change: func(tx *Repository, s domain.Shipment) error {
+				var invoiceID int64
+				if err := tx.db.QueryRow(ctx, "SELECT invoice_id FROM invoices WHERE customer_id = $1",
+					s.CustomerID).Scan(&invoiceID); err != nil {
+					return err
+				}
+				_, err := tx.VoidInvoice(ctx, invoiceID)
+				return err
+			},
+			want: ErrUndoConflict,
+		},
+		{
+			name:   "merge",
+			status: domain.StatusDraft,
+			change: func(tx *Repository, s domain.Shipment) error {
+				survivor := domain.Customer{Name: "Основной покупатель", City: "Казань"}
+				if err := tx.CreateCustomer(ctx, &survivor); err != nil {
+					return err
+				}
+				_, err := tx.MergeCustomers(ctx, survivor.CustomerID, s.CustomerID)
+				return err
+			},
+			want: ErrUndoConflict,
+		},
+		{
+			name:    "purge customer",
+			status:  domain.StatusDraft,
+			prepare: func(tx *Repository, s domain.Shipment) error { return tx.DeleteCustomer(ctx, s.CustomerID) },
+			change: func(tx *Repository, s domain.Shipment) error {
+				_, err := tx.PurgeCustomer(ctx, s.CustomerID, true)
+				return err
+			},
+		},
+		{
+			name:    "purge part",
+			status:  domain.StatusDraft,
+			prepare: func(tx *Repository, s domain.Shipment) error { return tx.DeletePart(ctx, s.PartCode) },
+			change: func(tx *Repository, s domain.Shipment) error {
+				_, err := tx.PurgePart(ctx, s.PartCode, true)
+				return err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := repo.Sandbox(ctx, func(tx *Repository) error {
+				s := testShipment(t, ctx, tx, tt.status)
+				if err := tx.RecordPayment(ctx, &domain.Payment{CustomerID: s.CustomerID, PaymentDate: s.ShipmentDate,
+					Amount: decimal.NewFromInt(500)}); err != nil {
+					t.Fatalf("record payment: %v", err)
+				}
+				if err := tx.CreateContact(ctx, &domain.Contact{CustomerID: s.CustomerID, Name: "Иванов",
+					Email: "ivanov@example.com", IsPrimary: true}); err != nil {
+					t.Fatalf("create contact: %v", err)
+				}
+				if err := tx.CreatePriceListEntry(ctx, &domain.PriceListEntry{CustomerID: s.CustomerID, PartCode: s.PartCode,
+					Price: decimal.NewFromInt(90), ValidFrom: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}); err != nil {
+					t.Fatalf("create price: %v", err)
+				}
+				if err := tx.AddPartUnit(ctx, &domain.PartUnit{PartCode: s.PartCode, UnitCode: "компл",
+					Factor: decimal.NewFromInt(5)}); err != nil {
+					t.Fatalf("add unit: %v", err)
+				}
+				if tt.prepare != nil {
+					if err := tt.prepare(tx, s); err != nil {
+						t.Fatalf("prepare: %v", err)
+					}
+				}
+				before := childRows(t, ctx, tx, s)
+
+				var mark int64
+				if err := tx.db.QueryRow(ctx, "SELECT COALESCE(MAX(log_id), 0) FROM change_log").Scan(&mark); err != nil {
+					t.Fatal(err)
+				}
+				if err := tt.change(tx, s); err != nil {
+					t.Fatalf("change: %v", err)
+				}
+				if err := tx.revertEntries(ctx, loggedSince(t, ctx, tx, mark)); err != nil {
+					return err
+				}
+				if after := childRows(t, ctx, tx, s); after != before {
+					t.Errorf("rows after undo = %v, want %v", after, before)
+				}
+				return nil
+			})
+			if tt.want == nil && err != nil || tt.want != nil && !errors.Is(err, tt.want) {
+				t.Fatalf("revertEntries() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,258 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/student/my-kpfu-db-app/internal/compare"
+	"github.com/student/my-kpfu-db-app/internal/domain"
+	"gorm.io/gorm"
+)
+
+// ============================================================================
+// Сравнение парных реализаций запросов (SQL / ORM / обход коллекций)
+// ============================================================================
+
+// timed выполняет fn и возвращает время выполнения в миллисекундах.
+func timed[T any](fn func() (T, error)) (T, float64, error) {
+	start := time.Now()
+	rows, err := fn()
+	return rows, float64(time.Since(start).Microseconds()) / 1000, err
+}
+
+// CompareTask1 выполняет SQL и ORM варианты Задачи 1 и сравнивает результаты.
+func (r *Repository) CompareTask1(ctx context.Context, city string) (*domain.Comparison, error) {
+	c := &domain.Comparison{Query: "task-1 city=" + city, Left: "sql", Right: "orm"}
+	left, ms, err := timed(func() ([]domain.Task1Result, error) { return r.GetTask1SQL(ctx, city) })
+	if err != nil {
+		return nil, err
+	}
+	c.LeftMS = ms
+	right, ms, err := timed(func() ([]domain.Task1Result, error) { return r.GetTask1ORM(ctx, city) })
+	if err != nil {
+		return nil, err
+	}
+	c.RightMS = ms
+
+	compare.Diff(c, left, right, compare.Task1Key, compare.Task1Value)
+	return c, nil
+}
+
+// CompareTask3 выполняет кванторный SQL и record-ориентированный варианты
+// Задачи 3 и сравнивает результаты.
+func (r *Repository) CompareTask3(ctx context.Context, q domain.Task3Query) (*domain.Comparison, error) {
+	c := &domain.Comparison{Query: "task-3 " + q.String(), Left: "sql", Right: "record"}
+	left, ms, err := timed(func() ([]domain.Task3Result, error) { return r.GetTask3SQL(ctx, q) })
+	if err != nil {
+		return nil, err
+	}
+	c.LeftMS = ms
+	right, ms, err := timed(func() ([]domain.Task3Result, error) { return r.GetTask3RecordBased(ctx, q) })
+	if err != nil {
+		return nil, err
+	}
+	c.RightMS = ms
+
+	compare.Diff(c, left, right, compare.Task3Key, compare.Task3Value)
+	return c, nil
+}
+
+// Sandbox выполняет fn в транзакции, которая всегда откатывается. В отличие
+// от inTx, GORM работает в той же транзакции: pgx и GORM используют одно
+// соединение, поэтому ORM-варианты видят незафиксированные данные.
+func (r *Repository) Sandbox(ctx context.Context, fn func(tx *Repository) error) error {
+	return r.pinnedTx(ctx, false, fn)
+}
+
+// errRollback откатывает вложенную транзакцию pinnedTx без фиксации.
+var errRollback = errors.New("rollback")
+
+// pinnedTx выполняет fn в транзакции на выделенном соединении, общем для pgx
+// и GORM, и фиксирует ее, если commit и fn завершилась без ошибки. Внутри
+// другой pinnedTx соединение уже общее, и транзакция становится точкой
+// сохранения: так операции GormStore можно выполнять в DryRun.
+func (r *Repository) pinnedTx(ctx context.Context, commit bool, fn func(tx *Repository) error) error {
+	if r.pinned {
+		err := r.inTx(ctx, func(tx *Repository) error {
+			if err := fn(tx); err != nil {
+				return err
+			}
+			if !commit {
+				return errRollback
+			}
+			return nil
+		})
+		if errors.Is(err, errRollback) {
+			return nil
+		}
+		return err
+	}
+
+	sqlDB, err := r.gormDB.DB()
+	if err != nil {
+		return err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Соединение закреплено за conn до Close и используется последовательно,
+	// поэтому pgx может работать с ним и за пределами Raw
+	var pgxConn *pgx.Conn
+	if err := conn.Raw(func(driverConn any) error {
+		c, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return fmt.Errorf("unexpected driver connection %T", driverConn)
+		}
+		pgxConn = c.Conn()
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	// Сессия GORM с настройками основного подключения, но на закрепленном
+	// соединении - так же GORM сам привязывает сессию к транзакции. Своя
+	// транзакция GORM для записи отключена: ее COMMIT на этом соединении
+	// зафиксировал бы транзакцию pgx
+	gormDB := r.gormDB.Session(&gorm.Session{NewDB: true, Context: ctx, SkipDefaultTransaction: true})
+	gormDB.Statement.ConnPool = conn
+
+	tx, err := pgxConn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "SELECT set_config('app.user', $1, true)", UserFromContext(ctx)); err != nil {
+		return err
+	}
+	if err := fn(&Repository{db: tx, gormDB: gormDB, pinned: true}); err != nil {
+		return err
+	}
+	if !commit {
+		return nil
+	}
+	return tx.Commit(ctx)
+}
+
+// loadDataset вставляет набор данных пакетно, по одному запросу на таблицу,
+// чтобы большие наборы загружались быстро. Номера покупателей берутся из
+// последовательности заранее, номера документов следуют за существующими.
+func (r *Repository) loadDataset(ctx context.Context, ds compare.Dataset) error {
+	if _, err := r.db.Exec(ctx, "INSERT INTO cities (name) SELECT unnest($1::text[])", ds.Cities); err != nil {
+		return err
+	}
+
+	rows, err := r.db.Query(ctx,
+		"SELECT nextval(pg_get_serial_sequence('customers', 'customer_id'))::int FROM generate_series(1, $1)",
+		len(ds.Customers))
+	if err != nil {
+		return err
+	}
+	customerIDs, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		return err
+	}
+	names := make([]string, len(ds.Customers))
+	cities := make([]string, len(ds.Customers))
+	currencies := make([]string, len(ds.Customers))
+	for i, c := range ds.Customers {
+		names[i], cities[i], currencies[i] = c.Name, c.City, c.Currency
+	}
+	_, err = r.db.Exec(ctx, `
+		INSERT INTO customers (customer_id, name, city, currency) OVERRIDING SYSTEM VALUE
+		SELECT * FROM unnest($1::int[], $2::text[], $3::text[], $4::text[])`,
+		customerIDs, names, cities, currencies)
+	if err != nil {
+		return err
+	}
+
+	var codes, types, partNames, units, prices, partCurrencies []string
+	for _, p := range ds.Parts {
+		codes = append(codes, p.PartCode)
+		types = append(types, p.PartType)
+		partNames = append(partNames, p.Name)
+		units = append(units, p.Unit)
+		prices = append(prices, p.PlanPrice.String())
+		partCurrencies = append(partCurrencies, p.Currency)
+	}
+	_, err = r.db.Exec(ctx, `
+		INSERT INTO parts (part_code, part_type, name, unit, plan_price, currency)
+		SELECT * FROM unnest($1::text[], $2::text[], $3::text[], $4::text[], $5::numeric[], $6::text[])`,
+		codes, types, partNames, units, prices, partCurrencies)
+	if err != nil {
+		return err
+	}
+
+	var lastDocNo int
+	if err := r.db.QueryRow(ctx, "SELECT COALESCE(MAX(shipment_doc_no), 0) FROM shipments").Scan(&lastDocNo); err != nil {
+		return err
+	}
+	n := len(ds.Shipments)
+	warehouses, docNos, shipCustomers := make([]int, n), make([]int, n), make([]int, n)
+	shipParts, shipUnits, qtys, shipPrices, statuses := make([]string, n), make([]string, n), make([]string, n), make([]string, n), make([]string, n)
+	dates := make([]time.Time, n)
+	for i, s := range ds.Shipments {
+		warehouses[i], docNos[i], shipCustomers[i] = s.WarehouseNo, lastDocNo+1+s.ShipmentDocNo, customerIDs[s.CustomerID]
+		shipParts[i], shipUnits[i], qtys[i], shipPrices[i] = s.PartCode, s.Unit, s.Qty.String(), s.Price.String()
+		dates[i], statuses[i] = s.ShipmentDate, string(s.Status)
+	}
+	_, err = r.db.Exec(ctx, `
+		INSERT INTO shipments (warehouse_no, shipment_doc_no, customer_id, part_code, unit, qty, price, shipment_date, status)
+		SELECT * FROM unnest($1::int[], $2::int[], $3::int[], $4::text[], $5::text[], $6::numeric[], $7::numeric[], $8::date[], $9::text[])`,
+		warehouses, docNos, shipCustomers, shipParts, shipUnits, qtys, shipPrices, dates, statuses)
+	return err
+}
+
+// CheckRandom сравнивает парные реализации на runs случайных наборах данных
+// (seed, seed+1, ...) размера size. Каждый набор загружается в Sandbox и
+// откатывается; Задача 1 проверяется по каждому городу набора в случайном
+// написании, Задача 3 - исходным и queries случайными условиями.
+func (r *Repository) CheckRandom(ctx context.Context, seed int64, runs, size, queries int) ([]domain.Comparison, error) {
+	var results []domain.Comparison
+	for run := int64(0); run < int64(runs); run++ {
+		s := seed + run
+		ds := compare.Random(s, size)
+		rng := rand.New(rand.NewSource(s))
+
+		err := r.Sandbox(ctx, func(tx *Repository) error {
+			if err := tx.loadDataset(ctx, ds); err != nil {
+				return err
+			}
+			add := func(c *domain.Comparison) {
+				c.Query = fmt.Sprintf("seed=%d %s", s, c.Query)
+				results = append(results, *c)
+			}
+
+			for _, city := range ds.Cities {
+				c, err := tx.CompareTask1(ctx, compare.CitySpelling(rng, city))
+				if err != nil {
+					return err
+				}
+				add(c)
+			}
+
+			q := domain.DefaultTask3Query()
+			for i := 0; i <= queries; i++ {
+				c, err := tx.CompareTask3(ctx, q)
+				if err != nil {
+					return err
+				}
+				add(c)
+				q = compare.RandomTask3Query(rng)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("seed %d: %w", s, err)
+		}
+	}
+	return results, nil
+}
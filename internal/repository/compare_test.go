@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/student/my-kpfu-db-app/internal/database"
+)
+
+// testRepository подключается к базе из DB_URL; без нее тест пропускается.
+// Тесты работают в Sandbox и ничего не оставляют в базе.
+func testRepository(t *testing.T) *Repository {
+	t.Helper()
+	url := os.Getenv("DB_URL")
+	if url == "" {
+		t.Skip("DB_URL is not set")
+	}
+
+	dbpool, err := database.NewConnection(url)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(dbpool.Close)
+	gormDB, err := database.NewGormConnection(url)
+	if err != nil {
+		t.Fatalf("connect with GORM: %v", err)
+	}
+	return New(dbpool, gormDB)
+}
+
+func TestCheckRandom(t *testing.T) {
+	repo := testRepository(t)
+	ctx := WithUser(context.Background(), "go-test")
+
+	results, err := repo.CheckRandom(ctx, 1, 5, 20, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) == 0 {
+		t.Fatal("no comparisons were made")
+	}
+	for _, c := range results {
+		if !c.Equal {
+			t.Errorf("%s: %s %d rows, %s %d rows; missing %v, extra %v, different %v",
+				c.Query, c.Left, c.LeftRows, c.Right, c.RightRows, c.Missing, c.Extra, c.Different)
+		}
+	}
+}
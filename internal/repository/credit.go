@@ -0,0 +1,207 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+	"github.com/student/my-kpfu-db-app/internal/domain"
+)
+
+// ============================================================================
+// Оплаты, задолженность и кредитный лимит
+// ============================================================================
+
+// CreditLimitError возвращается, если отгрузка выводит задолженность покупателя
+// за кредитный лимит.
+type CreditLimitError struct {
+	CustomerID  int
+	CreditLimit decimal.Decimal
+	Exposure    decimal.Decimal
+	Currency    string
+}
+
+func (e *CreditLimitError) Error() string {
+	return fmt.Sprintf("%v: customer %d would owe %s %s with limit %s %s",
+		ErrCreditLimit, e.CustomerID, e.Exposure.StringFixed(2), e.Currency, e.CreditLimit.StringFixed(2), e.Currency)
+}
+
+func (e *CreditLimitError) Unwrap() error {
+	return ErrCreditLimit
+}
+
+// RecordPayment регистрирует оплату покупателя. Валюта по умолчанию - валюта покупателя.
+func (r *Repository) RecordPayment(ctx context.Context, p *domain.Payment) error {
+	if !p.Amount.IsPositive() {
+		return fmt.Errorf("%w: amount must be positive", ErrInvalidPayment)
+	}
+	p.Amount = domain.RoundMoney(p.Amount)
+	p.CreatedBy = UserFromContext(ctx)
+
+	return r.inTx(ctx, func(tx *Repository) error {
+		var currency string
+		err := tx.db.QueryRow(ctx, "SELECT currency FROM customers WHERE customer_id = $1", p.CustomerID).Scan(&currency)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("customer %d: %w", p.CustomerID, ErrNotFound)
+		}
+		if err != nil {
+			return err
+		}
+		if p.Currency == "" {
+			p.Currency = currency
+		}
+		if err := tx.validateCurrency(ctx, p.Currency); err != nil {
+			return err
+		}
+
+		query := `INSERT INTO payments (customer_id, payment_date, amount, currency, reference, created_by)
+		          VALUES ($1, $2, $3, $4, $5, $6) RETURNING payment_id, created_at`
+		return tx.db.QueryRow(ctx, query, p.CustomerID, p.PaymentDate, p.Amount, p.Currency,
+			p.Reference, p.CreatedBy).Scan(&p.PaymentID, &p.CreatedAt)
+	})
+}
+
+// GetPayments возвращает оплаты, новые сначала; customerID = 0 - всех покупателей.
+func (r *Repository) GetPayments(ctx context.Context, customerID int) ([]domain.Payment, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT payment_id, customer_id, payment_date, amount, currency, reference, created_by, created_at
+		FROM payments
+		WHERE $1 = 0 OR customer_id = $1
+		ORDER BY payment_date DESC, payment_id DESC`, customerID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.Payment, error) {
+		var p domain.Payment
+		err := row.Scan(&p.PaymentID, &p.CustomerID, &p.PaymentDate, &p.Amount, &p.Currency,
+			&p.Reference, &p.CreatedBy, &p.CreatedAt)
+		return p, err
+	})
+}
+
+func (r *Repository) GetCustomerBalance(ctx context.Context, customerID int) (*domain.CustomerBalance, error) {
+	balances, err := r.queryBalances(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+	if len(balances) == 0 {
+		return nil, fmt.Errorf("customer %d: %w", customerID, ErrNotFound)
+	}
+	return &balances[0], nil
+}
+
+// GetDebtors возвращает покупателей с положительной задолженностью, крупные долги сначала.
+func (r *Repository) GetDebtors(ctx context.Context) ([]domain.CustomerBalance, error) {
+	balances, err := r.queryBalances(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var debtors []domain.CustomerBalance
+	for _, b := range balances {
+		if b.Balance.IsPositive() {
+			debtors = append(debtors, b)
+		}
+	}
+	// Суммы в разных валютах не сравниваются: сортируем по валюте, затем по долгу
+	sort.SliceStable(debtors, func(i, j int) bool {
+		if debtors[i].Currency != debtors[j].Currency {
+			return debtors[i].Currency < debtors[j].Currency
+		}
+		return debtors[i].Balance.GreaterThan(debtors[j].Balance)
+	})
+	return debtors, nil
+}
+
+// queryBalances считает задолженность покупателей в их валютах; customerID = 0 - всех.
+// Отгрузки оцениваются так же, как в GetCustomerShipmentSummary, оплаты
+// пересчитываются по курсу на дату оплаты.
+func (r *Repository) queryBalances(ctx context.Context, customerID int) ([]domain.CustomerBalance, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT c.customer_id, c.name, c.city, c.currency, c.credit_limit,
+		       sh.shipped_value, pay.paid, sh.open_value, pay.last_payment
+		FROM customers c,
+		LATERAL (
+			SELECT
+				COALESCE(SUM(v.value) FILTER (WHERE `+salesFilter+`), 0) AS shipped_value,
+				COALESCE(SUM(v.value) FILTER (WHERE v.status IN ('draft', 'confirmed', 'picked')), 0) AS open_value
+			FROM (
+				SELECT s.status, `+fmt.Sprintf(shipmentValueSQL, "c.currency")+` AS value
+				FROM shipments s
+				JOIN v_shipment_net n ON s.warehouse_no = n.warehouse_no AND s.shipment_doc_no = n.shipment_doc_no
+				JOIN parts p ON s.part_code = p.part_code
+				WHERE s.customer_id = c.customer_id
+			) v
+		) sh,
+		LATERAL (
+			SELECT
+				COALESCE(SUM(ROUND(pm.amount
+					* fn_exchange_rate(pm.currency, pm.payment_date)
+					/ fn_exchange_rate(c.currency, pm.payment_date), 2)), 0) AS paid,
+				MAX(pm.payment_date) AS last_payment
+			FROM payments pm
+			WHERE pm.customer_id = c.customer_id
+		) pay
+		WHERE $1 = 0 OR c.customer_id = $1
+		ORDER BY c.customer_id`, customerID)
+	if err != nil {
+		return nil, rateError(err)
+	}
+	balances, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.CustomerBalance, error) {
+		var b domain.CustomerBalance
+		err := row.Scan(&b.CustomerID, &b.CustomerName, &b.CustomerCity, &b.Currency, &b.CreditLimit,
+			&b.ShippedValue, &b.PaidAmount, &b.OpenValue, &b.LastPaymentDate)
+		b.Calculate()
+		return b, err
+	})
+	return balances, rateError(err)
+}
+
+// checkCreditLimit проверяет лимит покупателя после записи новой или измененной
+// отгрузки: она уже входит в незавершенные документы. Строка покупателя блокируется, чтобы
+// параллельные отгрузки не превысили лимит вместе (NO KEY UPDATE не конфликтует
+// с блокировкой внешнего ключа при вставке отгрузки). С override превышение
+// допускается и записывается в журнал.
+func (r *Repository) checkCreditLimit(ctx context.Context, s *domain.Shipment, override bool) error {
+	var limit *decimal.Decimal
+	err := r.db.QueryRow(ctx, "SELECT credit_limit FROM customers WHERE customer_id = $1 FOR NO KEY UPDATE",
+		s.CustomerID).Scan(&limit)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("customer %d: %w", s.CustomerID, ErrNotFound)
+	}
+	if err != nil || limit == nil {
+		return err
+	}
+
+	balance, err := r.GetCustomerBalance(ctx, s.CustomerID)
+	if err != nil {
+		return err
+	}
+	if !balance.OverLimit {
+		return nil
+	}
+	if !override {
+		return &CreditLimitError{
+			CustomerID:  s.CustomerID,
+			CreditLimit: *limit,
+			Exposure:    balance.Exposure,
+			Currency:    balance.Currency,
+		}
+	}
+
+	_, err = r.db.Exec(ctx, `
+		INSERT INTO credit_overrides (warehouse_no, shipment_doc_no, customer_id, credit_limit, exposure, currency, overridden_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		s.WarehouseNo, s.ShipmentDocNo, s.CustomerID, *limit, balance.Exposure, balance.Currency, UserFromContext(ctx))
+	return err
+}
+
+func validCreditLimit(limit *decimal.Decimal) error {
+	if limit != nil && limit.IsNegative() {
+		return fmt.Errorf("%w: must not be negative", ErrInvalidCreditLimit)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/student/my-kpfu-db-app/internal/domain"
+)
+
+// ============================================================================
+// Валюты и курсы
+// ============================================================================
+
+func (r *Repository) GetCurrencies(ctx context.Context) ([]domain.Currency, error) {
+	rows, err := r.db.Query(ctx, "SELECT currency_code, name FROM currencies ORDER BY currency_code")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var currencies []domain.Currency
+	for rows.Next() {
+		var c domain.Currency
+		if err := rows.Scan(&c.Code, &c.Name); err != nil {
+			return nil, err
+		}
+		currencies = append(currencies, c)
+	}
+	return currencies, nil
+}
+
+// GetExchangeRates возвращает курсы, новые сначала; пустая валюта - все валюты.
+func (r *Repository) GetExchangeRates(ctx context.Context, currency string) ([]domain.ExchangeRate, error) {
+	query := `SELECT currency_code, rate_date, rate FROM exchange_rates
+	          WHERE $1 = '' OR currency_code = $1
+	          ORDER BY rate_date DESC, currency_code`
+	rows, err := r.db.Query(ctx, query, currency)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rates []domain.ExchangeRate
+	for rows.Next() {
+		var rate domain.ExchangeRate
+		if err := rows.Scan(&rate.Currency, &rate.Date, &rate.Rate); err != nil {
+			return nil, err
+		}
+		rates = append(rates, rate)
+	}
+	return rates, nil
+}
+
+// ImportExchangeRates загружает курсы, заменяя уже известные на те же даты.
+// Курсы валют, отсутствующих в справочнике, пропускаются (в файле ЦБ их десятки).
+func (r *Repository) ImportExchangeRates(ctx context.Context, rates []domain.ExchangeRate) (int, error) {
+	imported := 0
+	err := r.inTx(ctx, func(tx *Repository) error {
+		for _, rate := range rates {
+			tag, err := tx.db.Exec(ctx, `
+				INSERT INTO exchange_rates (currency_code, rate_date, rate)
+				SELECT currency_code, $2, $3 FROM currencies WHERE currency_code = $1
+				ON CONFLICT (currency_code, rate_date) DO UPDATE SET rate = EXCLUDED.rate`,
+				rate.Currency, rate.Date, rate.Rate)
+			if err != nil {
+				return err
+			}
+			imported += int(tag.RowsAffected())
+		}
+		return nil
+	})
+	return imported, err
+}
+
+// validateCurrency проверяет, что валюта есть в справочнике.
+func (r *Repository) validateCurrency(ctx context.Context, currency string) error {
+	var known bool
+	err := r.db.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM currencies WHERE currency_code = $1)",
+		currency).Scan(&known)
+	if err != nil {
+		return err
+	}
+	if !known {
+		return fmt.Errorf("%w: %s", ErrUnknownCurrency, currency)
+	}
+	return nil
+}
+
+// rateError переводит ошибку fn_exchange_rate об отсутствии курса в ErrNoExchangeRate.
+func rateError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "P0002" {
+		return fmt.Errorf("%w: %s", ErrNoExchangeRate, pgErr.Message)
+	}
+	return err
+}
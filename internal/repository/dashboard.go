@@ -0,0 +1,230 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/student/my-kpfu-db-app/internal/domain"
+)
+
+// ============================================================================
+// Аналитика продаж: временные ряды и разрезы по VIEW
+// ============================================================================
+
+// salesStatuses - продажами считаются отгруженные и доставленные отгрузки
+const salesStatuses = "('shipped', 'delivered')"
+
+// salesFilter отбирает продажи среди строк VIEW, shipmentSalesFilter - среди
+// строк shipments s
+const (
+	salesFilter         = "v.status IN " + salesStatuses
+	shipmentSalesFilter = "s.status IN " + salesStatuses
+)
+
+// salesColumn - ключ и название значения измерения в строке VIEW и единица
+// количества. Количество выводится только в разрезе деталей: в остальных
+// разрезах складывались бы разные единицы, тогда unit пустой
+type salesColumn struct {
+	key, name, unit string
+}
+
+// salesValue - сумма строки VIEW в валюте отчета по курсу на дату отгрузки;
+// param - номер параметра с кодом валюты
+func salesValue(param int) string {
+	return fmt.Sprintf("ROUND(v.total_price_rub / fn_exchange_rate($%d::text, v.shipment_date), 2)", param)
+}
+
+var (
+	// salesTotal - весь период одним рядом
+	salesTotal = salesColumn{"''::text", "''::text", ""}
+
+	salesDimensionColumns = map[domain.SalesDimension]salesColumn{
+		domain.DimensionWarehouse: {"v.warehouse_no::text", "'Склад ' || v.warehouse_no", ""},
+		domain.DimensionCity:      {"v.customer_city", "v.customer_city", ""},
+		domain.DimensionPartType:  {"v.part_type", "v.part_type", ""},
+		domain.DimensionCustomer:  {"v.customer_id::text", "v.customer_name", ""},
+		domain.DimensionPart:      {"v.part_code", "v.part_name", "v.base_unit"},
+	}
+)
+
+// salesSeriesQuery строит временные ряды суммы продаж по значениям измерения
+// за период [$1, $2): периоды без продаж заполняются нулями, рост считается к
+// предыдущему периоду, накопленные итоги - с начала периода. $3 - число рядов
+// с наибольшей суммой (0 - все), $4 - валюта отчета.
+func salesSeriesQuery(g domain.Granularity, col salesColumn) string {
+	return fmt.Sprintf(`
+		WITH periods AS (
+			SELECT generate_series(date_trunc('%[1]s', $1::date::timestamp), ($2::date - 1)::timestamp,
+			                       interval '%[2]s')::date AS period
+		),
+		sales AS (
+			SELECT date_trunc('%[1]s', v.shipment_date::timestamp)::date AS period,
+			       %[3]s AS key,
+			       MIN(%[4]s) AS name,
+			       SUM(%[6]s) AS value
+			FROM v_full_shipment_info v
+			WHERE v.shipment_date >= $1::date AND v.shipment_date < $2::date AND %[5]s
+			GROUP BY 1, 2
+		),
+		series AS (
+			SELECT key, MIN(name) AS name, ROW_NUMBER() OVER (ORDER BY SUM(value) DESC, key) AS rank
+			FROM sales
+			GROUP BY key
+		)
+		SELECT 
+			key,
+			name,
+			period,
+			value,
+			ROUND((value / NULLIF(LAG(value) OVER w, 0) - 1) * 100, 2) AS value_growth,
+			SUM(value) OVER w AS cumulative_value
+		FROM (
+			SELECT r.key, r.name, r.rank, p.period, COALESCE(s.value, 0) AS value
+			FROM series r
+			CROSS JOIN periods p
+			LEFT JOIN sales s ON s.key = r.key AND s.period = p.period
+			WHERE $3::int = 0 OR r.rank <= $3::int
+		) t
+		WINDOW w AS (PARTITION BY key ORDER BY period)
+		ORDER BY rank, period
+	`, g, g.Interval(), col.key, col.name, salesFilter, salesValue(4))
+}
+
+// salesShareQuery строит продажи по значениям измерения за период [$1, $2)
+// с местом, долей от суммы периода и ростом к предыдущему периоду [$3, $1).
+// Количество и его единица - только если у измерения одна единица (col.unit),
+// иначе NULL. $4 - число строк (0 - все), $5 - валюта отчета.
+func salesShareQuery(col salesColumn) string {
+	qty, unit := "NULL::numeric", "''::text"
+	if col.unit != "" {
+		qty = "COALESCE(SUM(v.base_qty) FILTER (WHERE v.shipment_date >= $1::date), 0)"
+		unit = "MIN(" + col.unit + ")"
+	}
+	return fmt.Sprintf(`
+		SELECT rank, key, name, qty, unit, value, share, prev_value, growth
+		FROM (
+			SELECT 
+				key,
+				name,
+				qty,
+				unit,
+				value,
+				prev_value,
+				RANK() OVER (ORDER BY value DESC) AS rank,
+				ROUND(value / NULLIF(SUM(value) OVER (), 0) * 100, 2) AS share,
+				ROUND((value / NULLIF(prev_value, 0) - 1) * 100, 2) AS growth
+			FROM (
+				SELECT 
+					%[1]s AS key,
+					MIN(%[2]s) AS name,
+					%[5]s AS qty,
+					%[6]s AS unit,
+					COALESCE(SUM(%[4]s) FILTER (WHERE v.shipment_date >= $1::date), 0) AS value,
+					COALESCE(SUM(%[4]s) FILTER (WHERE v.shipment_date < $1::date), 0) AS prev_value
+				FROM v_full_shipment_info v
+				WHERE v.shipment_date >= $3::date AND v.shipment_date < $2::date AND %[3]s
+				GROUP BY 1
+				HAVING COUNT(*) FILTER (WHERE v.shipment_date >= $1::date) > 0
+			) t
+		) r
+		ORDER BY rank, key
+		LIMIT NULLIF($4::int, 0)
+	`, col.key, col.name, salesFilter, salesValue(5), qty, unit)
+}
+
+// salesTotalsQuery - итоги периода [$1, $2) и рост к предыдущему периоду [$3, $1)
+// в валюте $4
+var salesTotalsQuery = fmt.Sprintf(`
+		SELECT shipments, value, prev_value, ROUND((value / NULLIF(prev_value, 0) - 1) * 100, 2)
+		FROM (
+			SELECT 
+				COUNT(*) FILTER (WHERE v.shipment_date >= $1::date) AS shipments,
+				COALESCE(SUM(%[2]s) FILTER (WHERE v.shipment_date >= $1::date), 0) AS value,
+				COALESCE(SUM(%[2]s) FILTER (WHERE v.shipment_date < $1::date), 0) AS prev_value
+			FROM v_full_shipment_info v
+			WHERE v.shipment_date >= $3::date AND v.shipment_date < $2::date AND %[1]s
+		) t
+	`, salesFilter, salesValue(4))
+
+// GetDashboard собирает обзор продаж: итоги, общий временной ряд, разрез по
+// измерению f.By с рядами его первых f.TopN значений и первые f.TopN деталей
+// и покупателей.
+func (r *Repository) GetDashboard(ctx context.Context, f domain.DashboardFilter) (*domain.Dashboard, error) {
+	if err := r.validateCurrency(ctx, f.Currency); err != nil {
+		return nil, err
+	}
+	d := &domain.Dashboard{Filter: f, Currency: f.Currency, Series: []domain.SalesPoint{}}
+	prev := f.PreviousFrom()
+
+	t := &d.Totals
+	err := r.db.QueryRow(ctx, salesTotalsQuery, f.From, f.To, prev, f.Currency).
+		Scan(&t.Shipments, &t.Value, &t.PrevValue, &t.Growth)
+	if err != nil {
+		return nil, rateError(err)
+	}
+
+	total, err := r.querySalesSeries(ctx, f, salesTotal, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(total) > 0 {
+		d.Series = total[0].Points
+	}
+
+	dimension := salesDimensionColumns[f.By]
+	if d.Breakdown, err = r.querySalesShares(ctx, f, dimension, 0); err != nil {
+		return nil, err
+	}
+	if d.BreakdownSeries, err = r.querySalesSeries(ctx, f, dimension, f.TopN); err != nil {
+		return nil, err
+	}
+	if d.TopParts, err = r.querySalesShares(ctx, f, salesDimensionColumns[domain.DimensionPart], f.TopN); err != nil {
+		return nil, err
+	}
+	if d.TopCustomers, err = r.querySalesShares(ctx, f, salesDimensionColumns[domain.DimensionCustomer], f.TopN); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// querySalesSeries возвращает временные ряды первых limit значений измерения
+// (0 - всех), по убыванию суммы.
+func (r *Repository) querySalesSeries(ctx context.Context, f domain.DashboardFilter, col salesColumn, limit int) ([]domain.SalesSeries, error) {
+	rows, err := r.db.Query(ctx, salesSeriesQuery(f.Granularity, col), f.From, f.To, limit, f.Currency)
+	if err != nil {
+		return nil, rateError(err)
+	}
+	defer rows.Close()
+
+	series := []domain.SalesSeries{}
+	for rows.Next() {
+		var key, name string
+		var p domain.SalesPoint
+		if err := rows.Scan(&key, &name, &p.Period, &p.Value, &p.ValueGrowth, &p.CumulativeValue); err != nil {
+			return nil, rateError(err)
+		}
+		// Строки упорядочены по рядам, внутри ряда - по периодам
+		if len(series) == 0 || series[len(series)-1].Key != key {
+			series = append(series, domain.SalesSeries{Key: key, Name: name})
+		}
+		last := &series[len(series)-1]
+		last.Points = append(last.Points, p)
+	}
+	return series, rateError(rows.Err())
+}
+
+// querySalesShares возвращает первые limit значений измерения (0 - все) по
+// убыванию суммы.
+func (r *Repository) querySalesShares(ctx context.Context, f domain.DashboardFilter, col salesColumn, limit int) ([]domain.SalesShare, error) {
+	rows, err := r.db.Query(ctx, salesShareQuery(col), f.From, f.To, f.PreviousFrom(), limit, f.Currency)
+	if err != nil {
+		return nil, rateError(err)
+	}
+	shares, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.SalesShare, error) {
+		var s domain.SalesShare
+		err := row.Scan(&s.Rank, &s.Key, &s.Name, &s.Qty, &s.Unit, &s.Value, &s.Share, &s.PrevValue, &s.Growth)
+		return s, err
+	})
+	return shares, rateError(err)
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/student/my-kpfu-db-app/internal/domain"
+	"github.com/student/my-kpfu-db-app/internal/explain"
+	"github.com/student/my-kpfu-db-app/internal/quantifier"
+)
+
+// ============================================================================
+// План выполнения отчетов: EXPLAIN ANALYZE
+// ============================================================================
+
+// errExplain откатывает транзакцию, в которой выполнялся EXPLAIN ANALYZE.
+var errExplain = errors.New("explain")
+
+// explainQuery выполняет запрос под EXPLAIN (ANALYZE, BUFFERS, FORMAT JSON) с
+// теми же параметрами, что и отчет. ANALYZE действительно выполняет запрос,
+// поэтому он идет в транзакции, которая всегда откатывается.
+func (r *Repository) explainQuery(ctx context.Context, report, query string, args ...any) (*domain.Plan, error) {
+	var raw []byte
+	err := r.inTx(ctx, func(tx *Repository) error {
+		if err := tx.db.QueryRow(ctx, "EXPLAIN (ANALYZE, BUFFERS, FORMAT JSON) "+query, args...).Scan(&raw); err != nil {
+			return err
+		}
+		return errExplain
+	})
+	if !errors.Is(err, errExplain) {
+		return nil, rateError(err)
+	}
+
+	plan, err := explain.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	plan.Report, plan.SQL, plan.Args = report, strings.TrimSpace(query), args
+	return plan, nil
+}
+
+// ExplainTask1 - план SQL варианта Задачи 1.
+func (r *Repository) ExplainTask1(ctx context.Context, city string) (*domain.Plan, error) {
+	return r.explainQuery(ctx, "task-1", task1Query, city)
+}
+
+// ExplainTask2 - план Задачи 2 с фильтром f.
+func (r *Repository) ExplainTask2(ctx context.Context, f domain.Task2Filter) (*domain.Plan, error) {
+	query, args := task2Query(f)
+	return r.explainQuery(ctx, "task-2", query, args...)
+}
+
+// ExplainTask3 - план кванторного SQL варианта Задачи 3.
+func (r *Repository) ExplainTask3(ctx context.Context, q domain.Task3Query) (*domain.Plan, error) {
+	query, args := quantifier.SQL(q)
+	return r.explainQuery(ctx, "task-3", query, args...)
+}
+
+// ExplainFullShipmentInfo - план запроса к VIEW.
+func (r *Repository) ExplainFullShipmentInfo(ctx context.Context, status domain.ShipmentStatus, currency string) (*domain.Plan, error) {
+	if err := r.validateCurrency(ctx, currency); err != nil {
+		return nil, err
+	}
+	return r.explainQuery(ctx, "view", fullShipmentInfoQuery, string(status), currency)
+}
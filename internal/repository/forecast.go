@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+	"github.com/student/my-kpfu-db-app/internal/domain"
+	"github.com/student/my-kpfu-db-app/internal/forecast"
+)
+
+// ============================================================================
+// Прогноз спроса по месячной истории отгрузок
+// ============================================================================
+
+// forecastHistoryMonths - глубина истории, по которой строится прогноз
+const forecastHistoryMonths = 60
+
+// GetForecast прогнозирует помесячное количество отгрузок детали на складе
+// (nil - на всех) на q.Horizon месяцев вперед. Деталь обязательна: количества
+// разных деталей в разных единицах не складываются. История - продажи с
+// первого месяца с отгрузками, но не ранее чем за forecastHistoryMonths, до
+// последнего полного месяца; месяцы без продаж - нули.
+func (r *Repository) GetForecast(ctx context.Context, q domain.ForecastQuery) (*domain.Forecast, error) {
+	f := &domain.Forecast{Query: q, History: []domain.ForecastPoint{}, Models: []domain.ForecastModel{}}
+	err := r.db.QueryRow(ctx, "SELECT name, unit FROM parts WHERE part_code = $1", q.PartCode).
+		Scan(&f.PartName, &f.Unit)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("part %q: %w", q.PartCode, ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	to := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	from := to.AddDate(0, -forecastHistoryMonths, 0)
+	rows, err := r.db.Query(ctx, `
+		WITH monthly AS (
+			SELECT date_trunc('month', v.shipment_date::timestamp)::date AS month, SUM(v.base_qty) AS qty
+			FROM v_full_shipment_info v
+			WHERE v.shipment_date >= $1::date AND v.shipment_date < $2::date AND `+salesFilter+`
+			  AND v.part_code = $3::text
+			  AND ($4::int IS NULL OR v.warehouse_no = $4::int)
+			GROUP BY 1
+		)
+		SELECT m.month, COALESCE(monthly.qty, 0)
+		FROM (
+			SELECT generate_series((SELECT MIN(month) FROM monthly)::timestamp, ($2::date - 1)::timestamp,
+			                       interval '1 month')::date AS month
+		) m
+		LEFT JOIN monthly ON monthly.month = m.month
+		ORDER BY m.month`, from, to, q.PartCode, q.WarehouseNo)
+	if err != nil {
+		return nil, err
+	}
+	f.History, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.ForecastPoint, error) {
+		var p domain.ForecastPoint
+		err := row.Scan(&p.Month, &p.Qty)
+		return p, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	series := make([]float64, len(f.History))
+	for i, p := range f.History {
+		series[i] = p.Qty.InexactFloat64()
+	}
+	// Проверочные месяцы - последние месяцы истории, прогноз - следующие за ней
+	holdout := min(q.Holdout, len(f.History))
+	var bestMAPE *decimal.Decimal
+	for _, res := range forecast.Run(series, holdout, q.Horizon) {
+		m := domain.ForecastModel{
+			Method:   res.Method,
+			Params:   res.Params,
+			Holdout:  []domain.ForecastPoint{},
+			Forecast: forecastPoints(res.Forecast, to),
+		}
+		if res.Holdout != nil {
+			m.Holdout = forecastPoints(res.Holdout, f.History[len(f.History)-holdout].Month)
+		}
+		if res.MAPE != nil {
+			mape := decimal.NewFromFloat(*res.MAPE).Round(2)
+			m.MAPE = &mape
+			if bestMAPE == nil || mape.LessThan(*bestMAPE) {
+				bestMAPE, f.Best = &mape, res.Method
+			}
+		}
+		f.Models = append(f.Models, m)
+	}
+	return f, nil
+}
+
+// forecastPoints размечает прогнозные значения месяцами начиная с start.
+func forecastPoints(values []float64, start time.Time) []domain.ForecastPoint {
+	points := make([]domain.ForecastPoint, len(values))
+	for i, v := range values {
+		points[i] = domain.ForecastPoint{Month: start.AddDate(0, i, 0), Qty: decimal.NewFromFloat(v).Round(3)}
+	}
+	return points
+}
@@ -0,0 +1,337 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/student/my-kpfu-db-app/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GormStore implements Store with GORM. Parts, customers and shipments are
+// read and written through the GORM models and scopes; the rules shared with
+// the pgx implementation (currencies, addresses, pricing, credit limit and
+// status history) run in the same transaction, since writes use a connection
+// common to pgx and GORM.
+type GormStore struct {
+	r *Repository
+}
+
+// ============================================================================
+// Scopes GORM
+// ============================================================================
+
+// notDeleted отбирает записи, не перенесенные в корзину.
+func notDeleted(db *gorm.DB) *gorm.DB {
+	return db.Where(clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: "deleted_at"}, Value: nil})
+}
+
+// withStatus отбирает отгрузки в статусе status; пустой статус - все отгрузки.
+func withStatus(status domain.ShipmentStatus) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if status == "" {
+			return db
+		}
+		return db.Where("status = ?", status)
+	}
+}
+
+// shipmentKey отбирает отгрузку по номеру склада и документа.
+func shipmentKey(warehouseNo, shipmentDocNo int) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("warehouse_no = ? AND shipment_doc_no = ?", warehouseNo, shipmentDocNo)
+	}
+}
+
+// shippedToCity присоединяет к отгрузкам их покупателей (только имя) и
+// оставляет отгрузки покупателям города city.
+func shippedToCity(city string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		customers := db.Session(&gorm.Session{NewDB: true}).Select("name").Where(&domain.CustomerGorm{City: city})
+		return db.InnerJoins("Customer", customers)
+	}
+}
+
+// orderBy сортирует по столбцу основной таблицы.
+func orderBy(column string, desc bool) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Order(clause.OrderByColumn{Column: clause.Column{Table: clause.CurrentTable, Name: column}, Desc: desc})
+	}
+}
+
+// ============================================================================
+// CRUD операции для Parts
+// ============================================================================
+
+func (s *GormStore) GetParts(ctx context.Context) ([]domain.Part, error) {
+	var rows []domain.PartGorm
+	if err := s.r.gormDB.WithContext(ctx).Scopes(notDeleted, orderBy("part_code", false)).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	var parts []domain.Part
+	for _, p := range rows {
+		parts = append(parts, p.Part())
+	}
+	return parts, nil
+}
+
+func (s *GormStore) CreatePart(ctx context.Context, p *domain.Part) error {
+	if p.Currency == "" {
+		p.Currency = domain.BaseCurrency
+	}
+	return s.r.pinnedTx(ctx, true, func(tx *Repository) error {
+		if err := tx.validateCurrency(ctx, p.Currency); err != nil {
+			return err
+		}
+
+		db := tx.gormDB.WithContext(ctx)
+		part := domain.NewPartGorm(*p)
+		if err := db.Create(&part).Error; err != nil {
+			return err
+		}
+		// Собственная единица детали всегда допустима для отгрузки
+		return db.Table("part_units").Create(map[string]any{
+			"part_code": p.PartCode, "unit_code": p.Unit, "factor": 1,
+		}).Error
+	})
+}
+
+func (s *GormStore) UpdatePart(ctx context.Context, p *domain.Part) error {
+	if p.Currency == "" {
+		p.Currency = domain.BaseCurrency
+	}
+	return s.r.pinnedTx(ctx, true, func(tx *Repository) error {
+		if err := tx.validateCurrency(ctx, p.Currency); err != nil {
+			return err
+		}
+
+		db := tx.gormDB.WithContext(ctx)
+		var current domain.PartGorm
+		err := db.Clauses(clause.Locking{Strength: "UPDATE"}).Select("unit").
+			Where("part_code = ?", p.PartCode).Take(&current).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("part %s: %w", p.PartCode, ErrNotFound)
+		}
+		if err != nil {
+			return err
+		}
+
+		if current.Unit != p.Unit {
+			// Новая единица становится базовой: пересчитываем коэффициенты допустимых единиц
+			if err := tx.rebasePartUnits(ctx, p.PartCode, p.Unit); err != nil {
+				return err
+			}
+		}
+
+		part := domain.NewPartGorm(*p)
+		return db.Model(&domain.PartGorm{}).Where("part_code = ?", p.PartCode).
+			Select("part_type", "name", "unit", "plan_price", "currency").Updates(&part).Error
+	})
+}
+
+// DeletePart переносит деталь в корзину, как и pgx вариант.
+func (s *GormStore) DeletePart(ctx context.Context, partCode string) error {
+	var affected int64
+	err := s.r.pinnedTx(ctx, true, func(tx *Repository) error {
+		res := tx.gormDB.WithContext(ctx).Model(&domain.PartGorm{}).Scopes(notDeleted).
+			Where("part_code = ?", partCode).Updates(trashed(ctx))
+		affected = res.RowsAffected
+		return res.Error
+	})
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("part %s: %w", partCode, ErrNotFound)
+	}
+	return nil
+}
+
+// trashed - значения полей корзины для переноса записи в корзину.
+func trashed(ctx context.Context) map[string]any {
+	return map[string]any{"deleted_at": gorm.Expr("CURRENT_TIMESTAMP"), "deleted_by": UserFromContext(ctx)}
+}
+
+// ============================================================================
+// CRUD операции для Customers
+// ============================================================================
+
+func (s *GormStore) GetCustomers(ctx context.Context) ([]domain.Customer, error) {
+	var rows []domain.CustomerGorm
+	err := s.r.gormDB.WithContext(ctx).Model(&domain.CustomerGorm{}).
+		Select("customers.*, COALESCE(cities.region, '') AS region").
+		Joins("LEFT JOIN cities ON cities.name = customers.city").
+		Scopes(notDeleted, orderBy("customer_id", false)).
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var customers []domain.Customer
+	for _, c := range rows {
+		customers = append(customers, c.Customer())
+	}
+	return customers, nil
+}
+
+func (s *GormStore) CreateCustomer(ctx context.Context, c *domain.Customer) error {
+	if err := s.prepareCustomer(ctx, c); err != nil {
+		return err
+	}
+
+	return s.r.pinnedTx(ctx, true, func(tx *Repository) error {
+		customer := domain.NewCustomerGorm(*c)
+		if err := tx.gormDB.WithContext(ctx).Create(&customer).Error; err != nil {
+			return err
+		}
+		c.CustomerID = customer.CustomerID
+		return nil
+	})
+}
+
+func (s *GormStore) UpdateCustomer(ctx context.Context, c *domain.Customer) error {
+	if err := s.prepareCustomer(ctx, c); err != nil {
+		return err
+	}
+
+	var affected int64
+	err := s.r.pinnedTx(ctx, true, func(tx *Repository) error {
+		customer := domain.NewCustomerGorm(*c)
+		res := tx.gormDB.WithContext(ctx).Model(&domain.CustomerGorm{}).Where("customer_id = ?", c.CustomerID).
+			Select("name", "address", "city", "postal_code", "street", "building", "currency", "credit_limit").
+			Updates(&customer)
+		affected = res.RowsAffected
+		return res.Error
+	})
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("customer %d: %w", c.CustomerID, ErrNotFound)
+	}
+	return nil
+}
+
+// prepareCustomer проверяет валюту, кредитный лимит и адрес покупателя так же,
+// как pgx вариант.
+func (s *GormStore) prepareCustomer(ctx context.Context, c *domain.Customer) error {
+	if c.Currency == "" {
+		c.Currency = domain.BaseCurrency
+	}
+	if err := s.r.validateCurrency(ctx, c.Currency); err != nil {
+		return err
+	}
+
+	if err := validCreditLimit(c.CreditLimit); err != nil {
+		return err
+	}
+	return s.r.prepareCustomerAddress(ctx, c)
+}
+
+// DeleteCustomer переносит покупателя в корзину, как и pgx вариант.
+func (s *GormStore) DeleteCustomer(ctx context.Context, customerID int) error {
+	var affected int64
+	err := s.r.pinnedTx(ctx, true, func(tx *Repository) error {
+		res := tx.gormDB.WithContext(ctx).Model(&domain.CustomerGorm{}).Scopes(notDeleted).
+			Where("customer_id = ?", customerID).Updates(trashed(ctx))
+		affected = res.RowsAffected
+		return res.Error
+	})
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("customer %d: %w", customerID, ErrNotFound)
+	}
+	return nil
+}
+
+// ============================================================================
+// CRUD операции для Shipments
+// ============================================================================
+
+func (s *GormStore) GetShipments(ctx context.Context, status domain.ShipmentStatus) ([]domain.Shipment, error) {
+	var rows []domain.ShipmentGorm
+	if err := s.r.gormDB.WithContext(ctx).Scopes(withStatus(status), orderBy("shipment_date", true)).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	var shipments []domain.Shipment
+	for _, sh := range rows {
+		shipments = append(shipments, sh.Shipment())
+	}
+	return shipments, nil
+}
+
+// CreateShipment оформляет отгрузку черновиком; цена, кредитный лимит и
+// история статусов - как в pgx варианте.
+func (s *GormStore) CreateShipment(ctx context.Context, sh *domain.Shipment, overrideCredit bool) error {
+	sh.Status = domain.StatusDraft
+
+	return s.r.pinnedTx(ctx, true, func(tx *Repository) error {
+		if err := tx.checkNotArchived(ctx, sh.CustomerID, sh.PartCode); err != nil {
+			return err
+		}
+		if err := tx.priceShipment(ctx, sh); err != nil {
+			return err
+		}
+
+		shipment := domain.NewShipmentGorm(*sh)
+		if err := tx.gormDB.WithContext(ctx).Omit(clause.Associations).Create(&shipment).Error; err != nil {
+			return err
+		}
+		if err := tx.checkCreditLimit(ctx, sh, overrideCredit); err != nil {
+			return err
+		}
+		return tx.recordStatusChange(ctx, sh.WarehouseNo, sh.ShipmentDocNo, "", sh.Status)
+	})
+}
+
+func (s *GormStore) UpdateShipment(ctx context.Context, sh *domain.Shipment, overrideCredit bool) error {
+	return s.r.pinnedTx(ctx, true, func(tx *Repository) error {
+		db := tx.gormDB.WithContext(ctx)
+		key := shipmentKey(sh.WarehouseNo, sh.ShipmentDocNo)
+
+		var current domain.ShipmentGorm
+		err := db.Clauses(clause.Locking{Strength: "UPDATE"}).Scopes(key).Select("status").Take(&current).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("shipment %d/%d: %w", sh.WarehouseNo, sh.ShipmentDocNo, ErrNotFound)
+		}
+		if err != nil {
+			return err
+		}
+		if !current.Status.Editable() {
+			return fmt.Errorf("%w: %s", ErrShipmentLocked, current.Status)
+		}
+		if err := tx.checkNotArchived(ctx, sh.CustomerID, sh.PartCode); err != nil {
+			return err
+		}
+		// Покупатель, количество или дата могли измениться - цена пересчитывается
+		if err := tx.priceShipment(ctx, sh); err != nil {
+			return err
+		}
+
+		shipment := domain.NewShipmentGorm(*sh)
+		err = db.Model(&domain.ShipmentGorm{}).Scopes(key).
+			Select("customer_id", "part_code", "unit", "qty", "price", "shipment_date").
+			Updates(&shipment).Error
+		if err != nil {
+			return err
+		}
+		sh.Status = current.Status
+		return tx.checkCreditLimit(ctx, sh, overrideCredit)
+	})
+}
+
+func (s *GormStore) DeleteShipment(ctx context.Context, warehouseNo, shipmentDocNo int) error {
+	return s.r.pinnedTx(ctx, true, func(tx *Repository) error {
+		if err := tx.lockDeletableShipment(ctx, warehouseNo, shipmentDocNo); err != nil {
+			return err
+		}
+		return tx.gormDB.WithContext(ctx).Scopes(shipmentKey(warehouseNo, shipmentDocNo)).
+			Delete(&domain.ShipmentGorm{}).Error
+	})
+}
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"sort"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/student/my-kpfu-db-app/internal/domain"
+)
+
+// ============================================================================
+// Пробный запуск: последствия операции без сохранения
+// ============================================================================
+
+// DryRun выполняет fn в транзакции, считает измененные строки по таблицам и
+// откатывает транзакцию. Учитываются и строки, измененные триггерами и
+// каскадными внешними ключами: счетчики берутся из pg_stat_xact_user_tables,
+// которая ведет статистику текущей транзакции. Транзакция выполняется в
+// Sandbox, поэтому fn может работать и через GormStore.
+func (r *Repository) DryRun(ctx context.Context, fn func(tx *Repository) error) (*domain.Impact, error) {
+	impact := &domain.Impact{DryRun: true, Tables: []domain.TableImpact{}}
+	err := r.Sandbox(ctx, func(tx *Repository) error {
+		before, err := tx.tableStats(ctx)
+		if err != nil {
+			return err
+		}
+		if err := fn(tx); err != nil {
+			return err
+		}
+		after, err := tx.tableStats(ctx)
+		if err != nil {
+			return err
+		}
+
+		for table, a := range after {
+			b := before[table]
+			t := domain.TableImpact{
+				Table:    table,
+				Inserted: a.Inserted - b.Inserted,
+				Updated:  a.Updated - b.Updated,
+				Deleted:  a.Deleted - b.Deleted,
+			}
+			if t.Inserted != 0 || t.Updated != 0 || t.Deleted != 0 {
+				impact.Tables = append(impact.Tables, t)
+			}
+		}
+		sort.Slice(impact.Tables, func(i, j int) bool { return impact.Tables[i].Table < impact.Tables[j].Table })
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return impact, nil
+}
+
+// tableStats возвращает счетчики строк, измененных в текущей транзакции.
+func (r *Repository) tableStats(ctx context.Context) (map[string]domain.TableImpact, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT relname, n_tup_ins, n_tup_upd, n_tup_del
+		FROM pg_stat_xact_user_tables
+		WHERE schemaname = 'public'`)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.TableImpact, error) {
+		var t domain.TableImpact
+		err := row.Scan(&t.Table, &t.Inserted, &t.Updated, &t.Deleted)
+		return t, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	byTable := make(map[string]domain.TableImpact, len(stats))
+	for _, t := range stats {
+		byTable[t.Table] = t
+	}
+	return byTable, nil
+}
@@ -0,0 +1,226 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+	"github.com/student/my-kpfu-db-app/internal/domain"
+)
+
+// ============================================================================
+// Счета покупателям за период
+// ============================================================================
+
+const invoiceColumns = `i.invoice_id, i.invoice_no, i.customer_id, c.name, c.address, c.city,
+	i.period_from, i.period_to, i.issue_date, i.currency, i.vat_rate, i.net_amount, i.vat_amount, i.total_amount,
+	i.status, i.created_by, i.created_at, i.voided_by, i.voided_at`
+
+// CreateInvoice выставляет счет покупателю по всем отгруженным и еще не
+// выставленным документам за период [from, to]. Количество берется за вычетом
+// возвратов, цены пересчитываются в валюту покупателя по курсу на дату отгрузки.
+// Включенные отгрузки помечаются счетом, поэтому повторно не попадут.
+func (r *Repository) CreateInvoice(ctx context.Context, customerID int, from, to time.Time, vatRate decimal.Decimal) (*domain.Invoice, error) {
+	var invoiceID int64
+	err := r.inTx(ctx, func(tx *Repository) error {
+		var currency string
+		err := tx.db.QueryRow(ctx, "SELECT currency FROM customers WHERE customer_id = $1", customerID).Scan(&currency)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("customer %d: %w", customerID, ErrNotFound)
+		}
+		if err != nil {
+			return err
+		}
+
+		// Блокируем отгрузки периода, чтобы параллельный счет их не захватил
+		rows, err := tx.db.Query(ctx, `
+			SELECT s.warehouse_no, s.shipment_doc_no, s.shipment_date, s.part_code, p.name, s.unit,
+			       n.net_qty,
+			       ROUND(s.price * n.unit_factor
+			             * fn_exchange_rate(p.currency, s.shipment_date)
+			             / fn_exchange_rate(c.currency, s.shipment_date), 2)
+			FROM shipments s
+			JOIN v_shipment_net n ON s.warehouse_no = n.warehouse_no AND s.shipment_doc_no = n.shipment_doc_no
+			JOIN parts p ON s.part_code = p.part_code
+			JOIN customers c ON s.customer_id = c.customer_id
+			WHERE s.customer_id = $1
+			AND s.shipment_date BETWEEN $2 AND $3
+			AND `+shipmentSalesFilter+`
+			AND s.invoice_id IS NULL
+			AND n.net_qty > 0
+			ORDER BY s.shipment_date, s.warehouse_no, s.shipment_doc_no
+			FOR UPDATE OF s`,
+			customerID, from, to)
+		if err != nil {
+			return rateError(err)
+		}
+		lines, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.InvoiceLine, error) {
+			var l domain.InvoiceLine
+			err := row.Scan(&l.WarehouseNo, &l.ShipmentDocNo, &l.ShipmentDate, &l.PartCode, &l.PartName,
+				&l.Unit, &l.Qty, &l.Price)
+			return l, err
+		})
+		if err != nil {
+			return rateError(err)
+		}
+		if len(lines) == 0 {
+			return fmt.Errorf("%w: customer %d, %s - %s", ErrNothingToInvoice,
+				customerID, from.Format("2006-01-02"), to.Format("2006-01-02"))
+		}
+
+		net := decimal.Zero
+		for i := range lines {
+			lines[i].LineNo = i + 1
+			lines[i].Amount = domain.RoundMoney(lines[i].Qty.Mul(lines[i].Price))
+			net = net.Add(lines[i].Amount)
+		}
+		vat := domain.RoundMoney(net.Mul(vatRate).Div(decimal.NewFromInt(100)))
+
+		// Номера счетов идут без пропусков в пределах года
+		issueDate := time.Now()
+		var seq int
+		if err := tx.db.QueryRow(ctx, `
+			INSERT INTO invoice_numbering (year, last_no) VALUES ($1, 1)
+			ON CONFLICT (year) DO UPDATE SET last_no = invoice_numbering.last_no + 1
+			RETURNING last_no`, issueDate.Year()).Scan(&seq); err != nil {
+			return err
+		}
+
+		err = tx.db.QueryRow(ctx, `
+			INSERT INTO invoices (invoice_no, customer_id, period_from, period_to, issue_date, currency,
+			                      vat_rate, net_amount, vat_amount, total_amount, created_by)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+			RETURNING invoice_id`,
+			domain.InvoiceNumber(issueDate.Year(), seq), customerID, from, to, issueDate, currency,
+			vatRate, net, vat, net.Add(vat), UserFromContext(ctx),
+		).Scan(&invoiceID)
+		if err != nil {
+			return err
+		}
+
+		for _, l := range lines {
+			if _, err := tx.db.Exec(ctx, `
+				INSERT INTO invoice_lines (invoice_id, line_no, warehouse_no, shipment_doc_no, shipment_date,
+				                           part_code, part_name, unit, qty, price, amount)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+				invoiceID, l.LineNo, l.WarehouseNo, l.ShipmentDocNo, l.ShipmentDate,
+				l.PartCode, l.PartName, l.Unit, l.Qty, l.Price, l.Amount); err != nil {
+				return err
+			}
+			if _, err := tx.db.Exec(ctx, `
+				UPDATE shipments SET invoice_id = $1
+				WHERE warehouse_no = $2 AND shipment_doc_no = $3`,
+				invoiceID, l.WarehouseNo, l.ShipmentDocNo); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.GetInvoice(ctx, invoiceID)
+}
+
+// GetInvoices возвращает счета без строк; нулевой customerID - все покупатели.
+func (r *Repository) GetInvoices(ctx context.Context, customerID int) ([]domain.Invoice, error) {
+	query := `SELECT ` + invoiceColumns + `
+	          FROM invoices i
+	          JOIN customers c ON i.customer_id = c.customer_id
+	          WHERE $1 = 0 OR i.customer_id = $1
+	          ORDER BY i.issue_date DESC, i.invoice_id DESC`
+	rows, err := r.db.Query(ctx, query, customerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var invoices []domain.Invoice
+	for rows.Next() {
+		inv, err := scanInvoice(rows)
+		if err != nil {
+			return nil, err
+		}
+		invoices = append(invoices, *inv)
+	}
+	return invoices, rows.Err()
+}
+
+// GetInvoice возвращает счет вместе со строками.
+func (r *Repository) GetInvoice(ctx context.Context, invoiceID int64) (*domain.Invoice, error) {
+	query := `SELECT ` + invoiceColumns + `
+	          FROM invoices i
+	          JOIN customers c ON i.customer_id = c.customer_id
+	          WHERE i.invoice_id = $1`
+	inv, err := scanInvoice(r.db.QueryRow(ctx, query, invoiceID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("invoice %d: %w", invoiceID, ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, `
+		SELECT line_no, warehouse_no, shipment_doc_no, shipment_date, part_code, part_name, unit, qty, price, amount
+		FROM invoice_lines
+		WHERE invoice_id = $1
+		ORDER BY line_no`, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var l domain.InvoiceLine
+		if err := rows.Scan(&l.LineNo, &l.WarehouseNo, &l.ShipmentDocNo, &l.ShipmentDate, &l.PartCode,
+			&l.PartName, &l.Unit, &l.Qty, &l.Price, &l.Amount); err != nil {
+			return nil, err
+		}
+		inv.Lines = append(inv.Lines, l)
+	}
+	return inv, rows.Err()
+}
+
+// VoidInvoice аннулирует счет и освобождает его отгрузки для повторного выставления.
+func (r *Repository) VoidInvoice(ctx context.Context, invoiceID int64) (*domain.Invoice, error) {
+	err := r.inTx(ctx, func(tx *Repository) error {
+		var status domain.InvoiceStatus
+		err := tx.db.QueryRow(ctx, `SELECT status FROM invoices WHERE invoice_id = $1 FOR UPDATE`,
+			invoiceID).Scan(&status)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("invoice %d: %w", invoiceID, ErrNotFound)
+		}
+		if err != nil {
+			return err
+		}
+		if status != domain.InvoiceIssued {
+			return fmt.Errorf("%w: invoice is %s", ErrInvalidTransition, status)
+		}
+
+		if _, err := tx.db.Exec(ctx, `
+			UPDATE invoices SET status = 'void', voided_by = $2, voided_at = CURRENT_TIMESTAMP
+			WHERE invoice_id = $1`, invoiceID, UserFromContext(ctx)); err != nil {
+			return err
+		}
+		_, err = tx.db.Exec(ctx, `UPDATE shipments SET invoice_id = NULL WHERE invoice_id = $1`, invoiceID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.GetInvoice(ctx, invoiceID)
+}
+
+func scanInvoice(row pgx.Row) (*domain.Invoice, error) {
+	var inv domain.Invoice
+	err := row.Scan(&inv.InvoiceID, &inv.Number, &inv.CustomerID, &inv.CustomerName, &inv.CustomerAddress,
+		&inv.CustomerCity, &inv.PeriodFrom, &inv.PeriodTo, &inv.IssueDate, &inv.Currency, &inv.VATRate, &inv.NetAmount,
+		&inv.VATAmount, &inv.TotalAmount, &inv.Status, &inv.CreatedBy, &inv.CreatedAt, &inv.VoidedBy, &inv.VoidedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
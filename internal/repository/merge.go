@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/student/my-kpfu-db-app/internal/dedup"
+	"github.com/student/my-kpfu-db-app/internal/domain"
+)
+
+// ============================================================================
+// Поиск и объединение дублей покупателей
+// ============================================================================
+
+// mergedTables - таблицы со ссылкой на покупателя, переносимые на основную запись.
+var mergedTables = []string{
+	"shipments", "invoices", "payments", "price_lists", "discount_rules", "customer_contacts", "credit_overrides",
+}
+
+// FindDuplicateCustomers возвращает пары действующих покупателей, похожих на
+// одну и ту же компанию.
+func (r *Repository) FindDuplicateCustomers(ctx context.Context, threshold float64) ([]domain.DuplicateCandidate, error) {
+	customers, err := r.GetCustomers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return dedup.Find(customers, threshold), nil
+}
+
+// MergeCustomers переносит все ссылки с дубля на основного покупателя и
+// удаляет дубль. Отгрузки переносятся с записью MERGE в журнал аудита;
+// триггер каскадного удаления отгрузок при удалении дубля не срабатывает.
+func (r *Repository) MergeCustomers(ctx context.Context, survivorID, duplicateID int) (*domain.MergeResult, error) {
+	if survivorID == duplicateID {
+		return nil, fmt.Errorf("%w: customer cannot be merged into itself", ErrInvalidMerge)
+	}
+
+	result := &domain.MergeResult{SurvivorID: survivorID, DuplicateID: duplicateID, Moved: map[string]int64{}}
+	err := r.inTx(ctx, func(tx *Repository) error {
+		// Блокируем обе записи в порядке возрастания id, чтобы встречные объединения не взаимоблокировались
+		rows, err := tx.db.Query(ctx, `SELECT customer_id, currency, deleted_at IS NOT NULL FROM customers
+			WHERE customer_id IN ($1, $2) ORDER BY customer_id FOR UPDATE`, survivorID, duplicateID)
+		if err != nil {
+			return err
+		}
+		currencies := map[int]string{}
+		archived := map[int]bool{}
+		for rows.Next() {
+			var id int
+			var currency string
+			var deleted bool
+			if err := rows.Scan(&id, &currency, &deleted); err != nil {
+				rows.Close()
+				return err
+			}
+			currencies[id], archived[id] = currency, deleted
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		for _, id := range []int{survivorID, duplicateID} {
+			if _, ok := currencies[id]; !ok {
+				return fmt.Errorf("customer %d: %w", id, ErrNotFound)
+			}
+		}
+		if archived[survivorID] {
+			return fmt.Errorf("customer %d: %w", survivorID, ErrArchived)
+		}
+		if currencies[survivorID] != currencies[duplicateID] {
+			return fmt.Errorf("%w: customers are billed in %s and %s", ErrInvalidMerge,
+				currencies[survivorID], currencies[duplicateID])
+		}
+
+		// Основной контакт у покупателя один: контакты дубля переходят обычными
+		if _, err := tx.db.Exec(ctx, `UPDATE customer_contacts SET is_primary = false
+			WHERE customer_id = $2 AND is_primary
+			  AND EXISTS (SELECT 1 FROM customer_contacts WHERE customer_id = $1 AND is_primary)`,
+			survivorID, duplicateID); err != nil {
+			return err
+		}
+
+		// Перенос отгрузок фиксируется в журнале аудита с новым покупателем
+		if _, err := tx.db.Exec(ctx, `
+			INSERT INTO shipments_audit (warehouse_no, shipment_doc_no, customer_id, part_code, qty, shipment_date, action)
+			SELECT warehouse_no, shipment_doc_no, $1, part_code, qty, shipment_date, 'MERGE'
+			FROM shipments WHERE customer_id = $2`, survivorID, duplicateID); err != nil {
+			return err
+		}
+
+		for _, table := range mergedTables {
+			query := fmt.Sprintf("UPDATE %s SET customer_id = $1 WHERE customer_id = $2", pgx.Identifier{table}.Sanitize())
+			tag, err := tx.db.Exec(ctx, query, survivorID, duplicateID)
+			if err != nil {
+				return err
+			}
+			result.Moved[table] = tag.RowsAffected()
+		}
+
+		if _, err := tx.db.Exec(ctx, `
+			INSERT INTO customer_merges (survivor_id, duplicate_id, duplicate_image, shipments_moved, merged_by)
+			SELECT $1, c.customer_id, to_jsonb(c), $3, $4 FROM customers c WHERE c.customer_id = $2`,
+			survivorID, duplicateID, result.Moved["shipments"], UserFromContext(ctx)); err != nil {
+			return err
+		}
+
+		// app.merge отключает trg_customers_before_delete (условие WHEN триггера)
+		if _, err := tx.db.Exec(ctx, "SELECT set_config('app.merge', 'on', true)"); err != nil {
+			return err
+		}
+		if _, err := tx.db.Exec(ctx, "DELETE FROM customers WHERE customer_id = $1", duplicateID); err != nil {
+			return err
+		}
+		_, err = tx.db.Exec(ctx, "SELECT set_config('app.merge', 'off', true)")
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,232 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+	"github.com/student/my-kpfu-db-app/internal/domain"
+	"github.com/student/my-kpfu-db-app/internal/pricing"
+)
+
+// ============================================================================
+// Прайс-листы и скидки
+// ============================================================================
+
+// GetPriceLists возвращает цены прайс-листов; customerID = 0 - всех покупателей.
+func (r *Repository) GetPriceLists(ctx context.Context, customerID int) ([]domain.PriceListEntry, error) {
+	query := `SELECT price_list_id, customer_id, part_code, price, valid_from, valid_to FROM price_lists
+	          WHERE $1 = 0 OR customer_id = $1
+	          ORDER BY customer_id, part_code, valid_from DESC`
+	return r.queryPriceLists(ctx, query, customerID)
+}
+
+func (r *Repository) CreatePriceListEntry(ctx context.Context, e *domain.PriceListEntry) error {
+	if e.Price.IsNegative() {
+		return fmt.Errorf("%w: price must not be negative", ErrInvalidPricing)
+	}
+	if err := validPeriod(e.ValidFrom, e.ValidTo); err != nil {
+		return err
+	}
+	query := `INSERT INTO price_lists (customer_id, part_code, price, valid_from, valid_to)
+	          VALUES ($1, $2, $3, $4, $5) RETURNING price_list_id`
+	return r.db.QueryRow(ctx, query, e.CustomerID, e.PartCode, e.Price, e.ValidFrom, e.ValidTo).Scan(&e.PriceListID)
+}
+
+func (r *Repository) DeletePriceListEntry(ctx context.Context, id int64) error {
+	return r.deletePricingRow(ctx, "DELETE FROM price_lists WHERE price_list_id = $1", id)
+}
+
+func (r *Repository) GetDiscountRules(ctx context.Context) ([]domain.DiscountRule, error) {
+	query := `SELECT rule_id, customer_id, city, part_code, percent, valid_from, valid_to FROM discount_rules
+	          ORDER BY customer_id NULLS LAST, city, part_code NULLS LAST, valid_from`
+	return r.queryDiscountRules(ctx, query)
+}
+
+func (r *Repository) CreateDiscountRule(ctx context.Context, d *domain.DiscountRule) error {
+	if (d.CustomerID == nil) == (d.City == nil) {
+		return fmt.Errorf("%w: exactly one of customer_id and city must be set", ErrInvalidPricing)
+	}
+	if err := validPercent(d.Percent); err != nil {
+		return err
+	}
+	if err := validPeriod(d.ValidFrom, d.ValidTo); err != nil {
+		return err
+	}
+	// Скидка города сравнивается с городом покупателя, поэтому хранится название из справочника
+	if d.City != nil {
+		city, err := r.resolveCity(ctx, *d.City)
+		if err != nil {
+			return err
+		}
+		d.City = &city.Name
+	}
+	query := `INSERT INTO discount_rules (customer_id, city, part_code, percent, valid_from, valid_to)
+	          VALUES ($1, $2, $3, $4, $5, $6) RETURNING rule_id`
+	return r.db.QueryRow(ctx, query, d.CustomerID, d.City, d.PartCode, d.Percent,
+		d.ValidFrom, d.ValidTo).Scan(&d.RuleID)
+}
+
+func (r *Repository) DeleteDiscountRule(ctx context.Context, id int64) error {
+	return r.deletePricingRow(ctx, "DELETE FROM discount_rules WHERE rule_id = $1", id)
+}
+
+func (r *Repository) GetVolumeDiscounts(ctx context.Context) ([]domain.VolumeDiscount, error) {
+	query := `SELECT tier_id, part_code, min_qty, percent FROM volume_discounts
+	          ORDER BY part_code NULLS LAST, min_qty`
+	return r.queryVolumeDiscounts(ctx, query)
+}
+
+func (r *Repository) CreateVolumeDiscount(ctx context.Context, v *domain.VolumeDiscount) error {
+	if !v.MinQty.IsPositive() {
+		return fmt.Errorf("%w: min_qty must be positive", ErrInvalidPricing)
+	}
+	if err := validPercent(v.Percent); err != nil {
+		return err
+	}
+	query := `INSERT INTO volume_discounts (part_code, min_qty, percent) VALUES ($1, $2, $3)
+	          ON CONFLICT (part_code, min_qty) DO UPDATE SET percent = EXCLUDED.percent
+	          RETURNING tier_id`
+	return r.db.QueryRow(ctx, query, v.PartCode, v.MinQty, v.Percent).Scan(&v.TierID)
+}
+
+func (r *Repository) DeleteVolumeDiscount(ctx context.Context, id int64) error {
+	return r.deletePricingRow(ctx, "DELETE FROM volume_discounts WHERE tier_id = $1", id)
+}
+
+// QuotePrice рассчитывает действующую цену детали для покупателя на дату.
+// qty указывается в единице unit; пустая единица - единица детали.
+func (r *Repository) QuotePrice(ctx context.Context, customerID int, partCode, unit string, qty decimal.Decimal, date time.Time) (*domain.PriceQuote, error) {
+	req := pricing.Request{Date: date}
+
+	err := r.db.QueryRow(ctx, "SELECT customer_id, name, address, city, currency FROM customers WHERE customer_id = $1",
+		customerID).Scan(&req.Customer.CustomerID, &req.Customer.Name, &req.Customer.Address,
+		&req.Customer.City, &req.Customer.Currency)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("customer %d: %w", customerID, ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.db.QueryRow(ctx, "SELECT part_code, part_type, name, unit, plan_price, currency FROM parts WHERE part_code = $1",
+		partCode).Scan(&req.Part.PartCode, &req.Part.PartType, &req.Part.Name, &req.Part.Unit,
+		&req.Part.PlanPrice, &req.Part.Currency)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("part %s: %w", partCode, ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if unit == "" {
+		unit = req.Part.Unit
+	}
+	factor, err := r.shipmentUnitFactor(ctx, partCode, unit)
+	if err != nil {
+		return nil, err
+	}
+	req.Qty = qty.Mul(factor)
+
+	// Загружаем все правила покупателя и детали, отбор по дате и количеству делает движок
+	if req.PriceList, err = r.queryPriceLists(ctx, `
+		SELECT price_list_id, customer_id, part_code, price, valid_from, valid_to FROM price_lists
+		WHERE customer_id = $1 AND part_code = $2`, customerID, partCode); err != nil {
+		return nil, err
+	}
+	if req.Discounts, err = r.queryDiscountRules(ctx, `
+		SELECT rule_id, customer_id, city, part_code, percent, valid_from, valid_to FROM discount_rules
+		WHERE (customer_id = $1 OR city = $2) AND (part_code IS NULL OR part_code = $3)`,
+		customerID, req.Customer.City, partCode); err != nil {
+		return nil, err
+	}
+	if req.Volume, err = r.queryVolumeDiscounts(ctx, `
+		SELECT tier_id, part_code, min_qty, percent FROM volume_discounts
+		WHERE part_code IS NULL OR part_code = $1`, partCode); err != nil {
+		return nil, err
+	}
+
+	quote := pricing.Resolve(req)
+	quote.Qty = qty
+	quote.Unit = unit
+	quote.UnitPrice = domain.RoundMoney(quote.EffectivePrice.Mul(factor))
+	quote.Amount = domain.RoundMoney(quote.EffectivePrice.Mul(quote.BaseQty))
+	return &quote, nil
+}
+
+// priceShipment фиксирует в отгрузке цену, действующую на дату отгрузки.
+func (r *Repository) priceShipment(ctx context.Context, s *domain.Shipment) error {
+	quote, err := r.QuotePrice(ctx, s.CustomerID, s.PartCode, s.Unit, s.Qty, s.ShipmentDate)
+	if err != nil {
+		return err
+	}
+	s.Price = quote.EffectivePrice
+	return nil
+}
+
+func (r *Repository) queryPriceLists(ctx context.Context, query string, args ...any) ([]domain.PriceListEntry, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.PriceListEntry, error) {
+		var e domain.PriceListEntry
+		err := row.Scan(&e.PriceListID, &e.CustomerID, &e.PartCode, &e.Price, &e.ValidFrom, &e.ValidTo)
+		return e, err
+	})
+}
+
+func (r *Repository) queryDiscountRules(ctx context.Context, query string, args ...any) ([]domain.DiscountRule, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.DiscountRule, error) {
+		var d domain.DiscountRule
+		err := row.Scan(&d.RuleID, &d.CustomerID, &d.City, &d.PartCode, &d.Percent, &d.ValidFrom, &d.ValidTo)
+		return d, err
+	})
+}
+
+func (r *Repository) queryVolumeDiscounts(ctx context.Context, query string, args ...any) ([]domain.VolumeDiscount, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.VolumeDiscount, error) {
+		var v domain.VolumeDiscount
+		err := row.Scan(&v.TierID, &v.PartCode, &v.MinQty, &v.Percent)
+		return v, err
+	})
+}
+
+func (r *Repository) deletePricingRow(ctx context.Context, query string, id int64) error {
+	tag, err := r.db.Exec(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("pricing rule %d: %w", id, ErrNotFound)
+	}
+	return nil
+}
+
+func validPercent(p decimal.Decimal) error {
+	if !p.IsPositive() || p.GreaterThanOrEqual(decimal.NewFromInt(100)) {
+		return fmt.Errorf("%w: percent must be between 0 and 100", ErrInvalidPricing)
+	}
+	return nil
+}
+
+func validPeriod(from time.Time, to *time.Time) error {
+	if from.IsZero() {
+		return fmt.Errorf("%w: valid_from is required", ErrInvalidPricing)
+	}
+	if to != nil && to.Before(from) {
+		return fmt.Errorf("%w: valid_to is before valid_from", ErrInvalidPricing)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/student/my-kpfu-db-app/internal/domain"
+)
+
+// ============================================================================
+// Переименование кода детали
+// ============================================================================
+
+// partCodeReferences - таблицы, ссылающиеся на parts.part_code внешним ключом
+// ON UPDATE CASCADE; после переименования в них не должно остаться старого кода.
+var partCodeReferences = []string{"shipments", "part_units", "price_lists", "discount_rules", "volume_discounts"}
+
+// RenamePart меняет код детали. Ссылки с внешним ключом обновляет каскад,
+// результат проверяется; журнал аудита, не связанный ключом, обновляется
+// явно. Выставленные счета сохраняют код, напечатанный в документе.
+func (r *Repository) RenamePart(ctx context.Context, oldCode, newCode string) (*domain.PartRename, error) {
+	newCode = strings.TrimSpace(newCode)
+	if newCode == "" {
+		return nil, fmt.Errorf("%w: new code is required", ErrInvalidPartCode)
+	}
+	if newCode == oldCode {
+		return nil, fmt.Errorf("%w: new code equals the current one", ErrInvalidPartCode)
+	}
+
+	rename := &domain.PartRename{OldCode: oldCode, NewCode: newCode, RenamedBy: UserFromContext(ctx)}
+	err := r.inTx(ctx, func(tx *Repository) error {
+		var exists bool
+		err := tx.db.QueryRow(ctx, "SELECT true FROM parts WHERE part_code = $1 FOR UPDATE", oldCode).Scan(&exists)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("part %s: %w", oldCode, ErrNotFound)
+		}
+		if err != nil {
+			return err
+		}
+		if err := tx.db.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM parts WHERE part_code = $1)",
+			newCode).Scan(&exists); err != nil {
+			return err
+		}
+		if exists {
+			return fmt.Errorf("%w: %s", ErrPartCodeTaken, newCode)
+		}
+
+		if err := tx.db.QueryRow(ctx, "SELECT COUNT(*) FROM shipments WHERE part_code = $1",
+			oldCode).Scan(&rename.Shipments); err != nil {
+			return err
+		}
+
+		_, err = tx.db.Exec(ctx, "UPDATE parts SET part_code = $2 WHERE part_code = $1", oldCode, newCode)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return fmt.Errorf("%w: %s", ErrPartCodeTaken, newCode)
+		}
+		if err != nil {
+			return err
+		}
+
+		// Проверяем каскад: старый код не должен остаться ни в одной ссылающейся таблице,
+		// а все отгрузки детали - перейти на новый код
+		for _, table := range partCodeReferences {
+			var left int64
+			query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE part_code = $1", pgx.Identifier{table}.Sanitize())
+			if err := tx.db.QueryRow(ctx, query, oldCode).Scan(&left); err != nil {
+				return err
+			}
+			if left > 0 {
+				return fmt.Errorf("rename %s: %d rows in %s still reference the old code", oldCode, left, table)
+			}
+		}
+		var moved int64
+		if err := tx.db.QueryRow(ctx, "SELECT COUNT(*) FROM shipments WHERE part_code = $1",
+			newCode).Scan(&moved); err != nil {
+			return err
+		}
+		if moved != rename.Shipments {
+			return fmt.Errorf("rename %s: %d of %d shipments moved to %s", oldCode, moved, rename.Shipments, newCode)
+		}
+
+		tag, err := tx.db.Exec(ctx, "UPDATE shipments_audit SET part_code = $2 WHERE part_code = $1", oldCode, newCode)
+		if err != nil {
+			return err
+		}
+		rename.AuditRows = tag.RowsAffected()
+
+		return tx.db.QueryRow(ctx, `INSERT INTO part_renames (old_code, new_code, renamed_by)
+			VALUES ($1, $2, $3) RETURNING renamed_at`, oldCode, newCode, rename.RenamedBy).Scan(&rename.RenamedAt)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rename, nil
+}
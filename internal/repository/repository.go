@@ -2,17 +2,61 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/student/my-kpfu-db-app/internal/domain"
+	"github.com/student/my-kpfu-db-app/internal/quantifier"
 	"gorm.io/gorm"
 )
 
+// Errors returned by repository methods; handlers map them to HTTP statuses.
+var (
+	ErrNotFound           = errors.New("record not found")
+	ErrInvalidTransition  = errors.New("status transition is not allowed")
+	ErrShipmentLocked     = errors.New("shipment can no longer be edited in its current status")
+	ErrNotReturnable      = errors.New("shipment cannot be returned")
+	ErrReturnQty          = errors.New("invalid return quantity")
+	ErrReturnDate         = errors.New("invalid return date")
+	ErrNothingToInvoice   = errors.New("no uninvoiced shipments in the period")
+	ErrUnitNotAllowed     = errors.New("unit of measure is not allowed")
+	ErrUnknownCurrency    = errors.New("unknown currency")
+	ErrNoExchangeRate     = errors.New("no exchange rate")
+	ErrInvalidPricing     = errors.New("invalid pricing rule")
+	ErrCreditLimit        = errors.New("credit limit exceeded")
+	ErrInvalidPayment     = errors.New("invalid payment")
+	ErrInvalidCreditLimit = errors.New("invalid credit limit")
+	ErrUnknownCity        = errors.New("unknown city")
+	ErrInvalidAddress     = errors.New("invalid address")
+	ErrInvalidContact     = errors.New("invalid contact")
+	ErrArchived           = errors.New("record is in the trash")
+	ErrNotArchived        = errors.New("record is not in the trash")
+	ErrPurgeBlocked       = errors.New("record cannot be purged")
+	ErrUndoConflict       = errors.New("change cannot be undone")
+	ErrInvalidMerge       = errors.New("customers cannot be merged")
+	ErrPartCodeTaken      = errors.New("part code is already in use")
+	ErrInvalidPartCode    = errors.New("invalid part code")
+	ErrInvalidPeriod      = errors.New("invalid period")
+)
+
+// dbtx is implemented by both *pgxpool.Pool and pgx.Tx, so the same
+// repository methods can run on the pool or inside a transaction.
+type dbtx interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
 // Repository holds the database connection pool and GORM connection.
+// pinned means that db is a transaction on the connection gormDB is bound to.
 type Repository struct {
-	db     *pgxpool.Pool
+	db     dbtx
 	gormDB *gorm.DB
+	pinned bool
 }
 
 // New creates a new Repository with pgx and GORM connections.
@@ -20,12 +64,70 @@ func New(db *pgxpool.Pool, gormDB *gorm.DB) *Repository {
 	return &Repository{db: db, gormDB: gormDB}
 }
 
+// inTx runs fn against a copy of the repository bound to a transaction.
+// Nested calls become savepoints, so methods using inTx can be composed.
+// The outermost transaction passes the operator from the context to the
+// change log trigger through the app.user setting.
+func (r *Repository) inTx(ctx context.Context, fn func(tx *Repository) error) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, nested := r.db.(pgx.Tx); !nested {
+		if _, err := tx.Exec(ctx, "SELECT set_config('app.user', $1, true)", UserFromContext(ctx)); err != nil {
+			return err
+		}
+	}
+
+	if err := fn(&Repository{db: tx, gormDB: r.gormDB, pinned: r.pinned}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// execTx runs a single statement in its own transaction, so that changes it
+// makes are logged with the operator.
+func (r *Repository) execTx(ctx context.Context, query string, args ...any) (pgconn.CommandTag, error) {
+	var tag pgconn.CommandTag
+	err := r.inTx(ctx, func(tx *Repository) error {
+		var err error
+		tag, err = tx.db.Exec(ctx, query, args...)
+		return err
+	})
+	return tag, err
+}
+
+type userKey struct{}
+
+// WithUser returns a context carrying the name of the operator performing
+// the request; it is recorded by write operations that keep history.
+func WithUser(ctx context.Context, user string) context.Context {
+	return context.WithValue(ctx, userKey{}, user)
+}
+
+// UserFromContext returns the operator stored by WithUser or "system".
+func UserFromContext(ctx context.Context) string {
+	if user, ok := ctx.Value(userKey{}).(string); ok && user != "" {
+		return user
+	}
+	return "system"
+}
+
 // ============================================================================
 // CRUD операции для Parts
 // ============================================================================
 
+// GetParts возвращает действующие детали, без удаленных в корзину.
 func (r *Repository) GetParts(ctx context.Context) ([]domain.Part, error) {
-	query := "SELECT part_code, part_type, name, unit, plan_price FROM parts ORDER BY part_code"
+	return r.queryParts(ctx, "deleted_at IS NULL")
+}
+
+// queryParts возвращает детали, отобранные условием filter.
+func (r *Repository) queryParts(ctx context.Context, filter string) ([]domain.Part, error) {
+	query := `SELECT part_code, part_type, name, unit, plan_price, currency, deleted_at, COALESCE(deleted_by, '') 
+	          FROM parts WHERE ` + filter + ` ORDER BY part_code`
 	rows, err := r.db.Query(ctx, query)
 	if err != nil {
 		return nil, err
@@ -35,7 +137,8 @@ func (r *Repository) GetParts(ctx context.Context) ([]domain.Part, error) {
 	var parts []domain.Part
 	for rows.Next() {
 		var p domain.Part
-		if err := rows.Scan(&p.PartCode, &p.PartType, &p.Name, &p.Unit, &p.PlanPrice); err != nil {
+		if err := rows.Scan(&p.PartCode, &p.PartType, &p.Name, &p.Unit, &p.PlanPrice, &p.Currency, 
+			&p.DeletedAt, &p.DeletedBy); err != nil {
 			return nil, err
 		}
 		parts = append(parts, p)
@@ -44,32 +147,91 @@ func (r *Repository) GetParts(ctx context.Context) ([]domain.Part, error) {
 }
 
 func (r *Repository) CreatePart(ctx context.Context, p *domain.Part) error {
-	query := `INSERT INTO parts (part_code, part_type, name, unit, plan_price) 
-	          VALUES ($1, $2, $3, $4, $5)`
-	_, err := r.db.Exec(ctx, query, p.PartCode, p.PartType, p.Name, p.Unit, p.PlanPrice)
-	return err
+	if p.Currency == "" {
+		p.Currency = domain.BaseCurrency
+	}
+	return r.inTx(ctx, func(tx *Repository) error {
+		if err := tx.validateCurrency(ctx, p.Currency); err != nil {
+			return err
+		}
+
+		query := `INSERT INTO parts (part_code, part_type, name, unit, plan_price, currency) 
+		          VALUES ($1, $2, $3, $4, $5, $6)`
+		if _, err := tx.db.Exec(ctx, query, p.PartCode, p.PartType, p.Name, p.Unit, p.PlanPrice, p.Currency); err != nil {
+			return err
+		}
+		// Собственная единица детали всегда допустима для отгрузки
+		_, err := tx.db.Exec(ctx, "INSERT INTO part_units (part_code, unit_code, factor) VALUES ($1, $2, 1)",
+			p.PartCode, p.Unit)
+		return err
+	})
 }
 
 func (r *Repository) UpdatePart(ctx context.Context, p *domain.Part) error {
-	query := `UPDATE parts SET part_type = $2, name = $3, unit = $4, plan_price = $5 
-	          WHERE part_code = $1`
-	_, err := r.db.Exec(ctx, query, p.PartCode, p.PartType, p.Name, p.Unit, p.PlanPrice)
-	return err
+	if p.Currency == "" {
+		p.Currency = domain.BaseCurrency
+	}
+	return r.inTx(ctx, func(tx *Repository) error {
+		if err := tx.validateCurrency(ctx, p.Currency); err != nil {
+			return err
+		}
+
+		var oldUnit string
+		err := tx.db.QueryRow(ctx, "SELECT unit FROM parts WHERE part_code = $1 FOR UPDATE", p.PartCode).Scan(&oldUnit)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("part %s: %w", p.PartCode, ErrNotFound)
+		}
+		if err != nil {
+			return err
+		}
+
+		if oldUnit != p.Unit {
+			// Новая единица становится базовой: пересчитываем коэффициенты допустимых единиц
+			if err := tx.rebasePartUnits(ctx, p.PartCode, p.Unit); err != nil {
+				return err
+			}
+		}
+
+		query := `UPDATE parts SET part_type = $2, name = $3, unit = $4, plan_price = $5, currency = $6 
+		          WHERE part_code = $1`
+		_, err = tx.db.Exec(ctx, query, p.PartCode, p.PartType, p.Name, p.Unit, p.PlanPrice, p.Currency)
+		return err
+	})
 }
 
+// DeletePart переносит деталь в корзину. Отгрузки детали сохраняются;
+// окончательно деталь удаляет только PurgePart.
 func (r *Repository) DeletePart(ctx context.Context, partCode string) error {
-	query := "DELETE FROM parts WHERE part_code = $1"
-	_, err := r.db.Exec(ctx, query, partCode)
-	return err
+	query := "UPDATE parts SET deleted_at = CURRENT_TIMESTAMP, deleted_by = $2 WHERE part_code = $1 AND deleted_at IS NULL"
+	tag, err := r.execTx(ctx, query, partCode, UserFromContext(ctx))
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("part %s: %w", partCode, ErrNotFound)
+	}
+	return nil
 }
 
 // ============================================================================
 // CRUD операции для Customers
 // ============================================================================
 
+// GetCustomers возвращает действующих покупателей, без удаленных в корзину.
 func (r *Repository) GetCustomers(ctx context.Context) ([]domain.Customer, error) {
-	query := "SELECT customer_id, name, address, city FROM customers ORDER BY customer_id"
-	rows, err := r.db.Query(ctx, query)
+	return r.queryCustomers(ctx, "c.deleted_at IS NULL")
+}
+
+// queryCustomers возвращает покупателей, отобранных условием filter с параметрами args.
+func (r *Repository) queryCustomers(ctx context.Context, filter string, args ...any) ([]domain.Customer, error) {
+	query := `SELECT c.customer_id, c.name, c.address, c.city, COALESCE(ct.region, ''), COALESCE(c.postal_code, ''),
+	                 COALESCE(c.street, ''), COALESCE(c.building, ''), c.currency, c.credit_limit, 
+	                 c.deleted_at, COALESCE(c.deleted_by, '')
+	          FROM customers c
+	          LEFT JOIN cities ct ON ct.name = c.city
+	          WHERE ` + filter + `
+	          ORDER BY c.customer_id`
+	rows, err := r.db.Query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -78,7 +240,8 @@ func (r *Repository) GetCustomers(ctx context.Context) ([]domain.Customer, error
 	var customers []domain.Customer
 	for rows.Next() {
 		var c domain.Customer
-		if err := rows.Scan(&c.CustomerID, &c.Name, &c.Address, &c.City); err != nil {
+		if err := rows.Scan(&c.CustomerID, &c.Name, &c.Address, &c.City, &c.Region, &c.PostalCode,
+			&c.Street, &c.Building, &c.Currency, &c.CreditLimit, &c.DeletedAt, &c.DeletedBy); err != nil {
 			return nil, err
 		}
 		customers = append(customers, c)
@@ -87,32 +250,82 @@ func (r *Repository) GetCustomers(ctx context.Context) ([]domain.Customer, error
 }
 
 func (r *Repository) CreateCustomer(ctx context.Context, c *domain.Customer) error {
-	query := `INSERT INTO customers (name, address, city) 
-	          VALUES ($1, $2, $3) RETURNING customer_id`
-	return r.db.QueryRow(ctx, query, c.Name, c.Address, c.City).Scan(&c.CustomerID)
+	if c.Currency == "" {
+		c.Currency = domain.BaseCurrency
+	}
+	if err := r.validateCurrency(ctx, c.Currency); err != nil {
+		return err
+	}
+
+	if err := validCreditLimit(c.CreditLimit); err != nil {
+		return err
+	}
+	if err := r.prepareCustomerAddress(ctx, c); err != nil {
+		return err
+	}
+
+	query := `INSERT INTO customers (name, address, city, postal_code, street, building, currency, credit_limit) 
+	          VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''), $7, $8) RETURNING customer_id`
+	return r.inTx(ctx, func(tx *Repository) error {
+		return tx.db.QueryRow(ctx, query, c.Name, c.Address, c.City, c.PostalCode, c.Street, c.Building, 
+			c.Currency, c.CreditLimit).Scan(&c.CustomerID)
+	})
 }
 
 func (r *Repository) UpdateCustomer(ctx context.Context, c *domain.Customer) error {
-	query := `UPDATE customers SET name = $2, address = $3, city = $4 
+	if c.Currency == "" {
+		c.Currency = domain.BaseCurrency
+	}
+	if err := r.validateCurrency(ctx, c.Currency); err != nil {
+		return err
+	}
+
+	if err := validCreditLimit(c.CreditLimit); err != nil {
+		return err
+	}
+	if err := r.prepareCustomerAddress(ctx, c); err != nil {
+		return err
+	}
+
+	query := `UPDATE customers SET name = $2, address = $3, city = $4, postal_code = NULLIF($5, ''), 
+	                 street = NULLIF($6, ''), building = NULLIF($7, ''), currency = $8, credit_limit = $9 
 	          WHERE customer_id = $1`
-	_, err := r.db.Exec(ctx, query, c.CustomerID, c.Name, c.Address, c.City)
-	return err
+	tag, err := r.execTx(ctx, query, c.CustomerID, c.Name, c.Address, c.City, c.PostalCode, c.Street, c.Building, 
+		c.Currency, c.CreditLimit)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("customer %d: %w", c.CustomerID, ErrNotFound)
+	}
+	return nil
 }
 
+// DeleteCustomer переносит покупателя в корзину. Отгрузки покупателя
+// сохраняются; окончательно покупателя удаляет только PurgeCustomer.
 func (r *Repository) DeleteCustomer(ctx context.Context, customerID int) error {
-	query := "DELETE FROM customers WHERE customer_id = $1"
-	_, err := r.db.Exec(ctx, query, customerID)
-	return err
+	query := "UPDATE customers SET deleted_at = CURRENT_TIMESTAMP, deleted_by = $2 WHERE customer_id = $1 AND deleted_at IS NULL"
+	tag, err := r.execTx(ctx, query, customerID, UserFromContext(ctx))
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("customer %d: %w", customerID, ErrNotFound)
+	}
+	return nil
 }
 
 // ============================================================================
 // CRUD операции для Shipments
 // ============================================================================
 
-func (r *Repository) GetShipments(ctx context.Context) ([]domain.Shipment, error) {
-	query := `SELECT warehouse_no, shipment_doc_no, customer_id, part_code, unit, qty, shipment_date 
-	          FROM shipments ORDER BY shipment_date DESC`
-	rows, err := r.db.Query(ctx, query)
+func (r *Repository) GetShipments(ctx context.Context, status domain.ShipmentStatus) ([]domain.Shipment, error) {
+	// Пустой статус означает «все отгрузки»
+	query := `SELECT warehouse_no, shipment_doc_no, customer_id, part_code, unit, qty, price, shipment_date, status 
+	          FROM shipments
+	          WHERE $1 = '' OR status = $1
+	          ORDER BY shipment_date DESC`
+	rows, err := r.db.Query(ctx, query, string(status))
 	if err != nil {
 		return nil, err
 	}
@@ -122,7 +335,7 @@ func (r *Repository) GetShipments(ctx context.Context) ([]domain.Shipment, error
 	for rows.Next() {
 		var s domain.Shipment
 		if err := rows.Scan(&s.WarehouseNo, &s.ShipmentDocNo, &s.CustomerID, &s.PartCode, 
-			&s.Unit, &s.Qty, &s.ShipmentDate); err != nil {
+			&s.Unit, &s.Qty, &s.Price, &s.ShipmentDate, &s.Status); err != nil {
 			return nil, err
 		}
 		shipments = append(shipments, s)
@@ -130,38 +343,120 @@ func (r *Repository) GetShipments(ctx context.Context) ([]domain.Shipment, error
 	return shipments, nil
 }
 
-func (r *Repository) CreateShipment(ctx context.Context, s *domain.Shipment) error {
-	query := `INSERT INTO shipments (warehouse_no, shipment_doc_no, customer_id, part_code, unit, qty, shipment_date) 
-	          VALUES ($1, $2, $3, $4, $5, $6, $7)`
-	_, err := r.db.Exec(ctx, query, s.WarehouseNo, s.ShipmentDocNo, s.CustomerID, 
-		s.PartCode, s.Unit, s.Qty, s.ShipmentDate)
-	return err
+// CreateShipment оформляет отгрузку. Если она выводит задолженность покупателя
+// за кредитный лимит, возвращается *CreditLimitError, а с overrideCredit
+// отгрузка проходит и превышение записывается в журнал.
+func (r *Repository) CreateShipment(ctx context.Context, s *domain.Shipment, overrideCredit bool) error {
+	// Новый документ всегда создается черновиком, дальше статус меняется только переходами
+	s.Status = domain.StatusDraft
+
+	return r.inTx(ctx, func(tx *Repository) error {
+		if err := tx.checkNotArchived(ctx, s.CustomerID, s.PartCode); err != nil {
+			return err
+		}
+		// Цена фиксируется по прайс-листам и скидкам, действующим на дату отгрузки
+		if err := tx.priceShipment(ctx, s); err != nil {
+			return err
+		}
+
+		query := `INSERT INTO shipments (warehouse_no, shipment_doc_no, customer_id, part_code, unit, qty, price, shipment_date, status) 
+		          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+		if _, err := tx.db.Exec(ctx, query, s.WarehouseNo, s.ShipmentDocNo, s.CustomerID, 
+			s.PartCode, s.Unit, s.Qty, s.Price, s.ShipmentDate, s.Status); err != nil {
+			return err
+		}
+		if err := tx.checkCreditLimit(ctx, s, overrideCredit); err != nil {
+			return err
+		}
+		return tx.recordStatusChange(ctx, s.WarehouseNo, s.ShipmentDocNo, "", s.Status)
+	})
 }
 
-func (r *Repository) UpdateShipment(ctx context.Context, s *domain.Shipment) error {
-	query := `UPDATE shipments SET customer_id = $3, part_code = $4, unit = $5, qty = $6, shipment_date = $7 
-	          WHERE warehouse_no = $1 AND shipment_doc_no = $2`
-	_, err := r.db.Exec(ctx, query, s.WarehouseNo, s.ShipmentDocNo, s.CustomerID, 
-		s.PartCode, s.Unit, s.Qty, s.ShipmentDate)
-	return err
+// UpdateShipment изменяет черновик или подтвержденную отгрузку. Кредитный
+// лимит проверяется, как при создании: новое количество, цена или покупатель
+// могут вывести задолженность за лимит.
+func (r *Repository) UpdateShipment(ctx context.Context, s *domain.Shipment, overrideCredit bool) error {
+	return r.inTx(ctx, func(tx *Repository) error {
+		status, err := tx.lockShipmentStatus(ctx, s.WarehouseNo, s.ShipmentDocNo)
+		if err != nil {
+			return err
+		}
+		if !status.Editable() {
+			return fmt.Errorf("%w: %s", ErrShipmentLocked, status)
+		}
+		if err := tx.checkNotArchived(ctx, s.CustomerID, s.PartCode); err != nil {
+			return err
+		}
+		// Покупатель, количество или дата могли измениться - цена пересчитывается
+		if err := tx.priceShipment(ctx, s); err != nil {
+			return err
+		}
+
+		query := `UPDATE shipments SET customer_id = $3, part_code = $4, unit = $5, qty = $6, price = $7, shipment_date = $8 
+		          WHERE warehouse_no = $1 AND shipment_doc_no = $2`
+		if _, err := tx.db.Exec(ctx, query, s.WarehouseNo, s.ShipmentDocNo, s.CustomerID, 
+			s.PartCode, s.Unit, s.Qty, s.Price, s.ShipmentDate); err != nil {
+			return err
+		}
+		s.Status = status
+		return tx.checkCreditLimit(ctx, s, overrideCredit)
+	})
 }
 
+// DeleteShipment удаляет черновик или отмененную отгрузку, по которой не
+// выставлен счет. Остальные документы удалять нельзя: вместе с ними пропали бы
+// возвраты, история статусов и превышения кредитного лимита.
 func (r *Repository) DeleteShipment(ctx context.Context, warehouseNo, shipmentDocNo int) error {
-	query := "DELETE FROM shipments WHERE warehouse_no = $1 AND shipment_doc_no = $2"
-	_, err := r.db.Exec(ctx, query, warehouseNo, shipmentDocNo)
-	return err
+	return r.inTx(ctx, func(tx *Repository) error {
+		if err := tx.lockDeletableShipment(ctx, warehouseNo, shipmentDocNo); err != nil {
+			return err
+		}
+		query := "DELETE FROM shipments WHERE warehouse_no = $1 AND shipment_doc_no = $2"
+		_, err := tx.db.Exec(ctx, query, warehouseNo, shipmentDocNo)
+		return err
+	})
 }
 
 // ============================================================================
 // VIEW - Получение полной информации об отгрузках
 // ============================================================================
 
-func (r *Repository) GetFullShipmentInfo(ctx context.Context) ([]domain.FullShipmentInfo, error) {
-	query := "SELECT * FROM v_full_shipment_info ORDER BY shipment_date DESC"
-	rows, err := r.db.Query(ctx, query)
+// fullShipmentInfoQuery - VIEW с фильтром по статусу $1 и итогом в валюте $2
+const fullShipmentInfoQuery = `SELECT v.*, $2::text, ROUND(v.total_price_rub / fn_exchange_rate($2, v.shipment_date), 2)
+	          FROM v_full_shipment_info v
+	          WHERE $1 = '' OR v.status = $1
+	          ORDER BY v.shipment_date DESC`
+
+// GetFullShipmentInfo возвращает строки VIEW с суммой, пересчитанной в валюту
+// отчета по курсу на дату отгрузки.
+func (r *Repository) GetFullShipmentInfo(ctx context.Context, status domain.ShipmentStatus, currency string) ([]domain.FullShipmentInfo, error) {
+	if err := r.validateCurrency(ctx, currency); err != nil {
+		return nil, err
+	}
+	return r.queryFullShipmentInfo(ctx, fullShipmentInfoQuery, string(status), currency)
+}
+
+// GetShipmentDocument возвращает строки VIEW по одному документу отгрузки (для печати).
+func (r *Repository) GetShipmentDocument(ctx context.Context, warehouseNo, shipmentDocNo int) ([]domain.FullShipmentInfo, error) {
+	query := `SELECT v.*, 'RUB', v.total_price_rub
+	          FROM v_full_shipment_info v
+	          WHERE v.warehouse_no = $1 AND v.shipment_doc_no = $2
+	          ORDER BY v.part_code`
+	lines, err := r.queryFullShipmentInfo(ctx, query, warehouseNo, shipmentDocNo)
 	if err != nil {
 		return nil, err
 	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("shipment %d/%d: %w", warehouseNo, shipmentDocNo, ErrNotFound)
+	}
+	return lines, nil
+}
+
+func (r *Repository) queryFullShipmentInfo(ctx context.Context, query string, args ...any) ([]domain.FullShipmentInfo, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, rateError(err)
+	}
 	defer rows.Close()
 
 	var results []domain.FullShipmentInfo
@@ -171,41 +466,69 @@ func (r *Repository) GetFullShipmentInfo(ctx context.Context) ([]domain.FullShip
 			&info.WarehouseNo, &info.ShipmentDocNo, &info.ShipmentDate, &info.Qty,
 			&info.CustomerID, &info.CustomerName, &info.CustomerAddress, &info.CustomerCity,
 			&info.PartCode, &info.PartName, &info.PartType, &info.Unit, 
-			&info.PlanPrice, &info.TotalPrice,
+			&info.PlanPrice, &info.EffectivePrice, &info.TotalPrice, &info.Status, &info.ShippedQty, &info.ReturnedQty,
+			&info.BaseQty, &info.BaseUnit, &info.UnitPrice,
+			&info.Currency, &info.CustomerCurrency, &info.ExchangeRate, &info.TotalPriceRUB,
+			&info.ReportCurrency, &info.ReportTotal,
 		); err != nil {
-			return nil, err
+			return nil, rateError(err)
 		}
 		results = append(results, info)
 	}
-	return results, nil
+	return results, rateError(rows.Err())
 }
 
 // ============================================================================
 // Хранимая процедура
 // ============================================================================
 
-func (r *Repository) GetCustomerShipmentSummary(ctx context.Context, customerID int) (*domain.ProcedureResult, error) {
-	var result domain.ProcedureResult
-	
-	// Вызываем процедуру через CALL и получаем OUT параметры
-	err := r.db.QueryRow(ctx, 
-		`SELECT total_qty, total_value FROM (
-			SELECT $1::int as cid
-		) params,
-		LATERAL (
-			SELECT 
-				COALESCE(SUM(s.qty), 0) as total_qty,
-				COALESCE(SUM(s.qty * p.plan_price), 0) as total_value
-			FROM shipments s
-			JOIN parts p ON s.part_code = p.part_code
-			WHERE s.customer_id = params.cid
-		) result`,
-		customerID,
-	).Scan(&result.TotalQty, &result.TotalValue)
+// shipmentValueSQL - стоимость отгрузки за вычетом возвратов в валюте %s по курсу
+// на дату отгрузки. Ожидает псевдонимы s (shipments), n (v_shipment_net) и p (parts).
+// Общая для сводки по покупателю и расчета его задолженности.
+const shipmentValueSQL = `ROUND(n.net_base_qty * s.price
+					* fn_exchange_rate(p.currency, s.shipment_date)
+					/ fn_exchange_rate(%s, s.shipment_date), 2)`
+
+func (r *Repository) GetCustomerShipmentSummary(ctx context.Context, customerID int, currency string) (*domain.ProcedureResult, error) {
+	if err := r.validateCurrency(ctx, currency); err != nil {
+		return nil, err
+	}
+	result := domain.ProcedureResult{Parts: []domain.PartSummary{}, Currency: currency}
 	
+	// Тот же расчет, что и в процедуре, но с разбивкой по деталям: количества
+	// разных деталей в разных единицах не складываются, итог - только стоимость.
+	// Учитываются только продажи - отгруженные и доставленные документы.
+	// Количество и стоимость считаются за вычетом возвратов, в единицах деталей;
+	// стоимость каждой отгрузки пересчитывается в валюту отчета по курсу на ее дату
+	rows, err := r.db.Query(ctx, 
+		`SELECT s.part_code, p.name, p.unit,
+		        SUM(n.net_base_qty),
+		        SUM(`+fmt.Sprintf(shipmentValueSQL, "$2::text")+`)
+		FROM shipments s
+		JOIN v_shipment_net n ON s.warehouse_no = n.warehouse_no AND s.shipment_doc_no = n.shipment_doc_no
+		JOIN parts p ON s.part_code = p.part_code
+		WHERE s.customer_id = $1 AND n.net_qty > 0 AND `+shipmentSalesFilter+`
+		GROUP BY s.part_code, p.name, p.unit
+		ORDER BY s.part_code`,
+		customerID, currency,
+	)
 	if err != nil {
-		return nil, err
+		return nil, rateError(err)
 	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var part domain.PartSummary
+		if err := rows.Scan(&part.PartCode, &part.PartName, &part.Unit, &part.Qty, &part.Value); err != nil {
+			return nil, rateError(err)
+		}
+		result.Parts = append(result.Parts, part)
+		result.TotalValue = result.TotalValue.Add(part.Value)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, rateError(err)
+	}
+
 	return &result, nil
 }
 
@@ -213,8 +536,8 @@ func (r *Repository) GetCustomerShipmentSummary(ctx context.Context, customerID
 // ЗАДАЧА 1: SQL вариант
 // ============================================================================
 
-func (r *Repository) GetTask1SQL(ctx context.Context, city string) ([]domain.Task1Result, error) {
-	query := `
+// task1Query - отгрузки покупателям города $1 (в любом написании)
+const task1Query = `
 		SELECT 
 			s.warehouse_no,
 			s.part_code,
@@ -223,11 +546,15 @@ func (r *Repository) GetTask1SQL(ctx context.Context, city string) ([]domain.Tas
 			c.name AS customer_name
 		FROM shipments s
 		JOIN customers c ON s.customer_id = c.customer_id
-		WHERE c.city = $1
+		JOIN cities ct ON c.city = ct.name
+		WHERE ct.name_key = fn_city_key($1)
 		ORDER BY s.shipment_date DESC
 	`
-	
-	rows, err := r.db.Query(ctx, query, city)
+
+// GetTask1SQL возвращает отгрузки покупателям города. Город сравнивается по
+// нормализованному названию: «г. Казань», «казань» и «Казань» дают один результат.
+func (r *Repository) GetTask1SQL(ctx context.Context, city string) ([]domain.Task1Result, error) {
+	rows, err := r.db.Query(ctx, task1Query, city)
 	if err != nil {
 		return nil, err
 	}
@@ -241,65 +568,116 @@ func (r *Repository) GetTask1SQL(ctx context.Context, city string) ([]domain.Tas
 		}
 		results = append(results, r)
 	}
-	return results, nil
+	return results, rows.Err()
 }
 
 // ============================================================================
-// ЗАДАЧА 1: ORM вариант (обход коллекции с использованием GORM)
+// ЗАДАЧА 1: ORM вариант (GORM, соединение по связи модели)
 // ============================================================================
 
+// GetTask1ORM строит тот же запрос средствами GORM: покупатель присоединяется
+// по связи Customer модели отгрузки, а город отбирается в условии соединения,
+// так что загружаются только отгрузки нужного города.
 func (r *Repository) GetTask1ORM(ctx context.Context, city string) ([]domain.Task1Result, error) {
-	// Используем GORM для загрузки объектов с автоматической подгрузкой связей
-	var shipments []domain.ShipmentGorm
-	
-	// Preload загружает связанные объекты Customer для каждой отгрузки
-	// GORM автоматически выполняет JOIN и маппит данные на объекты
-	err := r.gormDB.Preload("Customer").Find(&shipments).Error
+	results, _, err := r.task1ORM(ctx, city)
+	return results, err
+}
+
+// task1ORM выполняет GetTask1ORM и возвращает также число строк, прочитанных GORM.
+func (r *Repository) task1ORM(ctx context.Context, city string) ([]domain.Task1Result, int64, error) {
+	// Введенный город приводится к названию из справочника, как и в SQL варианте
+	canonical, err := r.resolveCity(ctx, city)
+	if errors.Is(err, ErrUnknownCity) {
+		return nil, 0, nil
+	}
 	if err != nil {
-		return nil, err
+		return nil, 0, err
+	}
+
+	var shipments []domain.ShipmentGorm
+	res := r.gormDB.WithContext(ctx).
+		Scopes(shippedToCity(canonical.Name), orderBy("shipment_date", true)).
+		Find(&shipments)
+	if res.Error != nil {
+		return nil, 0, res.Error
 	}
 
-	// Обход коллекции ORM объектов с фильтрацией В КОДЕ
 	var results []domain.Task1Result
 	for _, s := range shipments {
-		// Фильтрация по городу происходит в коде Go, а не в SQL
-		if s.Customer.City == city {
-			results = append(results, domain.Task1Result{
-				WarehouseNo:  s.WarehouseNo,
-				PartCode:     s.PartCode,
-				ShipmentDate: s.ShipmentDate,
-				Qty:          s.Qty,
-				CustomerName: s.Customer.Name,
-			})
-		}
+		results = append(results, domain.Task1Result{
+			WarehouseNo:  s.WarehouseNo,
+			PartCode:     s.PartCode,
+			ShipmentDate: s.ShipmentDate,
+			Qty:          s.Qty,
+			CustomerName: s.Customer.Name,
+		})
 	}
-	
-	return results, nil
+	return results, res.RowsAffected, nil
 }
 
 // ============================================================================
 // ЗАДАЧА 2: с оконными функциями
 // ============================================================================
 
-func (r *Repository) GetTask2(ctx context.Context) ([]domain.Task2Result, error) {
-	query := `
+// task2Partitions сопоставляет разрез расчета доли с выражением PARTITION BY
+var task2Partitions = map[domain.Task2Partition]string{
+	domain.PartitionPart:      "s.part_code",
+	domain.PartitionCustomer:  "s.customer_id",
+	domain.PartitionWarehouse: "s.warehouse_no",
+}
+
+// task2Query строит запрос Задачи 2 и его параметры по фильтру.
+func task2Query(f domain.Task2Filter) (string, []any) {
+	partition, ok := task2Partitions[f.Partition]
+	if !ok {
+		f.Partition = domain.PartitionPart
+		partition = task2Partitions[f.Partition]
+	}
+	// Доля в пределах детали считается по количеству, в пределах покупателя
+	// или склада - по стоимости в рублях: количества разных деталей в разных
+	// единицах складывать нельзя
+	share := "n.net_base_qty"
+	if f.Partition.ByValue() {
+		share = "n.net_base_qty * s.price * fn_exchange_rate(p.currency, s.shipment_date)"
+	}
+
+	// Количество берется за вычетом возвратов и пересчитывается в единицу детали,
+	// чтобы не складывать, например, граммы с килограммами;
+	// полностью возвращенные отгрузки, черновики и отмененные документы не учитываются.
+	// Период задается полуинтервалом по shipment_date, чтобы работал индекс;
+	// если разрез складывает разные единицы, итог количества и его единица не выводятся
+	query := fmt.Sprintf(`
 		SELECT 
 			s.warehouse_no,
 			s.part_code,
 			c.name AS customer_name,
-			s.qty,
-			SUM(s.qty) OVER (PARTITION BY s.part_code) AS total_part_qty,
-			ROUND(
-				(s.qty / SUM(s.qty) OVER (PARTITION BY s.part_code) * 100)::numeric, 
+			n.net_base_qty AS qty,
+			p.unit,
+			CASE WHEN MIN(p.unit) OVER w = MAX(p.unit) OVER w THEN SUM(n.net_base_qty) OVER w END AS total_part_qty,
+			CASE WHEN MIN(p.unit) OVER w = MAX(p.unit) OVER w THEN p.unit ELSE '' END AS total_unit,
+			COALESCE(ROUND(
+				(%[2]s / NULLIF(SUM(%[2]s) OVER w, 0) * 100)::numeric, 
 				2
-			) AS share_of_total
+			), 0) AS share_of_total
 		FROM shipments s
+		JOIN v_shipment_net n ON s.warehouse_no = n.warehouse_no AND s.shipment_doc_no = n.shipment_doc_no
+		JOIN parts p ON s.part_code = p.part_code
 		JOIN customers c ON s.customer_id = c.customer_id
-		WHERE EXTRACT(YEAR FROM s.shipment_date) = EXTRACT(YEAR FROM CURRENT_DATE)
-		ORDER BY s.part_code, s.warehouse_no
-	`
+		WHERE s.shipment_date >= $1 AND s.shipment_date < $2
+		AND ($3::int = 0 OR s.warehouse_no = $3)
+		AND ($4::int = 0 OR s.customer_id = $4)
+		AND ($5::text = '' OR s.part_code = $5)
+		AND n.net_qty > 0 AND %[3]s
+		WINDOW w AS (PARTITION BY %[1]s)
+		ORDER BY %[1]s, s.part_code, s.warehouse_no, s.shipment_doc_no
+	`, partition, share, shipmentSalesFilter)
+	return query, []any{f.From, f.To, f.WarehouseNo, f.CustomerID, f.PartCode}
+}
+
+func (r *Repository) GetTask2(ctx context.Context, f domain.Task2Filter) ([]domain.Task2Result, error) {
+	query, args := task2Query(f)
 	
-	rows, err := r.db.Query(ctx, query)
+	rows, err := r.db.Query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -309,50 +687,26 @@ func (r *Repository) GetTask2(ctx context.Context) ([]domain.Task2Result, error)
 	for rows.Next() {
 		var r domain.Task2Result
 		if err := rows.Scan(&r.WarehouseNo, &r.PartCode, &r.CustomerName, 
-			&r.Qty, &r.TotalPartQty, &r.ShareOfTotal); err != nil {
+			&r.Qty, &r.Unit, &r.TotalPartQty, &r.TotalUnit, &r.ShareOfTotal); err != nil {
 			return nil, err
 		}
 		results = append(results, r)
 	}
-	return results, nil
+	return results, rows.Err()
 }
 
 // ============================================================================
 // ЗАДАЧА 3: Кванторный SQL запрос
 // ============================================================================
 
-func (r *Repository) GetTask3SQL(ctx context.Context) ([]domain.Task3Result, error) {
-	// Все покупатели, такие что:
-	// для некоторой детали с ценой > 100
-	// все документы об отгрузке этой детали этому покупателю были только со склада 5
-	query := `
-		SELECT DISTINCT c.customer_id, c.name, c.city
-		FROM customers c
-		WHERE EXISTS (
-			-- Существует деталь с ценой > 100
-			SELECT 1
-			FROM parts p
-			WHERE p.plan_price > 100
-			AND EXISTS (
-				-- Для которой есть отгрузка этому покупателю
-				SELECT 1
-				FROM shipments s1
-				WHERE s1.customer_id = c.customer_id
-				AND s1.part_code = p.part_code
-			)
-			AND NOT EXISTS (
-				-- И нет отгрузок этой детали не со склада 5
-				SELECT 1
-				FROM shipments s2
-				WHERE s2.customer_id = c.customer_id
-				AND s2.part_code = p.part_code
-				AND s2.warehouse_no != 5
-			)
-		)
-		ORDER BY c.customer_id
-	`
+// GetTask3SQL выполняет кванторный запрос, построенный по параметрам q.
+// По умолчанию (domain.DefaultTask3Query) это исходная задача: все покупатели,
+// такие что для некоторой детали с ценой > 100 все документы об отгрузке
+// этой детали этому покупателю были только со склада 5
+func (r *Repository) GetTask3SQL(ctx context.Context, q domain.Task3Query) ([]domain.Task3Result, error) {
+	query, args := quantifier.SQL(q)
 	
-	rows, err := r.db.Query(ctx, query)
+	rows, err := r.db.Query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -366,81 +720,54 @@ func (r *Repository) GetTask3SQL(ctx context.Context) ([]domain.Task3Result, err
 		}
 		results = append(results, r)
 	}
-	return results, nil
+	return results, rows.Err()
 }
 
 // ============================================================================
 // ЗАДАЧА 3: Record-ориентированный подход
 // ============================================================================
 
-func (r *Repository) GetTask3RecordBased(ctx context.Context) ([]domain.Task3Result, error) {
-	// Шаг 1: Получаем всех покупателей
-	customers, err := r.GetCustomers(ctx)
+// GetTask3RecordBased проверяет условие по записям за один проход: из базы
+// читаются только отгрузки деталей, удовлетворяющих P, и только подошедшие
+// покупатели, а состояние кванторов хранится по паре (покупатель, деталь).
+func (r *Repository) GetTask3RecordBased(ctx context.Context, q domain.Task3Query) ([]domain.Task3Result, error) {
+	// Шаг 1: Получаем детали и проверяем условие P при обходе
+	parts, err := r.queryParts(ctx, "TRUE")
 	if err != nil {
 		return nil, err
 	}
+	e := quantifier.NewEvaluator(q, parts)
 
-	// Шаг 2: Получаем все детали с ценой > 100
-	partsQuery := "SELECT part_code FROM parts WHERE plan_price > 100"
-	partsRows, err := r.db.Query(ctx, partsQuery)
+	// Шаг 2: Обходим поток отгрузок только отобранных деталей, не сохраняя их;
+	// условие Q и кванторы проверяются для каждой записи
+	rows, err := r.db.Query(ctx, `SELECT customer_id, part_code, warehouse_no, shipment_date 
+	                              FROM shipments WHERE part_code = ANY($1)`, e.PartCodes())
 	if err != nil {
 		return nil, err
 	}
-	defer partsRows.Close()
-
-	var expensiveParts []string
-	for partsRows.Next() {
-		var partCode string
-		if err := partsRows.Scan(&partCode); err != nil {
+	defer rows.Close()
+	for rows.Next() {
+		var s domain.Shipment
+		if err := rows.Scan(&s.CustomerID, &s.PartCode, &s.WarehouseNo, &s.ShipmentDate); err != nil {
 			return nil, err
 		}
-		expensiveParts = append(expensiveParts, partCode)
+		e.Add(s)
 	}
-
-	// Шаг 3: Получаем все отгрузки
-	shipments, err := r.GetShipments(ctx)
-	if err != nil {
+	if err := rows.Err(); err != nil {
 		return nil, err
 	}
 
-	// Шаг 4: Обходим покупателей и проверяем условия
-	var results []domain.Task3Result
-	for _, customer := range customers {
-		hasValidPart := false
-		
-		// Проверяем каждую дорогую деталь
-		for _, partCode := range expensiveParts {
-			hasShipment := false
-			allFromWarehouse5 := true
-			
-			// Проверяем отгрузки этой детали этому покупателю
-			for _, shipment := range shipments {
-				if shipment.CustomerID == customer.CustomerID && shipment.PartCode == partCode {
-					hasShipment = true
-					if shipment.WarehouseNo != 5 {
-						allFromWarehouse5 = false
-						break
-					}
-				}
-			}
-			
-			// Если есть отгрузки этой детали и все со склада 5
-			if hasShipment && allFromWarehouse5 {
-				hasValidPart = true
-				break
-			}
-		}
-		
-		if hasValidPart {
-			results = append(results, domain.Task3Result{
-				CustomerID:   customer.CustomerID,
-				CustomerName: customer.Name,
-				CustomerCity: customer.City,
-			})
-		}
+	// Шаг 3: Загружаем только подошедших покупателей (включая удаленных в корзину -
+	// их отгрузки сохранены)
+	ids := e.CustomerIDs()
+	if len(ids) == 0 {
+		return nil, nil
 	}
-
-	return results, nil
+	customers, err := r.queryCustomers(ctx, "c.customer_id = ANY($1)", ids)
+	if err != nil {
+		return nil, err
+	}
+	return e.Results(customers), nil
 }
 
 // ============================================================================
@@ -451,11 +778,45 @@ func (r *Repository) GetTableData(ctx context.Context, tableName string) ([]map[
 	var query string
 	switch tableName {
 	case "parts":
-		query = "SELECT part_code, part_type, name, unit, plan_price FROM parts"
+		query = "SELECT part_code, part_type, name, unit, plan_price, currency, deleted_at, deleted_by FROM parts"
 	case "customers":
-		query = "SELECT customer_id, name, address, city FROM customers"
+		query = "SELECT customer_id, name, address, city, postal_code, street, building, currency, credit_limit, deleted_at, deleted_by FROM customers"
+	case "cities":
+		query = "SELECT name, region, name_key FROM cities"
+	case "customer_contacts":
+		query = "SELECT contact_id, customer_id, name, role, phone, email, is_primary FROM customer_contacts"
 	case "shipments":
-		query = "SELECT warehouse_no, shipment_doc_no, customer_id, part_code, unit, qty, shipment_date FROM shipments"
+		query = "SELECT warehouse_no, shipment_doc_no, customer_id, part_code, unit, qty, price, shipment_date, status FROM shipments"
+	case "units":
+		query = "SELECT unit_code, name, base_unit, factor FROM units"
+	case "part_units":
+		query = "SELECT part_code, unit_code, factor FROM part_units"
+	case "exchange_rates":
+		query = "SELECT currency_code, rate_date, rate FROM exchange_rates"
+	case "payments":
+		query = "SELECT payment_id, customer_id, payment_date, amount, currency, reference, created_by FROM payments"
+	case "credit_overrides":
+		query = "SELECT override_id, warehouse_no, shipment_doc_no, customer_id, credit_limit, exposure, currency, overridden_by, overridden_at FROM credit_overrides"
+	case "customer_merges":
+		query = "SELECT merge_id, survivor_id, duplicate_id, shipments_moved, merged_by, merged_at FROM customer_merges"
+	case "part_renames":
+		query = "SELECT rename_id, old_code, new_code, renamed_by, renamed_at FROM part_renames"
+	case "bench_runs":
+		query = "SELECT run_id, run_at, run_by, seed, customers, parts, shipments, iterations FROM bench_runs"
+	case "bench_results":
+		query = "SELECT result_id, run_id, strategy, p50_ms, p90_ms, p99_ms, mean_ms, result_rows, rows_read, alloc_bytes, allocs FROM bench_results"
+	case "abc_xyz_snapshots":
+		query = "SELECT snapshot_id, subject, period_from, period_to, months, a_threshold, b_threshold, x_threshold, y_threshold, total_value, created_at, created_by FROM abc_xyz_snapshots"
+	case "abc_xyz_items":
+		query = "SELECT snapshot_id, item_key, name, value, share, cumulative_share, qty, mean_qty, cv, abc_class, xyz_class FROM abc_xyz_items"
+	case "change_log":
+		query = "SELECT log_id, change_id, table_name, operation, changed_by, changed_at, is_undo, undone_by, undone_at FROM change_log ORDER BY log_id DESC"
+	case "price_lists":
+		query = "SELECT price_list_id, customer_id, part_code, price, valid_from, valid_to FROM price_lists"
+	case "discount_rules":
+		query = "SELECT rule_id, customer_id, city, part_code, percent, valid_from, valid_to FROM discount_rules"
+	case "volume_discounts":
+		query = "SELECT tier_id, part_code, min_qty, percent FROM volume_discounts"
 	default:
 		return nil, fmt.Errorf("unknown table: %s", tableName)
 	}
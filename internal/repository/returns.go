@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+	"github.com/student/my-kpfu-db-app/internal/domain"
+)
+
+// ============================================================================
+// Возвраты от покупателей и кредитовые ноты
+// ============================================================================
+
+const returnColumns = `r.return_id, r.warehouse_no, r.shipment_doc_no, s.customer_id, s.part_code, s.unit,
+	r.qty, r.return_date, r.reason, r.credit_amount, r.currency, r.created_by, r.created_at`
+
+// CreateReturn оформляет возврат по исходному документу отгрузки.
+// Документ должен быть отгружен, дата возврата - не раньше даты отгрузки,
+// а суммарный возврат не может превышать отгруженное количество. Сумма кредит-ноты - в валюте покупателя по курсу на
+// дату отгрузки, как и в счете. Исходная отгрузка не изменяется.
+func (r *Repository) CreateReturn(ctx context.Context, ret *domain.ShipmentReturn) error {
+	return r.inTx(ctx, func(tx *Repository) error {
+		var shipment domain.Shipment
+		var price, returned decimal.Decimal
+		// Блокируем строку отгрузки, чтобы параллельные возвраты не превысили количество
+		err := tx.db.QueryRow(ctx, `
+			SELECT s.customer_id, s.part_code, s.unit, s.qty, s.status, s.shipment_date, c.currency,
+			       ROUND(s.price * COALESCE(pu.factor, 1)
+			             * fn_exchange_rate(p.currency, s.shipment_date)
+			             / fn_exchange_rate(c.currency, s.shipment_date), 2),
+			       (SELECT COALESCE(SUM(r.qty), 0) FROM shipment_returns r
+			        WHERE r.warehouse_no = s.warehouse_no AND r.shipment_doc_no = s.shipment_doc_no)
+			FROM shipments s
+			JOIN parts p ON s.part_code = p.part_code
+			JOIN customers c ON s.customer_id = c.customer_id
+			LEFT JOIN part_units pu ON s.part_code = pu.part_code AND s.unit = pu.unit_code
+			WHERE s.warehouse_no = $1 AND s.shipment_doc_no = $2
+			FOR UPDATE OF s`,
+			ret.WarehouseNo, ret.ShipmentDocNo,
+		).Scan(&shipment.CustomerID, &shipment.PartCode, &shipment.Unit, &shipment.Qty,
+			&shipment.Status, &shipment.ShipmentDate, &ret.Currency, &price, &returned)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("shipment %d/%d: %w", ret.WarehouseNo, ret.ShipmentDocNo, ErrNotFound)
+		}
+		if err != nil {
+			return rateError(err)
+		}
+
+		// Статус проверяется первым: по неотгруженному документу нечего возвращать
+		if !shipment.Returnable() {
+			return fmt.Errorf("%w: shipment is %s", ErrNotReturnable, shipment.Status)
+		}
+		if !ret.ReturnDate.IsZero() && ret.ReturnDate.Before(shipment.ShipmentDate) {
+			return fmt.Errorf("%w: %s is before the shipment date %s", ErrReturnDate,
+				ret.ReturnDate.Format("2006-01-02"), shipment.ShipmentDate.Format("2006-01-02"))
+		}
+		if !ret.Qty.IsPositive() {
+			return fmt.Errorf("%w: quantity must be positive", ErrReturnQty)
+		}
+		if returned.Add(ret.Qty).GreaterThan(shipment.Qty) {
+			return fmt.Errorf("%w: shipped %s, already returned %s, requested %s",
+				ErrReturnQty, shipment.Qty, returned, ret.Qty)
+		}
+
+		ret.CustomerID = shipment.CustomerID
+		ret.PartCode = shipment.PartCode
+		ret.Unit = shipment.Unit
+		ret.CreditAmount = domain.RoundMoney(ret.Qty.Mul(price))
+		ret.CreatedBy = UserFromContext(ctx)
+		if ret.Reason == "" {
+			ret.Reason = "Не указана"
+		}
+
+		var returnDate any
+		if !ret.ReturnDate.IsZero() {
+			returnDate = ret.ReturnDate
+		}
+		err = tx.db.QueryRow(ctx, `
+			INSERT INTO shipment_returns (warehouse_no, shipment_doc_no, qty, return_date, reason, credit_amount, currency, created_by)
+			VALUES ($1, $2, $3, COALESCE($4::date, CURRENT_DATE), $5, $6, $7, $8)
+			RETURNING return_id, return_date, created_at`,
+			ret.WarehouseNo, ret.ShipmentDocNo, ret.Qty, returnDate, ret.Reason, ret.CreditAmount, ret.Currency, ret.CreatedBy,
+		).Scan(&ret.ReturnID, &ret.ReturnDate, &ret.CreatedAt)
+		if err != nil {
+			return err
+		}
+		ret.CreditNoteNo = domain.CreditNoteNumber(ret.ReturnID)
+
+		// Возврат фиксируется в журнале аудита вместе с исходным документом
+		_, err = tx.db.Exec(ctx, `
+			INSERT INTO shipments_audit (warehouse_no, shipment_doc_no, customer_id, part_code, qty, shipment_date, action)
+			VALUES ($1, $2, $3, $4, $5, $6, 'RETURN')`,
+			ret.WarehouseNo, ret.ShipmentDocNo, ret.CustomerID, ret.PartCode, ret.Qty, ret.ReturnDate)
+		return err
+	})
+}
+
+func (r *Repository) GetReturns(ctx context.Context) ([]domain.ShipmentReturn, error) {
+	query := `SELECT ` + returnColumns + `
+	          FROM shipment_returns r
+	          JOIN shipments s ON r.warehouse_no = s.warehouse_no AND r.shipment_doc_no = s.shipment_doc_no
+	          ORDER BY r.return_date DESC, r.return_id DESC`
+	return r.queryReturns(ctx, query)
+}
+
+func (r *Repository) GetShipmentReturns(ctx context.Context, warehouseNo, shipmentDocNo int) ([]domain.ShipmentReturn, error) {
+	query := `SELECT ` + returnColumns + `
+	          FROM shipment_returns r
+	          JOIN shipments s ON r.warehouse_no = s.warehouse_no AND r.shipment_doc_no = s.shipment_doc_no
+	          WHERE r.warehouse_no = $1 AND r.shipment_doc_no = $2
+	          ORDER BY r.return_date, r.return_id`
+	return r.queryReturns(ctx, query, warehouseNo, shipmentDocNo)
+}
+
+func (r *Repository) queryReturns(ctx context.Context, query string, args ...any) ([]domain.ShipmentReturn, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var returns []domain.ShipmentReturn
+	for rows.Next() {
+		var ret domain.ShipmentReturn
+		if err := rows.Scan(&ret.ReturnID, &ret.WarehouseNo, &ret.ShipmentDocNo, &ret.CustomerID,
+			&ret.PartCode, &ret.Unit, &ret.Qty, &ret.ReturnDate, &ret.Reason, &ret.CreditAmount,
+			&ret.Currency, &ret.CreatedBy, &ret.CreatedAt); err != nil {
+			return nil, err
+		}
+		ret.CreditNoteNo = domain.CreditNoteNumber(ret.ReturnID)
+		returns = append(returns, ret)
+	}
+	return returns, nil
+}
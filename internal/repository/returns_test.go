@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/student/my-kpfu-db-app/internal/domain"
+)
+
+// statusPaths - переходы, которыми новая отгрузка доводится до статуса
+var statusPaths = map[domain.ShipmentStatus][]domain.ShipmentStatus{
+	domain.StatusDraft:     nil,
+	domain.StatusConfirmed: {domain.StatusConfirmed},
+	domain.StatusPicked:    {domain.StatusConfirmed, domain.StatusPicked},
+	domain.StatusShipped:   {domain.StatusConfirmed, domain.StatusPicked, domain.StatusShipped},
+	domain.StatusDelivered: {domain.StatusConfirmed, domain.StatusPicked, domain.StatusShipped, domain.StatusDelivered},
+	domain.StatusCancelled: {domain.StatusCancelled},
+}
+
+// testShipment создает в tx покупателя, деталь и отгрузку 10 шт от 1 марта
+// 2024 года и доводит ее до статуса status.
+func testShipment(t *testing.T, ctx context.Context, tx *Repository, status domain.ShipmentStatus) domain.Shipment {
+	t.Helper()
+	c := domain.Customer{Name: "Тестовый покупатель", City: "Казань"}
+	if err := tx.CreateCustomer(ctx, &c); err != nil {
+		t.Fatalf("create customer: %v", err)
+	}
+	p := domain.Part{PartCode: fmt.Sprintf("TEST-%d", c.CustomerID), PartType: "покупная",
+		Name: "Тестовая деталь", Unit: "шт", PlanPrice: decimal.NewFromInt(100)}
+	if err := tx.CreatePart(ctx, &p); err != nil {
+		t.Fatalf("create part: %v", err)
+	}
+
+	s := domain.Shipment{WarehouseNo: 1, CustomerID: c.CustomerID, PartCode: p.PartCode, Unit: p.Unit,
+		Qty: decimal.NewFromInt(10), ShipmentDate: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)}
+	if err := tx.db.QueryRow(ctx, "SELECT COALESCE(MAX(shipment_doc_no), 0) + 1 FROM shipments").Scan(&s.ShipmentDocNo); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.CreateShipment(ctx, &s, true); err != nil {
+		t.Fatalf("create shipment: %v", err)
+	}
+	for _, to := range statusPaths[status] {
+		if _, err := tx.TransitionShipment(ctx, s.WarehouseNo, s.ShipmentDocNo, to); err != nil {
+			t.Fatalf("%s -> %s: %v", s.Status, to, err)
+		}
+		s.Status = to
+	}
+	return s
+}
+
+func TestCreateReturn(t *testing.T) {
+	repo := testRepository(t)
+	ctx := WithUser(context.Background(), "go-test")
+	shipmentDate := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		status     domain.ShipmentStatus
+		qty        int64
+		returnDate time.Time
+		want       error
+	}{
+		{"shipped", domain.StatusShipped, 4, shipmentDate.AddDate(0, 0, 3), nil},
+		{"delivered on the shipment date", domain.StatusDelivered, 10, shipmentDate, nil},
+		{"date before the shipment", domain.StatusDelivered, 4, shipmentDate.AddDate(0, 0, -1), ErrReturnDate},
+		{"more than shipped", domain.StatusShipped, 11, shipmentDate, ErrReturnQty},
+		{"zero quantity", domain.StatusShipped, 0, shipmentDate, ErrReturnQty},
+		// Статус проверяется раньше количества и даты
+		{"draft", domain.StatusDraft, 11, shipmentDate.AddDate(0, 0, -1), ErrNotReturnable},
+		{"picked", domain.StatusPicked, 4, shipmentDate, ErrNotReturnable},
+		{"cancelled", domain.StatusCancelled, 11, shipmentDate, ErrNotReturnable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := repo.Sandbox(ctx, func(tx *Repository) error {
+				s := testShipment(t, ctx, tx, tt.status)
+				ret := domain.ShipmentReturn{WarehouseNo: s.WarehouseNo, ShipmentDocNo: s.ShipmentDocNo,
+					Qty: decimal.NewFromInt(tt.qty), ReturnDate: tt.returnDate}
+				return tx.CreateReturn(ctx, &ret)
+			})
+			if tt.want == nil && err != nil || tt.want != nil && !errors.Is(err, tt.want) {
+				t.Fatalf("CreateReturn() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
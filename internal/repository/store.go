@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/student/my-kpfu-db-app/internal/domain"
+)
+
+// Store is the CRUD of parts, customers and shipments. Repository implements
+// it with pgx and GormStore with GORM; both apply the same business rules and
+// return the same errors, so the API behaves identically on either backend.
+type Store interface {
+	GetParts(ctx context.Context) ([]domain.Part, error)
+	CreatePart(ctx context.Context, p *domain.Part) error
+	UpdatePart(ctx context.Context, p *domain.Part) error
+	DeletePart(ctx context.Context, partCode string) error
+
+	GetCustomers(ctx context.Context) ([]domain.Customer, error)
+	CreateCustomer(ctx context.Context, c *domain.Customer) error
+	UpdateCustomer(ctx context.Context, c *domain.Customer) error
+	DeleteCustomer(ctx context.Context, customerID int) error
+
+	GetShipments(ctx context.Context, status domain.ShipmentStatus) ([]domain.Shipment, error)
+	CreateShipment(ctx context.Context, s *domain.Shipment, overrideCredit bool) error
+	UpdateShipment(ctx context.Context, s *domain.Shipment, overrideCredit bool) error
+	DeleteShipment(ctx context.Context, warehouseNo, shipmentDocNo int) error
+}
+
+// Store backends selectable by configuration.
+const (
+	BackendPgx  = "pgx"
+	BackendGorm = "gorm"
+)
+
+// Store возвращает реализацию CRUD для backend.
+func (r *Repository) Store(backend string) (Store, error) {
+	switch backend {
+	case "", BackendPgx:
+		return r, nil
+	case BackendGorm:
+		return &GormStore{r: r}, nil
+	}
+	return nil, fmt.Errorf("unknown repository backend %q", backend)
+}
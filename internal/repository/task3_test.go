@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+
+	"github.com/student/my-kpfu-db-app/internal/compare"
+	"github.com/student/my-kpfu-db-app/internal/domain"
+)
+
+func TestTask3RecordBasedMatchesSQL(t *testing.T) {
+	repo := testRepository(t)
+	ctx := WithUser(context.Background(), "go-test")
+
+	for seed := int64(1); seed <= 3; seed++ {
+		ds := compare.Generate(seed, domain.Volumes{Customers: 30, Parts: 10, Shipments: 300})
+		rng := rand.New(rand.NewSource(seed))
+		queries := []domain.Task3Query{domain.DefaultTask3Query()}
+		for i := 0; i < 5; i++ {
+			queries = append(queries, compare.RandomTask3Query(rng))
+		}
+
+		err := repo.Sandbox(ctx, func(tx *Repository) error {
+			if err := tx.loadDataset(ctx, ds); err != nil {
+				return err
+			}
+			for _, base := range queries {
+				for _, parts := range domain.PartQuantifiers {
+					for _, shipments := range domain.ShipmentQuantifiers {
+						q := base
+						q.Parts, q.Shipments = parts, shipments
+
+						c, err := tx.CompareTask3(ctx, q)
+						if err != nil {
+							return err
+						}
+						if !c.Equal {
+							t.Errorf("seed %d, %s: sql %d rows, record %d rows; missing %v, extra %v, different %v",
+								seed, q, c.LeftRows, c.RightRows, c.Missing, c.Extra, c.Different)
+						}
+					}
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("seed %d: %v", seed, err)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/student/my-kpfu-db-app/internal/domain"
+)
+
+// ============================================================================
+// Корзина: восстановление и окончательное удаление
+// ============================================================================
+
+// GetTrash возвращает покупателей и детали, удаленные в корзину.
+func (r *Repository) GetTrash(ctx context.Context) (*domain.Trash, error) {
+	customers, err := r.queryCustomers(ctx, "c.deleted_at IS NOT NULL")
+	if err != nil {
+		return nil, err
+	}
+	parts, err := r.queryParts(ctx, "deleted_at IS NOT NULL")
+	if err != nil {
+		return nil, err
+	}
+	return &domain.Trash{Customers: customers, Parts: parts}, nil
+}
+
+func (r *Repository) RestoreCustomer(ctx context.Context, customerID int) error {
+	tag, err := r.execTx(ctx, `UPDATE customers SET deleted_at = NULL, deleted_by = NULL
+		WHERE customer_id = $1 AND deleted_at IS NOT NULL`, customerID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("customer %d: %w", customerID, ErrNotArchived)
+	}
+	return nil
+}
+
+func (r *Repository) RestorePart(ctx context.Context, partCode string) error {
+	tag, err := r.execTx(ctx, `UPDATE parts SET deleted_at = NULL, deleted_by = NULL
+		WHERE part_code = $1 AND deleted_at IS NOT NULL`, partCode)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("part %s: %w", partCode, ErrNotArchived)
+	}
+	return nil
+}
+
+// PurgeCustomer окончательно удаляет покупателя из корзины вместе с отгрузками
+// (триггер trg_customers_before_delete). Без confirm только считает отгрузки.
+// Покупателя со счетами удалить нельзя - счета должны сохраниться.
+func (r *Repository) PurgeCustomer(ctx context.Context, customerID int, confirm bool) (*domain.PurgeResult, error) {
+	result := &domain.PurgeResult{}
+	err := r.inTx(ctx, func(tx *Repository) error {
+		var deletedAt *time.Time
+		err := tx.db.QueryRow(ctx, "SELECT deleted_at FROM customers WHERE customer_id = $1 FOR UPDATE",
+			customerID).Scan(&deletedAt)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("customer %d: %w", customerID, ErrNotFound)
+		}
+		if err != nil {
+			return err
+		}
+		if deletedAt == nil {
+			return fmt.Errorf("customer %d: %w", customerID, ErrNotArchived)
+		}
+
+		var invoices int
+		if err := tx.db.QueryRow(ctx, `
+			SELECT (SELECT COUNT(*) FROM shipments WHERE customer_id = $1),
+			       (SELECT COUNT(*) FROM invoices WHERE customer_id = $1)`,
+			customerID).Scan(&result.Shipments, &invoices); err != nil {
+			return err
+		}
+		if invoices > 0 {
+			return fmt.Errorf("%w: customer %d has %d invoices", ErrPurgeBlocked, customerID, invoices)
+		}
+		if !confirm {
+			return nil
+		}
+
+		if _, err := tx.db.Exec(ctx, "DELETE FROM customers WHERE customer_id = $1", customerID); err != nil {
+			return err
+		}
+		result.Purged = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// PurgePart окончательно удаляет деталь из корзины; отгрузки, единицы и цены
+// детали удаляются каскадом. Без confirm только считает отгрузки.
+// Деталь, отгрузки которой вошли в счета, удалить нельзя - счета должны
+// ссылаться на сохранившиеся отгрузки.
+func (r *Repository) PurgePart(ctx context.Context, partCode string, confirm bool) (*domain.PurgeResult, error) {
+	result := &domain.PurgeResult{}
+	err := r.inTx(ctx, func(tx *Repository) error {
+		var deletedAt *time.Time
+		err := tx.db.QueryRow(ctx, "SELECT deleted_at FROM parts WHERE part_code = $1 FOR UPDATE",
+			partCode).Scan(&deletedAt)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("part %s: %w", partCode, ErrNotFound)
+		}
+		if err != nil {
+			return err
+		}
+		if deletedAt == nil {
+			return fmt.Errorf("part %s: %w", partCode, ErrNotArchived)
+		}
+
+		// Аннулированные счета тоже учитываются: их строки ссылаются на отгрузки
+		var invoices int
+		if err := tx.db.QueryRow(ctx, `
+			SELECT (SELECT COUNT(*) FROM shipments WHERE part_code = $1),
+			       (SELECT COUNT(DISTINCT l.invoice_id) FROM invoice_lines l
+			        JOIN shipments s ON l.warehouse_no = s.warehouse_no AND l.shipment_doc_no = s.shipment_doc_no
+			        WHERE s.part_code = $1)`,
+			partCode).Scan(&result.Shipments, &invoices); err != nil {
+			return err
+		}
+		if invoices > 0 {
+			return fmt.Errorf("%w: part %s is billed in %d invoices", ErrPurgeBlocked, partCode, invoices)
+		}
+		if !confirm {
+			return nil
+		}
+
+		if _, err := tx.db.Exec(ctx, "DELETE FROM parts WHERE part_code = $1", partCode); err != nil {
+			return err
+		}
+		result.Purged = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// checkNotArchived запрещает оформлять отгрузки покупателю или детали из корзины.
+// Несуществующие записи пропускаются - их отклонит расчет цены.
+func (r *Repository) checkNotArchived(ctx context.Context, customerID int, partCode string) error {
+	var customerArchived, partArchived bool
+	err := r.db.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM customers WHERE customer_id = $1 AND deleted_at IS NOT NULL),
+		       EXISTS (SELECT 1 FROM parts WHERE part_code = $2 AND deleted_at IS NOT NULL)`,
+		customerID, partCode).Scan(&customerArchived, &partArchived)
+	if err != nil {
+		return err
+	}
+	if customerArchived {
+		return fmt.Errorf("customer %d: %w", customerID, ErrArchived)
+	}
+	if partArchived {
+		return fmt.Errorf("part %s: %w", partCode, ErrArchived)
+	}
+	return nil
+}
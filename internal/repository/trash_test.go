@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/student/my-kpfu-db-app/internal/domain"
+)
+
+func TestPurgePart(t *testing.T) {
+	repo := testRepository(t)
+	ctx := WithUser(context.Background(), "go-test")
+
+	tests := []struct {
+		name     string
+		status   domain.ShipmentStatus
+		invoiced bool
+		want     error
+	}{
+		{"not invoiced", domain.StatusShipped, false, nil},
+		{"draft", domain.StatusDraft, false, nil},
+		{"invoiced", domain.StatusShipped, true, ErrPurgeBlocked},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := repo.Sandbox(ctx, func(tx *Repository) error {
+				s := testShipment(t, ctx, tx, tt.status)
+				if tt.invoiced {
+					if _, err := tx.CreateInvoice(ctx, s.CustomerID, s.ShipmentDate, s.ShipmentDate, decimal.NewFromInt(20)); err != nil {
+						t.Fatalf("create invoice: %v", err)
+					}
+				}
+				if err := tx.DeletePart(ctx, s.PartCode); err != nil {
+					t.Fatalf("delete part: %v", err)
+				}
+
+				result, err := tx.PurgePart(ctx, s.PartCode, true)
+				if err != nil {
+					return err
+				}
+				if !result.Purged || result.Shipments != 1 {
+					t.Errorf("PurgePart() = %+v, want 1 shipment purged", result)
+				}
+				return nil
+			})
+			if tt.want == nil && err != nil || tt.want != nil && !errors.Is(err, tt.want) {
+				t.Fatalf("PurgePart() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+	"github.com/student/my-kpfu-db-app/internal/domain"
+)
+
+// ============================================================================
+// Единицы измерения и допустимые единицы деталей
+// ============================================================================
+
+func (r *Repository) GetUnits(ctx context.Context) ([]domain.Unit, error) {
+	query := "SELECT unit_code, name, base_unit, factor FROM units ORDER BY base_unit, factor"
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var units []domain.Unit
+	for rows.Next() {
+		var u domain.Unit
+		if err := rows.Scan(&u.UnitCode, &u.Name, &u.BaseUnit, &u.Factor); err != nil {
+			return nil, err
+		}
+		units = append(units, u)
+	}
+	return units, nil
+}
+
+func (r *Repository) GetPartUnits(ctx context.Context, partCode string) ([]domain.PartUnit, error) {
+	query := `SELECT part_code, unit_code, factor FROM part_units
+	          WHERE part_code = $1
+	          ORDER BY factor, unit_code`
+	rows, err := r.db.Query(ctx, query, partCode)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var units []domain.PartUnit
+	for rows.Next() {
+		var u domain.PartUnit
+		if err := rows.Scan(&u.PartCode, &u.UnitCode, &u.Factor); err != nil {
+			return nil, err
+		}
+		units = append(units, u)
+	}
+	return units, nil
+}
+
+// AddPartUnit разрешает отгружать деталь в единице pu.UnitCode. Если коэффициент
+// не задан, он вычисляется по справочнику единиц; это возможно только для
+// единиц с той же базовой единицей (г -> кг). Для прочих (компл -> шт)
+// коэффициент указывается явно.
+func (r *Repository) AddPartUnit(ctx context.Context, pu *domain.PartUnit) error {
+	return r.inTx(ctx, func(tx *Repository) error {
+		if !pu.Factor.IsPositive() {
+			factor, err := tx.unitFactor(ctx, pu.PartCode, pu.UnitCode)
+			if err != nil {
+				return err
+			}
+			pu.Factor = factor
+		}
+
+		query := `INSERT INTO part_units (part_code, unit_code, factor) VALUES ($1, $2, $3)
+		          ON CONFLICT (part_code, unit_code) DO UPDATE SET factor = EXCLUDED.factor`
+		_, err := tx.db.Exec(ctx, query, pu.PartCode, pu.UnitCode, pu.Factor)
+		return err
+	})
+}
+
+// DeletePartUnit запрещает единицу для детали. Собственную единицу детали и
+// единицы, в которых уже есть отгрузки, удалить нельзя.
+func (r *Repository) DeletePartUnit(ctx context.Context, partCode, unitCode string) error {
+	return r.inTx(ctx, func(tx *Repository) error {
+		var baseUnit string
+		var used bool
+		err := tx.db.QueryRow(ctx, `
+			SELECT p.unit, EXISTS (SELECT 1 FROM shipments s WHERE s.part_code = p.part_code AND s.unit = $2)
+			FROM parts p WHERE p.part_code = $1`, partCode, unitCode).Scan(&baseUnit, &used)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("part %s: %w", partCode, ErrNotFound)
+		}
+		if err != nil {
+			return err
+		}
+		if unitCode == baseUnit || used {
+			return fmt.Errorf("%w: unit %s of part %s is in use", ErrUnitNotAllowed, unitCode, partCode)
+		}
+
+		_, err = tx.db.Exec(ctx, "DELETE FROM part_units WHERE part_code = $1 AND unit_code = $2", partCode, unitCode)
+		return err
+	})
+}
+
+// unitFactor вычисляет коэффициент пересчета unitCode в единицу детали по
+// справочнику единиц.
+func (r *Repository) unitFactor(ctx context.Context, partCode, unitCode string) (decimal.Decimal, error) {
+	var factor decimal.Decimal
+	err := r.db.QueryRow(ctx, `
+		SELECT u.factor / b.factor
+		FROM parts p
+		JOIN units b ON b.unit_code = p.unit
+		JOIN units u ON u.base_unit = b.base_unit
+		WHERE p.part_code = $1 AND u.unit_code = $2`, partCode, unitCode).Scan(&factor)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return decimal.Zero, fmt.Errorf("%w: %s cannot be converted to the unit of part %s", ErrUnitNotAllowed, unitCode, partCode)
+	}
+	return factor, err
+}
+
+// rebasePartUnits делает newUnit собственной единицей детали: коэффициенты всех
+// допустимых единиц пересчитываются так, чтобы у newUnit он стал равен 1.
+func (r *Repository) rebasePartUnits(ctx context.Context, partCode, newUnit string) error {
+	var factor decimal.Decimal
+	err := r.db.QueryRow(ctx, "SELECT factor FROM part_units WHERE part_code = $1 AND unit_code = $2",
+		partCode, newUnit).Scan(&factor)
+	if errors.Is(err, pgx.ErrNoRows) {
+		if factor, err = r.unitFactor(ctx, partCode, newUnit); err != nil {
+			return err
+		}
+		_, err = r.db.Exec(ctx, "INSERT INTO part_units (part_code, unit_code, factor) VALUES ($1, $2, $3)",
+			partCode, newUnit, factor)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.Exec(ctx, "UPDATE part_units SET factor = factor / $2 WHERE part_code = $1", partCode, factor)
+	return err
+}
+
+// shipmentUnitFactor проверяет, что деталь разрешено отгружать в этой единице,
+// и возвращает коэффициент пересчета в единицу детали.
+func (r *Repository) shipmentUnitFactor(ctx context.Context, partCode, unitCode string) (decimal.Decimal, error) {
+	var factor decimal.Decimal
+	err := r.db.QueryRow(ctx, "SELECT factor FROM part_units WHERE part_code = $1 AND unit_code = $2",
+		partCode, unitCode).Scan(&factor)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return decimal.Zero, fmt.Errorf("%w: part %s cannot be shipped in %s", ErrUnitNotAllowed, partCode, unitCode)
+	}
+	return factor, err
+}
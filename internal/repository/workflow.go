@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/student/my-kpfu-db-app/internal/domain"
+)
+
+// ============================================================================
+// Жизненный цикл документа отгрузки
+// ============================================================================
+
+// TransitionShipment переводит документ в статус to, если это разрешено
+// конечным автоматом domain.ShipmentStatus, и записывает переход в историю.
+func (r *Repository) TransitionShipment(ctx context.Context, warehouseNo, shipmentDocNo int, to domain.ShipmentStatus) (*domain.Shipment, error) {
+	var shipment domain.Shipment
+	err := r.inTx(ctx, func(tx *Repository) error {
+		from, err := tx.lockShipmentStatus(ctx, warehouseNo, shipmentDocNo)
+		if err != nil {
+			return err
+		}
+		if !from.CanTransitionTo(to) {
+			return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, from, to)
+		}
+
+		query := `UPDATE shipments SET status = $3
+		          WHERE warehouse_no = $1 AND shipment_doc_no = $2
+		          RETURNING warehouse_no, shipment_doc_no, customer_id, part_code, unit, qty, shipment_date, status`
+		if err := tx.db.QueryRow(ctx, query, warehouseNo, shipmentDocNo, to).Scan(
+			&shipment.WarehouseNo, &shipment.ShipmentDocNo, &shipment.CustomerID, &shipment.PartCode,
+			&shipment.Unit, &shipment.Qty, &shipment.ShipmentDate, &shipment.Status,
+		); err != nil {
+			return err
+		}
+
+		return tx.recordStatusChange(ctx, warehouseNo, shipmentDocNo, from, to)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &shipment, nil
+}
+
+func (r *Repository) GetShipmentStatusHistory(ctx context.Context, warehouseNo, shipmentDocNo int) ([]domain.ShipmentStatusChange, error) {
+	query := `SELECT warehouse_no, shipment_doc_no, COALESCE(from_status, ''), to_status, changed_by, changed_at
+	          FROM shipment_status_history
+	          WHERE warehouse_no = $1 AND shipment_doc_no = $2
+	          ORDER BY changed_at, history_id`
+	rows, err := r.db.Query(ctx, query, warehouseNo, shipmentDocNo)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []domain.ShipmentStatusChange
+	for rows.Next() {
+		var h domain.ShipmentStatusChange
+		if err := rows.Scan(&h.WarehouseNo, &h.ShipmentDocNo, &h.FromStatus, &h.ToStatus,
+			&h.ChangedBy, &h.ChangedAt); err != nil {
+			return nil, err
+		}
+		history = append(history, h)
+	}
+	return history, nil
+}
+
+// lockShipmentStatus блокирует строку отгрузки до конца транзакции
+// и возвращает ее текущий статус.
+func (r *Repository) lockShipmentStatus(ctx context.Context, warehouseNo, shipmentDocNo int) (domain.ShipmentStatus, error) {
+	var status domain.ShipmentStatus
+	query := `SELECT status FROM shipments
+	          WHERE warehouse_no = $1 AND shipment_doc_no = $2
+	          FOR UPDATE`
+	err := r.db.QueryRow(ctx, query, warehouseNo, shipmentDocNo).Scan(&status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", fmt.Errorf("shipment %d/%d: %w", warehouseNo, shipmentDocNo, ErrNotFound)
+	}
+	return status, err
+}
+
+// lockDeletableShipment блокирует строку отгрузки и проверяет, что ее можно
+// удалить: документ в черновике или отменен и не попал в счет.
+func (r *Repository) lockDeletableShipment(ctx context.Context, warehouseNo, shipmentDocNo int) error {
+	var status domain.ShipmentStatus
+	var invoiced bool
+	query := `SELECT status, invoice_id IS NOT NULL FROM shipments
+	          WHERE warehouse_no = $1 AND shipment_doc_no = $2
+	          FOR UPDATE`
+	err := r.db.QueryRow(ctx, query, warehouseNo, shipmentDocNo).Scan(&status, &invoiced)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("shipment %d/%d: %w", warehouseNo, shipmentDocNo, ErrNotFound)
+	}
+	if err != nil {
+		return err
+	}
+	if !status.Deletable() {
+		return fmt.Errorf("%w: %s", ErrShipmentLocked, status)
+	}
+	if invoiced {
+		return fmt.Errorf("%w: invoiced", ErrShipmentLocked)
+	}
+	return nil
+}
+
+func (r *Repository) recordStatusChange(ctx context.Context, warehouseNo, shipmentDocNo int, from, to domain.ShipmentStatus) error {
+	query := `INSERT INTO shipment_status_history (warehouse_no, shipment_doc_no, from_status, to_status, changed_by)
+	          VALUES ($1, $2, NULLIF($3, ''), $4, $5)`
+	_, err := r.db.Exec(ctx, query, warehouseNo, shipmentDocNo, string(from), string(to), UserFromContext(ctx))
+	return err
+}